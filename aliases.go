@@ -0,0 +1,153 @@
+package blockmatrix
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// aliasPrefix prefixes the entries mapping the database key of an alias to the key it is an alias of.
+const aliasPrefix = "alias:"
+
+// aliasValue is the value of an alias entry.
+type aliasValue struct {
+	// Key is the key the alias refers to
+	Key string `json:"key"`
+	// Alias is the alias as given to AddAlias, after normalization
+	Alias string `json:"alias"`
+}
+
+func aliasEntry(dbKey []byte) []byte {
+	return append([]byte(aliasPrefix), dbKey...)
+}
+
+// AddAlias makes alias another key for the block associated with existingKey, without copying the block.  GetBlock,
+// BlockNumber, UpdateBlock, and EraseBlock accept the alias in place of the key.  If existingKey is itself an alias the
+// new alias refers to the key it is an alias of.  An error wrapping ErrKeyExists is returned if alias is already a key
+// or an alias, and ErrNotFound if existingKey has no block.  A key added later with the same name as an alias takes
+// precedence over the alias.  Aliases are not part of the block matrix's hashes.
+func (b *BlockMatrix) AddAlias(existingKey string, alias string) error {
+	defer b.startSpan("AddAlias")()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	key, err := b.resolveAlias(existingKey)
+	if err != nil {
+		return err
+	}
+
+	if ok, err := b.has(b.dbKey(key)); err != nil {
+		return err
+	} else if !ok {
+		return fmt.Errorf("%w: key %q", ErrNotFound, existingKey)
+	}
+
+	alias = b.normalizeKey(alias)
+	dbKey := b.dbKey(alias)
+	if err = b.checkKeyLength(alias, dbKey); err != nil {
+		return err
+	}
+
+	if err = checkReservedKey(alias, dbKey); err != nil {
+		return err
+	}
+
+	for _, entry := range [][]byte{dbKey, aliasEntry(dbKey)} {
+		if ok, err := b.has(entry); err != nil {
+			return err
+		} else if ok {
+			return fmt.Errorf("%w: %q is already a key or an alias", ErrKeyExists, alias)
+		}
+	}
+
+	bytes, err := json.Marshal(aliasValue{Key: key, Alias: alias})
+	if err != nil {
+		return err
+	}
+
+	return b.put(aliasEntry(dbKey), bytes)
+}
+
+// Aliases returns the aliases of the block associated with key, in sorted order.  key may be the key of the block or
+// one of its aliases.
+func (b *BlockMatrix) Aliases(key string) ([]string, error) {
+	key, err := b.resolveAlias(key)
+	if err != nil {
+		return nil, err
+	}
+
+	values, err := b.aliasesOf(key)
+	if err != nil {
+		return nil, err
+	}
+
+	aliases := make([]string, len(values))
+	for i, value := range values {
+		aliases[i] = value.Alias
+	}
+	sort.Strings(aliases)
+
+	return aliases, nil
+}
+
+// resolveAlias returns the key key is an alias of, or key itself, normalized, if it is not an alias.
+func (b *BlockMatrix) resolveAlias(key string) (string, error) {
+	bytes, err := b.get(aliasEntry(b.dbKey(key)))
+	if errors.Is(err, ErrNotFound) {
+		return b.normalizeKey(key), nil
+	} else if err != nil {
+		return "", err
+	}
+
+	value := aliasValue{}
+	if err = json.Unmarshal(bytes, &value); err != nil {
+		return "", fmt.Errorf("error decoding alias %q: %w", key, err)
+	}
+
+	return value.Key, nil
+}
+
+// aliasesOf returns the aliases of key, which must not be an alias itself.
+func (b *BlockMatrix) aliasesOf(key string) ([]aliasValue, error) {
+	values := make([]aliasValue, 0)
+	err := b.iterate([]byte(aliasPrefix), func(entry []byte, bytes []byte) error {
+		value := aliasValue{}
+		if err := json.Unmarshal(bytes, &value); err != nil {
+			return fmt.Errorf("error decoding alias entry %q: %w", entry, err)
+		}
+
+		if value.Key == key {
+			values = append(values, value)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return values, nil
+}
+
+// erasedAliasEntries returns the alias entries to delete when the block associated with key, which must not be an
+// alias itself, is erased.  With WithRefuseAliasedErase an error wrapping ErrHasAliases is returned instead if key has
+// any aliases.
+func (b *BlockMatrix) erasedAliasEntries(key string) ([][]byte, error) {
+	values, err := b.aliasesOf(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(values) > 0 && b.refuseAliasedErase {
+		return nil, fmt.Errorf("%w: key %q has %d aliases", ErrHasAliases, key, len(values))
+	}
+
+	entries := make([][]byte, len(values))
+	for i, value := range values {
+		entries[i] = aliasEntry(b.dbKey(value.Alias))
+	}
+
+	return entries, nil
+}
@@ -0,0 +1,82 @@
+package blockmatrix
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestAddAlias(t *testing.T) {
+	bm, err := New(NewMemStore())
+	require.NoError(t, err)
+	require.NoError(t, createTestBlocks(bm, 5))
+	root, err := bm.RootHash()
+	require.NoError(t, err)
+
+	require.NoError(t, bm.AddAlias("key2", "second"))
+	require.NoError(t, bm.AddAlias("second", "two"))
+
+	for _, key := range []string{"key2", "second", "two"} {
+		block, err := bm.GetBlock(key)
+		require.NoError(t, err)
+		require.Equal(t, []byte{2}, block.Data)
+
+		aliases, err := bm.Aliases(key)
+		require.NoError(t, err)
+		require.Equal(t, []string{"second", "two"}, aliases)
+	}
+
+	// aliases are neither blocks nor keys
+	count, err := bm.BlockCount()
+	require.NoError(t, err)
+	require.Equal(t, 5, count)
+	keys, err := bm.Keys()
+	require.NoError(t, err)
+	require.Equal(t, []string{"key1", "key2", "key3", "key4", "key5"}, keys)
+	aliasedRoot, err := bm.RootHash()
+	require.NoError(t, err)
+	require.Equal(t, root, aliasedRoot)
+
+	require.ErrorIs(t, bm.AddAlias("key3", "second"), ErrKeyExists)
+	require.ErrorIs(t, bm.AddAlias("key3", "key4"), ErrKeyExists)
+	require.ErrorIs(t, bm.AddAlias("missing", "alias"), ErrNotFound)
+
+	// updating through an alias updates the block
+	require.NoError(t, bm.UpdateBlock("two", []byte("updated")))
+	block, err := bm.GetBlock("key2")
+	require.NoError(t, err)
+	require.Equal(t, []byte("updated"), block.Data)
+
+	// erasing through an alias erases the block and removes every alias
+	require.NoError(t, bm.EraseBlock("second"))
+	for _, key := range []string{"key2", "second", "two"} {
+		_, err = bm.GetBlock(key)
+		require.ErrorIs(t, err, ErrNotFound)
+	}
+	aliases, err := bm.Aliases("key2")
+	require.NoError(t, err)
+	require.Empty(t, aliases)
+
+	ok, err := bm.IsValid()
+	require.NoError(t, err)
+	require.True(t, ok)
+}
+
+func TestWithRefuseAliasedErase(t *testing.T) {
+	bm, err := New(NewMemStore(), WithRefuseAliasedErase())
+	require.NoError(t, err)
+	require.NoError(t, createTestBlocks(bm, 5))
+	require.NoError(t, bm.AddAlias("key2", "second"))
+
+	require.ErrorIs(t, bm.EraseBlock("key2"), ErrHasAliases)
+	require.ErrorIs(t, bm.DeleteBlock("second"), ErrHasAliases)
+	tx := bm.Begin()
+	require.NoError(t, tx.EraseBlock("key2"))
+	require.ErrorIs(t, tx.Commit(), ErrHasAliases)
+
+	block, err := bm.GetBlock("second")
+	require.NoError(t, err)
+	require.Equal(t, []byte{2}, block.Data)
+
+	// blocks without aliases can still be erased
+	require.NoError(t, bm.EraseBlock("key3"))
+}
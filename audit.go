@@ -0,0 +1,104 @@
+package blockmatrix
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"time"
+)
+
+// AuditReport writes a human readable summary of the block matrix to w for compliance reviews: its ID, size, block
+// count, fill ratio, root hash, the erased blocks with when they were erased, and the integrity violations found by
+// Validate.  Erase times come from the change log and are reported as unknown for erases made before times were
+// recorded.  Blocks freed by DeleteBlock are listed as erased.
+func (b *BlockMatrix) AuditReport(w io.Writer) error {
+	defer b.startSpan("AuditReport")()
+
+	info, err := b.GetBlockMatrixInfo()
+	if err != nil {
+		return err
+	}
+
+	geometry, err := b.ExportGeometry()
+	if err != nil {
+		return err
+	}
+
+	result, err := b.Validate()
+	if err != nil {
+		return err
+	}
+
+	times, err := b.lastWriteTimes()
+	if err != nil {
+		return err
+	}
+
+	id := info.ID
+	if id == "" {
+		id = "none"
+	}
+
+	fillRatio := 0.0
+	if geometry.Capacity > 0 {
+		fillRatio = float64(len(geometry.Live)) / float64(geometry.Capacity)
+	}
+
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "Block matrix audit report\n")
+	fmt.Fprintf(buf, "ID:          %s\n", id)
+	fmt.Fprintf(buf, "Size:        %d\n", info.Size)
+	fmt.Fprintf(buf, "Block count: %d\n", info.BlockCount)
+	fmt.Fprintf(buf, "Fill ratio:  %.2f (%d of %d slots live)\n", fillRatio, len(geometry.Live), geometry.Capacity)
+	fmt.Fprintf(buf, "Root hash:   %x\n", b.rootHash(info))
+
+	last, err := b.lastBlock(info)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(buf, "\nErased blocks:\n")
+	erased := 0
+	for blockNum := 1; blockNum <= last; blockNum++ {
+		block, err := b.GetBlockByNumber(blockNum)
+		if err != nil {
+			return err
+		}
+
+		// reserved blocks are empty but keep their key
+		if reserved, err := b.IsReserved(blockNum); err != nil {
+			return err
+		} else if !block.IsEmpty() || reserved {
+			continue
+		}
+
+		erasedAt := "at an unknown time"
+		if t, ok := times[blockNum]; ok {
+			erasedAt = "at " + t.UTC().Format(time.RFC3339)
+		}
+
+		row, col := locateBlock(blockNum)
+		fmt.Fprintf(buf, "  block %d (row %d, column %d) erased %s\n", blockNum, row, col, erasedAt)
+		erased++
+	}
+	if erased == 0 {
+		fmt.Fprintf(buf, "  none\n")
+	}
+
+	fmt.Fprintf(buf, "\nIntegrity violations:\n")
+	for _, blockNum := range result.BlockErrors {
+		fmt.Fprintf(buf, "  block %d hash does not match its data\n", blockNum)
+	}
+	for _, row := range result.RowErrors {
+		fmt.Fprintf(buf, "  row %d hash does not match its blocks\n", row)
+	}
+	for _, col := range result.ColumnErrors {
+		fmt.Fprintf(buf, "  column %d hash does not match its blocks\n", col)
+	}
+	if result.OK {
+		fmt.Fprintf(buf, "  none\n")
+	}
+
+	_, err = w.Write(buf.Bytes())
+	return err
+}
@@ -0,0 +1,48 @@
+package blockmatrix
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAuditReport(t *testing.T) {
+	bm, err := NewWithLevelDB(newTestDB(t))
+	require.NoError(t, err)
+	require.NoError(t, createTestBlocks(bm, 6))
+
+	before := time.Now()
+	require.NoError(t, bm.EraseBlock("key4"))
+	_, err = bm.ReserveBlock("reserved")
+	require.NoError(t, err)
+
+	buf := &bytes.Buffer{}
+	require.NoError(t, bm.AuditReport(buf))
+	report := buf.String()
+
+	root, err := bm.RootHash()
+	require.NoError(t, err)
+	require.Contains(t, report, fmt.Sprintf("Root hash:   %x\n", root))
+
+	id, err := bm.MatrixID()
+	require.NoError(t, err)
+	require.Contains(t, report, id)
+	require.Contains(t, report, "Block count: 7\n")
+	require.Contains(t, report, "Fill ratio:  0.42 (5 of 12 slots live)\n")
+
+	row, col := locateBlock(4)
+	erased := fmt.Sprintf("  block 4 (row %d, column %d) erased at ", row, col)
+	require.Contains(t, report, erased)
+	// the reserved block is empty but not erased
+	require.NotContains(t, report, "block 7 (")
+
+	line := report[strings.Index(report, erased)+len(erased):]
+	erasedAt, err := time.Parse(time.RFC3339, line[:strings.Index(line, "\n")])
+	require.NoError(t, err)
+	require.False(t, erasedAt.Before(before.Truncate(time.Second)))
+
+	require.Contains(t, report, "Integrity violations:\n  none\n")
+}
@@ -0,0 +1,87 @@
+package blockmatrix
+
+import (
+	"sync"
+	"time"
+)
+
+type (
+	// backgroundTask is maintenance work run periodically while the block matrix is open.
+	backgroundTask struct {
+		interval time.Duration
+		run      func()
+	}
+
+	// background coordinates the goroutines running background tasks.  A task only runs while holding mu, so pausing
+	// waits for running tasks to finish and no task starts until background work is resumed.
+	background struct {
+		mu     sync.Mutex
+		paused bool
+		stop   chan struct{}
+		wg     sync.WaitGroup
+	}
+)
+
+// startBackground starts a goroutine for each background task.
+func (b *BlockMatrix) startBackground() {
+	b.background.stop = make(chan struct{})
+	for _, task := range b.backgroundTasks {
+		b.background.wg.Add(1)
+		go b.background.loop(task)
+	}
+}
+
+func (bg *background) loop(task backgroundTask) {
+	defer bg.wg.Done()
+
+	ticker := time.NewTicker(task.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-bg.stop:
+			return
+		case <-ticker.C:
+			bg.mu.Lock()
+			if !bg.paused {
+				task.run()
+			}
+			bg.mu.Unlock()
+		}
+	}
+}
+
+// PauseBackground stops background maintenance, such as background validation, from running until ResumeBackground is
+// called.  It waits for any background work that is already running to finish.
+func (b *BlockMatrix) PauseBackground() {
+	b.background.mu.Lock()
+	defer b.background.mu.Unlock()
+	b.background.paused = true
+}
+
+// ResumeBackground resumes background maintenance paused by PauseBackground.
+func (b *BlockMatrix) ResumeBackground() {
+	b.background.mu.Lock()
+	defer b.background.mu.Unlock()
+	b.background.paused = false
+}
+
+// Close stops all background maintenance, waits for it to finish, and records the clean shutdown reported by
+// WasCleanlyClosed.  The database is not closed, it is owned by the caller, unless the block matrix was created by
+// NewInMemory.  Close must only be called once.
+func (b *BlockMatrix) Close() error {
+	if b.background.stop != nil {
+		close(b.background.stop)
+		b.background.wg.Wait()
+	}
+
+	if err := b.markClean(); err != nil {
+		return err
+	}
+
+	if b.ownedDB != nil {
+		return b.ownedDB.Close()
+	}
+
+	return nil
+}
@@ -0,0 +1,38 @@
+package blockmatrix
+
+import (
+	"github.com/stretchr/testify/require"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPauseBackground(t *testing.T) {
+	var runs int64
+	bm, err := NewWithLevelDB(newTestDB(t), WithBackgroundValidation(time.Millisecond, func(result *ValidationResult, err error) {
+		if err == nil && result.OK {
+			atomic.AddInt64(&runs, 1)
+		}
+	}))
+	require.NoError(t, err)
+	require.NoError(t, createTestBlocks(bm, 5))
+
+	require.Eventually(t, func() bool {
+		return atomic.LoadInt64(&runs) > 0
+	}, time.Second, time.Millisecond)
+
+	bm.PauseBackground()
+	paused := atomic.LoadInt64(&runs)
+	time.Sleep(20 * time.Millisecond)
+	require.Equal(t, paused, atomic.LoadInt64(&runs))
+
+	bm.ResumeBackground()
+	require.Eventually(t, func() bool {
+		return atomic.LoadInt64(&runs) > paused
+	}, time.Second, time.Millisecond)
+
+	require.NoError(t, bm.Close())
+	closed := atomic.LoadInt64(&runs)
+	time.Sleep(20 * time.Millisecond)
+	require.Equal(t, closed, atomic.LoadInt64(&runs))
+}
@@ -0,0 +1,254 @@
+package blockmatrix
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"math"
+	"os"
+)
+
+// backupMagic identifies a backup file written by Backup.
+var backupMagic = []byte("BMBKUP01")
+
+// backupEnd is written in place of a key length to mark the end of the entries of a backup file.
+const backupEnd = math.MaxUint32
+
+// Backup writes every entry of the block matrix, the info, the keys, the blocks, and the internal entries, to a single
+// file at path.  The file starts with a magic string followed by each entry as a big-endian uint32 key length, the key,
+// a uint32 value length, and the value.  The entries end with a marker, the number of entries as a uint64, and the
+// SHA-256 hash of everything before it, so Restore can tell a complete backup from a truncated or corrupted one.  The
+// file is written next to path and renamed into place once complete so an existing backup is never left half written.
+func (b *BlockMatrix) Backup(path string) error {
+	defer b.startSpan("Backup")()
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	tmp := path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp)
+
+	if err = b.writeBackup(f); err != nil {
+		f.Close()
+		return err
+	}
+
+	if err = f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+
+	if err = f.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, path)
+}
+
+// writeBackup writes the backup of the block matrix to w.
+func (b *BlockMatrix) writeBackup(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	h := sha256.New()
+	out := io.MultiWriter(bw, h)
+
+	if _, err := out.Write(backupMagic); err != nil {
+		return err
+	}
+
+	count := uint64(0)
+	err := b.iterate(nil, func(key []byte, value []byte) error {
+		if string(key) == cleanShutdownKey {
+			return nil
+		}
+
+		count++
+		for _, field := range [][]byte{key, value} {
+			if err := writeUint32(out, uint32(len(field))); err != nil {
+				return err
+			}
+
+			if _, err := out.Write(field); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if err = writeUint32(out, backupEnd); err != nil {
+		return err
+	}
+
+	countBytes := make([]byte, 8)
+	binary.BigEndian.PutUint64(countBytes, count)
+	if _, err = out.Write(countBytes); err != nil {
+		return err
+	}
+
+	if _, err = bw.Write(h.Sum(nil)); err != nil {
+		return err
+	}
+
+	return bw.Flush()
+}
+
+func writeUint32(w io.Writer, n uint32) error {
+	buf := make([]byte, 4)
+	binary.BigEndian.PutUint32(buf, n)
+	_, err := w.Write(buf)
+	return err
+}
+
+// Restore replaces the contents of the block matrix with the entries of a backup file written by Backup.  The whole file
+// is read and checked before anything is written, so a truncated or corrupted backup returns an error wrapping
+// ErrMalformedBackup and leaves the block matrix untouched.  Unless force is true, ErrMatrixExists is returned if the
+// block matrix already has blocks.  The entries are written atomically, replacing every existing entry.
+func (b *BlockMatrix) Restore(path string, force bool) error {
+	defer b.startSpan("Restore")()
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	writes, err := readBackup(data)
+	if err != nil {
+		return err
+	}
+
+	info, err := backupInfo(writes)
+	if err != nil {
+		return err
+	}
+
+	if err = b.checkHasher(info); err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !force {
+		if blockCount, err := b.BlockCount(); err != nil {
+			return err
+		} else if blockCount > 0 {
+			return fmt.Errorf("%w: cannot restore over a block matrix with %d blocks", ErrMatrixExists, blockCount)
+		}
+	}
+
+	return b.replaceEntries(writes)
+}
+
+// readBackup decodes and checks the entries of a backup file.
+func readBackup(data []byte) ([]StoreWrite, error) {
+	if len(data) < len(backupMagic) || !bytes.Equal(data[:len(backupMagic)], backupMagic) {
+		return nil, fmt.Errorf("%w: not a block matrix backup", ErrMalformedBackup)
+	}
+
+	r := &backupReader{data: data, pos: len(backupMagic), h: sha256.New()}
+	r.h.Write(backupMagic)
+
+	writes := make([]StoreWrite, 0)
+	for {
+		keyLen, err := r.uint32()
+		if err != nil {
+			return nil, err
+		}
+
+		if keyLen == backupEnd {
+			break
+		}
+
+		key, err := r.next(int(keyLen))
+		if err != nil {
+			return nil, err
+		}
+
+		valueLen, err := r.uint32()
+		if err != nil {
+			return nil, err
+		}
+
+		value, err := r.next(int(valueLen))
+		if err != nil {
+			return nil, err
+		}
+
+		writes = append(writes, StoreWrite{Key: copyBytes(key), Value: copyBytes(value)})
+	}
+
+	countBytes, err := r.next(8)
+	if err != nil {
+		return nil, err
+	}
+
+	if count := binary.BigEndian.Uint64(countBytes); count != uint64(len(writes)) {
+		return nil, fmt.Errorf("%w: has %d entries, expected %d", ErrMalformedBackup, len(writes), count)
+	}
+
+	sum := r.h.Sum(nil)
+	if len(data)-r.pos != len(sum) {
+		return nil, fmt.Errorf("%w: missing or malformed checksum", ErrMalformedBackup)
+	}
+
+	if !bytes.Equal(data[r.pos:], sum) {
+		return nil, fmt.Errorf("%w: checksum does not match", ErrMalformedBackup)
+	}
+
+	return writes, nil
+}
+
+// backupInfo returns the decoded info entry of a backup.
+func backupInfo(writes []StoreWrite) (*BlockMatrixInfo, error) {
+	for _, write := range writes {
+		if bytes.Equal(write.Key, InfoKey) {
+			info, err := decodeInfo(write.Value)
+			if err != nil {
+				return nil, fmt.Errorf("%w: error decoding info: %v", ErrMalformedBackup, err)
+			}
+
+			return info, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%w: missing info", ErrMalformedBackup)
+}
+
+// backupReader reads the fields of a backup file, hashing every byte it reads.
+type backupReader struct {
+	data []byte
+	pos  int
+	h    hash.Hash
+}
+
+func (r *backupReader) next(n int) ([]byte, error) {
+	if n > len(r.data)-r.pos {
+		return nil, fmt.Errorf("%w: truncated at byte %d", ErrMalformedBackup, len(r.data))
+	}
+
+	field := r.data[r.pos : r.pos+n]
+	r.pos += n
+	r.h.Write(field)
+	return field, nil
+}
+
+func (r *backupReader) uint32() (uint32, error) {
+	field, err := r.next(4)
+	if err != nil {
+		return 0, err
+	}
+
+	return binary.BigEndian.Uint32(field), nil
+}
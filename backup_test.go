@@ -0,0 +1,86 @@
+package blockmatrix
+
+import (
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func TestBackupRestore(t *testing.T) {
+	bm, err := NewWithLevelDB(newTestDB(t))
+	require.NoError(t, err)
+	require.NoError(t, createTestBlocks(bm, 7))
+	require.NoError(t, bm.EraseBlock("key3"))
+
+	path := filepath.Join(t.TempDir(), "matrix.backup")
+	require.NoError(t, bm.Backup(path))
+
+	restored, err := New(NewMemStore())
+	require.NoError(t, err)
+	require.NoError(t, restored.Restore(path, false))
+
+	expected, err := bm.Matrix()
+	require.NoError(t, err)
+	actual, err := restored.Matrix()
+	require.NoError(t, err)
+	require.Equal(t, expected, actual)
+
+	expectedKeys, err := bm.Keys()
+	require.NoError(t, err)
+	actualKeys, err := restored.Keys()
+	require.NoError(t, err)
+	require.Equal(t, expectedKeys, actualKeys)
+
+	ok, err := restored.IsValid()
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	// the restored block matrix keeps working
+	require.NoError(t, restored.AddBlock("new", []byte("new")))
+
+	// restoring over blocks requires force
+	require.ErrorIs(t, restored.Restore(path, false), ErrMatrixExists)
+	require.NoError(t, restored.Restore(path, true))
+	actual, err = restored.Matrix()
+	require.NoError(t, err)
+	require.Equal(t, expected, actual)
+	_, err = restored.GetBlock("new")
+	require.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestRestoreTruncatedBackup(t *testing.T) {
+	bm, err := New(NewMemStore())
+	require.NoError(t, err)
+	require.NoError(t, createTestBlocks(bm, 5))
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "matrix.backup")
+	require.NoError(t, bm.Backup(path))
+	data, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+
+	target, err := New(NewMemStore())
+	require.NoError(t, err)
+	require.NoError(t, target.AddBlock("existing", []byte("existing")))
+	expected, err := target.Matrix()
+	require.NoError(t, err)
+
+	truncated := filepath.Join(dir, "truncated.backup")
+	for _, n := range []int{0, 4, len(backupMagic), len(backupMagic) + 2, len(data) / 2, len(data) - 40, len(data) - 1} {
+		require.NoError(t, ioutil.WriteFile(truncated, data[:n], 0644))
+		require.ErrorIs(t, target.Restore(truncated, true), ErrMalformedBackup, "truncated to %d bytes", n)
+	}
+
+	corrupted := append([]byte{}, data...)
+	corrupted[len(data)/2] ^= 0xff
+	require.NoError(t, ioutil.WriteFile(truncated, corrupted, 0644))
+	require.ErrorIs(t, target.Restore(truncated, true), ErrMalformedBackup)
+
+	// none of the failed restores touched the block matrix
+	actual, err := target.Matrix()
+	require.NoError(t, err)
+	require.Equal(t, expected, actual)
+	_, err = target.GetBlock("existing")
+	require.NoError(t, err)
+}
@@ -0,0 +1,142 @@
+package blockmatrix
+
+import "sort"
+
+type (
+	// Batch collects block matrix mutations and applies them together with Commit.  Each mutation marks the row and
+	// column of its block as dirty and every dirty row and column hash is recomputed once when the batch is committed,
+	// instead of once per mutation.
+	Batch struct {
+		bm  *BlockMatrix
+		ops []batchOp
+		// getBlock reads the blocks hashed when recomputing the dirty rows and columns
+		getBlock func(int) (*Block, error)
+	}
+
+	batchOp struct {
+		key   string
+		data  []byte
+		erase bool
+	}
+)
+
+// NewBatch returns an empty batch for this block matrix.
+func (b *BlockMatrix) NewBatch() *Batch {
+	return &Batch{
+		bm:       b,
+		getBlock: b.getSlot,
+	}
+}
+
+// AddBlock queues adding a block with the given key and data.
+func (batch *Batch) AddBlock(key string, data []byte) {
+	batch.ops = append(batch.ops, batchOp{key: key, data: data})
+}
+
+// EraseBlock queues erasing the block associated with the given key.
+func (batch *Batch) EraseBlock(key string) {
+	batch.ops = append(batch.ops, batchOp{key: key, erase: true})
+}
+
+// Len returns the number of queued mutations.
+func (batch *Batch) Len() int {
+	return len(batch.ops)
+}
+
+// Commit applies the queued mutations in order and then recomputes the hashes of the rows and columns they touched.  If
+// a mutation fails, the mutations before it remain applied, their hashes are still updated, and the error is returned.
+// The batch is empty after Commit returns.
+func (batch *Batch) Commit() error {
+	b := batch.bm
+	defer b.startSpan("Batch.Commit")()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return batch.commit()
+}
+
+func (batch *Batch) commit() error {
+	b := batch.bm
+	ops := batch.ops
+	batch.ops = nil
+
+	info, err := b.GetBlockMatrixInfo()
+	if err != nil {
+		return err
+	}
+
+	dirtyRows := make(map[int]bool)
+	dirtyCols := make(map[int]bool)
+	grew := false
+
+	var opErr error
+	for _, op := range ops {
+		var blockNum int
+		if op.erase {
+			blockNum, opErr = b.clearBlock(op.key)
+		} else {
+			var opGrew bool
+			blockNum, opGrew, opErr = b.storeBlock(info, op.key, b.newBlock(op.data))
+			grew = grew || opGrew
+		}
+		if opErr != nil {
+			break
+		}
+
+		row, col := locateBlock(blockNum)
+		dirtyRows[row] = true
+		dirtyCols[col] = true
+	}
+
+	// growing the matrix adds empty blocks to every existing row and column so all of the hashes need to be updated
+	if grew {
+		for i := 0; i < info.Size; i++ {
+			dirtyRows[i] = true
+			dirtyCols[i] = true
+		}
+	}
+
+	if err = batch.updateHashes(info, dirtyRows, dirtyCols); err != nil {
+		return err
+	}
+
+	return opErr
+}
+
+// updateHashes recomputes the hashes of the given rows and columns and stores the info.
+func (batch *Batch) updateHashes(info *BlockMatrixInfo, rows map[int]bool, cols map[int]bool) error {
+	for _, row := range sortedIndices(rows) {
+		blockNums, err := rowBlockNumbers(row, info.Size)
+		if err != nil {
+			return err
+		}
+
+		if info.Rows[row], err = hashBlocks(batch.bm.hasher, blockNums, batch.getBlock); err != nil {
+			return err
+		}
+	}
+
+	for _, col := range sortedIndices(cols) {
+		blockNums, err := columnBlockNumbers(col, info.Size)
+		if err != nil {
+			return err
+		}
+
+		if info.Cols[col], err = hashBlocks(batch.bm.hasher, blockNums, batch.getBlock); err != nil {
+			return err
+		}
+	}
+
+	return batch.bm.putBlockMatrixInfo(info)
+}
+
+func sortedIndices(set map[int]bool) []int {
+	indices := make([]int, 0, len(set))
+	for i := range set {
+		indices = append(indices, i)
+	}
+	sort.Ints(indices)
+
+	return indices
+}
@@ -0,0 +1,108 @@
+package blockmatrix
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/require"
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/storage"
+	"testing"
+)
+
+func TestBatch(t *testing.T) {
+	expected, err := NewWithLevelDB(newTestDB(t), WithClock(testClock))
+	require.NoError(t, err)
+	require.NoError(t, createTestBlocks(expected, 5))
+	require.NoError(t, expected.EraseBlock("key2"))
+	require.NoError(t, expected.AddBlock("key6", []byte{6}))
+	require.NoError(t, expected.EraseBlock("key4"))
+	require.NoError(t, expected.AddBlock("key7", []byte{7}))
+
+	bm, err := NewWithLevelDB(newTestDB(t), WithClock(testClock))
+	require.NoError(t, err)
+	require.NoError(t, createTestBlocks(bm, 5))
+
+	batch := bm.NewBatch()
+	batch.EraseBlock("key2")
+	batch.AddBlock("key6", []byte{6})
+	batch.EraseBlock("key4")
+	batch.AddBlock("key7", []byte{7})
+	require.Equal(t, 4, batch.Len())
+	require.NoError(t, batch.Commit())
+	require.Equal(t, 0, batch.Len())
+
+	expectedInfo, err := expected.GetBlockMatrixInfo()
+	require.NoError(t, err)
+	info, err := bm.GetBlockMatrixInfo()
+	require.NoError(t, err)
+	require.Equal(t, expectedInfo.Size, info.Size)
+	require.Equal(t, expectedInfo.BlockCount, info.BlockCount)
+	require.Equal(t, expectedInfo.Rows, info.Rows)
+	require.Equal(t, expectedInfo.Cols, info.Cols)
+
+	result, err := bm.Validate()
+	require.NoError(t, err)
+	require.True(t, result.OK)
+}
+
+func TestBatchCommitError(t *testing.T) {
+	bm, err := NewWithLevelDB(newTestDB(t))
+	require.NoError(t, err)
+	require.NoError(t, createTestBlocks(bm, 3))
+
+	batch := bm.NewBatch()
+	batch.EraseBlock("key1")
+	batch.EraseBlock("missing")
+	batch.AddBlock("key4", []byte{4})
+	require.Error(t, batch.Commit())
+
+	// the erase before the failure is applied and hashed, the add after it is not
+	_, err = bm.GetBlock("key1")
+	require.Error(t, err)
+	_, err = bm.GetBlock("key4")
+	require.Error(t, err)
+
+	result, err := bm.Validate()
+	require.NoError(t, err)
+	require.True(t, result.OK)
+}
+
+// BenchmarkBatchErase erases the three blocks in the first row of a 4x4 block matrix, committing each erase on its own
+// and all of them together, and reports the number of blocks read to recompute the hashes.
+func BenchmarkBatchErase(b *testing.B) {
+	keys := []string{"key1", "key3", "key7"}
+
+	for _, batched := range []bool{false, true} {
+		b.Run(fmt.Sprintf("batched=%t", batched), func(b *testing.B) {
+			reads := 0
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				db, err := leveldb.Open(storage.NewMemStorage(), nil)
+				require.NoError(b, err)
+				bm, err := NewWithLevelDB(db)
+				require.NoError(b, err)
+				require.NoError(b, createTestBlocks(bm, 9))
+				b.StartTimer()
+
+				batch := bm.NewBatch()
+				batch.getBlock = func(blockNum int) (*Block, error) {
+					reads++
+					return bm.GetBlockByNumber(blockNum)
+				}
+
+				for _, key := range keys {
+					batch.EraseBlock(key)
+					if !batched {
+						require.NoError(b, batch.Commit())
+					}
+				}
+				require.NoError(b, batch.Commit())
+
+				b.StopTimer()
+				require.NoError(b, db.Close())
+				b.StartTimer()
+			}
+
+			b.ReportMetric(float64(reads)/float64(b.N), "reads/op")
+		})
+	}
+}
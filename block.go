@@ -1,18 +1,43 @@
 package blockmatrix
 
-import "crypto/sha256"
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"hash"
+	"sort"
+	"time"
+)
 
 type Block struct {
 	Data []byte `json:"data"`
 	Hash []byte `json:"hash"`
+	// Timestamp is when the block was created, it is part of the hash unless it is the zero time, which it is for empty
+	// blocks and blocks stored before timestamps were recorded
+	Timestamp time.Time `json:"timestamp"`
+	// Metadata holds application tags such as the content type or the source system, it is part of the hash unless it
+	// is empty
+	Metadata map[string]string `json:"metadata,omitempty"`
+	// MAC is the HMAC-SHA256 of the data, only set when the block matrix is configured WithHMAC
+	MAC []byte `json:"mac,omitempty"`
+	// Codecs names the codecs the stored data was encoded with in the order they were applied, it is only set in
+	// storage when the block matrix is configured WithCodecs
+	Codecs []string `json:"codecs,omitempty"`
 }
 
 func NewBlock(data []byte) *Block {
-	hash := calculateHash(data)
-	return &Block{
-		Data: data,
-		Hash: hash,
+	block := &Block{
+		Data:      data,
+		Timestamp: newTimestamp(),
 	}
+	block.Hash = block.CalculateHash()
+	return block
+}
+
+// newTimestamp returns the current time in UTC without a monotonic clock reading, so that it is equal to itself after a
+// JSON round trip.
+func newTimestamp() time.Time {
+	return time.Now().UTC().Round(0)
 }
 
 func calculateHash(bytes []byte) []byte {
@@ -21,6 +46,34 @@ func calculateHash(bytes []byte) []byte {
 	return h.Sum(nil)
 }
 
+// hashBlock returns the hash of a block's data, timestamp, and metadata using h.  A zero timestamp and empty metadata are
+// left out so blocks without them hash to the hash of their data alone.  The metadata is hashed in key order with each
+// key and value length prefixed, so the hash does not depend on map iteration order and entries cannot run together.
+func hashBlock(h hash.Hash, block *Block) []byte {
+	var buf [8]byte
+	h.Write(block.Data)
+	if !block.Timestamp.IsZero() {
+		binary.BigEndian.PutUint64(buf[:], uint64(block.Timestamp.UnixNano()))
+		h.Write(buf[:])
+	}
+
+	keys := make([]string, 0, len(block.Metadata))
+	for key := range block.Metadata {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		for _, s := range []string{key, block.Metadata[key]} {
+			binary.BigEndian.PutUint64(buf[:], uint64(len(s)))
+			h.Write(buf[:])
+			h.Write([]byte(s))
+		}
+	}
+
+	return h.Sum(nil)
+}
+
 func EmptyBlock() *Block {
 	bytes := []byte{0}
 	return &Block{
@@ -29,6 +82,14 @@ func EmptyBlock() *Block {
 	}
 }
 
+// CalculateHash returns the SHA-256 hash of the block's data, timestamp, and metadata.  A block does not know the hash
+// function of its block matrix, so the hashes of blocks of a block matrix created WithHasher don't match CalculateHash.
 func (b Block) CalculateHash() []byte {
-	return calculateHash(b.Data)
+	return hashBlock(sha256.New(), &b)
+}
+
+// IsEmpty returns true if the block holds the same data as an empty block, which is the case for blocks that have not
+// been added yet and blocks that have been erased.
+func (b Block) IsEmpty() bool {
+	return bytes.Equal(b.Data, EmptyBlock().Data)
 }
@@ -0,0 +1,266 @@
+package blockmatrix
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// binaryBlockVersion is the first byte of blocks stored in the binary encoding.  Blocks stored as JSON start with '{'.
+const binaryBlockVersion = 1
+
+// BlockEncoding serializes blocks for storage.  The encoding of a block matrix is chosen with WithBlockEncoding when it
+// is created and recorded in its info, see BlockMatrixInfo.BlockEncoding.  Stored blocks starting with '{' are always
+// read as JSON, so an encoding other than JSON must not produce output starting with '{'.
+type BlockEncoding interface {
+	// Name identifies the encoding in the info of the block matrices created with it, it must not change once blocks
+	// are written with it
+	Name() string
+	// Marshal serializes the block, including its data as encoded by the codecs, MAC, and codec chain
+	Marshal(block *Block) ([]byte, error)
+	// Unmarshal reverses Marshal
+	Unmarshal(data []byte) (*Block, error)
+}
+
+type (
+	// jsonBlockEncoding stores blocks as JSON objects.
+	jsonBlockEncoding struct{}
+
+	// binaryBlockEncoding stores blocks as a version byte followed by length prefixed fields.
+	binaryBlockEncoding struct{}
+)
+
+// JSONBlockEncoding returns the encoding block matrices were stored with before the binary encoding.  It is recorded as
+// an empty name in the info.
+func JSONBlockEncoding() BlockEncoding {
+	return jsonBlockEncoding{}
+}
+
+// BinaryBlockEncoding returns the default encoding of new block matrices.  A block is stored as a version byte, then
+// its data, hash, and MAC, each prefixed with its uvarint length plus one so that nil and empty byte slices stay
+// distinct, then a flag byte and the big endian Unix nanoseconds of its timestamp if it is not zero, then the uvarint
+// number of metadata entries followed by each key and value in key order, and last the uvarint number of codecs
+// followed by their names, every string prefixed with its uvarint length.  It is a third to half smaller than JSON,
+// which base64 encodes the data and hashes and spells out the field names.
+func BinaryBlockEncoding() BlockEncoding {
+	return binaryBlockEncoding{}
+}
+
+func (jsonBlockEncoding) Name() string {
+	return "json"
+}
+
+func (jsonBlockEncoding) Marshal(block *Block) ([]byte, error) {
+	return json.Marshal(block)
+}
+
+func (jsonBlockEncoding) Unmarshal(data []byte) (*Block, error) {
+	block := &Block{}
+	if err := json.Unmarshal(data, block); err != nil {
+		return nil, err
+	}
+
+	return block, nil
+}
+
+func (binaryBlockEncoding) Name() string {
+	return "binary"
+}
+
+func (binaryBlockEncoding) Marshal(block *Block) ([]byte, error) {
+	size := 1 + 3*binary.MaxVarintLen64 + len(block.Data) + len(block.Hash) + len(block.MAC) + 9
+	buf := make([]byte, 0, size)
+	buf = append(buf, binaryBlockVersion)
+	for _, field := range [][]byte{block.Data, block.Hash, block.MAC} {
+		if field == nil {
+			buf = appendUvarint(buf, 0)
+			continue
+		}
+
+		buf = appendUvarint(buf, uint64(len(field))+1)
+		buf = append(buf, field...)
+	}
+
+	if block.Timestamp.IsZero() {
+		buf = append(buf, 0)
+	} else {
+		var nanos [8]byte
+		binary.BigEndian.PutUint64(nanos[:], uint64(block.Timestamp.UnixNano()))
+		buf = append(buf, 1)
+		buf = append(buf, nanos[:]...)
+	}
+
+	keys := make([]string, 0, len(block.Metadata))
+	for key := range block.Metadata {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	buf = appendUvarint(buf, uint64(len(keys)))
+	for _, key := range keys {
+		buf = appendString(buf, key)
+		buf = appendString(buf, block.Metadata[key])
+	}
+
+	buf = appendUvarint(buf, uint64(len(block.Codecs)))
+	for _, codec := range block.Codecs {
+		buf = appendString(buf, codec)
+	}
+
+	return buf, nil
+}
+
+func (binaryBlockEncoding) Unmarshal(data []byte) (*Block, error) {
+	if len(data) == 0 || data[0] != binaryBlockVersion {
+		return nil, fmt.Errorf("binary block does not start with version %d", binaryBlockVersion)
+	}
+
+	r := &binaryBlockReader{data: data[1:]}
+	block := &Block{}
+	for _, field := range []*[]byte{&block.Data, &block.Hash, &block.MAC} {
+		*field = r.bytes()
+	}
+
+	if r.byte() == 1 {
+		if nanos := r.next(8); nanos != nil {
+			block.Timestamp = time.Unix(0, int64(binary.BigEndian.Uint64(nanos))).UTC()
+		}
+	}
+
+	if n := r.uvarint(); n > 0 {
+		block.Metadata = make(map[string]string)
+		for i := uint64(0); i < n && r.err == nil; i++ {
+			key := r.string()
+			block.Metadata[key] = r.string()
+		}
+	}
+
+	if n := r.uvarint(); n > 0 {
+		for i := uint64(0); i < n && r.err == nil; i++ {
+			block.Codecs = append(block.Codecs, r.string())
+		}
+	}
+
+	if r.err == nil && len(r.data) > 0 {
+		r.err = fmt.Errorf("binary block has %d trailing bytes", len(r.data))
+	}
+
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	return block, nil
+}
+
+func appendString(buf []byte, s string) []byte {
+	buf = appendUvarint(buf, uint64(len(s)))
+	return append(buf, s...)
+}
+
+// binaryBlockReader reads the fields of a block in the binary encoding, recording the first error so that the fields
+// can be read without checking for one after each.  Once an error is recorded every read returns a zero value.
+type binaryBlockReader struct {
+	data []byte
+	err  error
+}
+
+// next returns the next n bytes, nil if there are fewer.
+func (r *binaryBlockReader) next(n uint64) []byte {
+	if r.err != nil {
+		return nil
+	}
+
+	if uint64(len(r.data)) < n {
+		r.err = fmt.Errorf("binary block is truncated")
+		return nil
+	}
+
+	next := r.data[:n]
+	r.data = r.data[n:]
+	return next
+}
+
+func (r *binaryBlockReader) byte() byte {
+	if next := r.next(1); next != nil {
+		return next[0]
+	}
+
+	return 0
+}
+
+func (r *binaryBlockReader) uvarint() uint64 {
+	if r.err != nil {
+		return 0
+	}
+
+	v, n := binary.Uvarint(r.data)
+	if n <= 0 {
+		r.err = fmt.Errorf("binary block has a malformed length")
+		return 0
+	}
+
+	r.data = r.data[n:]
+	return v
+}
+
+// bytes returns a copy of the next byte slice, nil if its length prefix is 0.
+func (r *binaryBlockReader) bytes() []byte {
+	n := r.uvarint()
+	if n == 0 {
+		return nil
+	}
+
+	return append([]byte{}, r.next(n-1)...)
+}
+
+func (r *binaryBlockReader) string() string {
+	return string(r.next(r.uvarint()))
+}
+
+// blockEncodings are the built in encodings, by the name recorded in the info.
+var blockEncodings = map[string]BlockEncoding{
+	"":       jsonBlockEncoding{},
+	"binary": binaryBlockEncoding{},
+}
+
+// blockEncodingID returns the name of the encoding recorded in the info, which is empty for JSON.
+func blockEncodingID(encoding BlockEncoding) string {
+	if _, ok := encoding.(jsonBlockEncoding); ok {
+		return ""
+	}
+
+	return encoding.Name()
+}
+
+// recordedBlockEncoding returns the encoding recorded in info, so that a block matrix is read and written with the
+// encoding it was created with whatever WithBlockEncoding it is opened with.  An error wrapping ErrUnknownBlockEncoding
+// is returned if the recorded encoding is neither built in nor the configured one.
+func (b *BlockMatrix) recordedBlockEncoding(info *BlockMatrixInfo) (BlockEncoding, error) {
+	if info.BlockEncoding == blockEncodingID(b.blockEncoding) {
+		return b.blockEncoding, nil
+	}
+
+	encoding, ok := blockEncodings[info.BlockEncoding]
+	if !ok {
+		return nil, fmt.Errorf("%w: the block matrix was created with block encoding %q", ErrUnknownBlockEncoding,
+			info.BlockEncoding)
+	}
+
+	return encoding, nil
+}
+
+// unmarshalBlock deserializes a stored block with the encoding of the block matrix.  Blocks starting with '{' are read
+// as JSON, and a block matrix stored as JSON reads binary blocks too, so blocks copied between block matrices with
+// different built in encodings, for example by ReplicateIncremental, remain readable.
+func (b *BlockMatrix) unmarshalBlock(bytes []byte) (*Block, error) {
+	switch {
+	case len(bytes) > 0 && bytes[0] == '{':
+		return jsonBlockEncoding{}.Unmarshal(bytes)
+	case len(bytes) > 0 && bytes[0] == binaryBlockVersion && blockEncodingID(b.blockEncoding) == "":
+		return binaryBlockEncoding{}.Unmarshal(bytes)
+	}
+
+	return b.blockEncoding.Unmarshal(bytes)
+}
@@ -0,0 +1,163 @@
+package blockmatrix
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+// testEncodingBlocks returns blocks exercising every field of the block encodings.
+func testEncodingBlocks() []*Block {
+	full := NewBlock(bytes.Repeat([]byte("data"), 100))
+	full.Metadata = map[string]string{"content-type": "text/plain", "source": "", "": "empty key"}
+	full.MAC = calculateMAC([]byte("key"), full.Data)
+	full.Codecs = []string{"flate", "aes-gcm"}
+
+	return []*Block{
+		full,
+		NewBlock([]byte("small")),
+		EmptyBlock(),
+		{Data: []byte{}, Hash: []byte{}},
+		{},
+	}
+}
+
+func TestBlockEncodings(t *testing.T) {
+	for _, encoding := range []BlockEncoding{JSONBlockEncoding(), BinaryBlockEncoding()} {
+		t.Run(encoding.Name(), func(t *testing.T) {
+			for _, block := range testEncodingBlocks() {
+				encoded, err := encoding.Marshal(block)
+				require.NoError(t, err)
+
+				decoded, err := encoding.Unmarshal(encoded)
+				require.NoError(t, err)
+				require.Equal(t, block.Hash, decoded.Hash)
+				require.True(t, block.Timestamp.Equal(decoded.Timestamp))
+				require.Equal(t, block.CalculateHash(), decoded.CalculateHash())
+				require.Equal(t, block.MAC, decoded.MAC)
+				require.Equal(t, block.Codecs, decoded.Codecs)
+			}
+		})
+	}
+
+	// truncated binary blocks are rejected rather than read short
+	encoded, err := BinaryBlockEncoding().Marshal(testEncodingBlocks()[0])
+	require.NoError(t, err)
+	for n := 0; n < len(encoded); n++ {
+		_, err = BinaryBlockEncoding().Unmarshal(encoded[:n])
+		require.Error(t, err, "truncated to %d bytes", n)
+	}
+	_, err = BinaryBlockEncoding().Unmarshal(append(encoded, 0))
+	require.Error(t, err)
+}
+
+func TestBlockEncodingSize(t *testing.T) {
+	for _, block := range testEncodingBlocks()[:3] {
+		jsonBytes, err := JSONBlockEncoding().Marshal(block)
+		require.NoError(t, err)
+		binaryBytes, err := BinaryBlockEncoding().Marshal(block)
+		require.NoError(t, err)
+
+		t.Logf("%d bytes of data: %d bytes as JSON, %d bytes as binary", len(block.Data), len(jsonBytes),
+			len(binaryBytes))
+		require.Less(t, len(binaryBytes), len(jsonBytes)*3/4)
+	}
+}
+
+func TestWithBlockEncoding(t *testing.T) {
+	db := newTestDB(t)
+	bm, err := NewWithLevelDB(db, WithBlockEncoding(JSONBlockEncoding()))
+	require.NoError(t, err)
+	require.NoError(t, createTestBlocks(bm, 5))
+
+	stored, err := db.Get([]byte("1"), nil)
+	require.NoError(t, err)
+	require.Equal(t, byte('{'), stored[0])
+	info, err := bm.GetBlockMatrixInfo()
+	require.NoError(t, err)
+	require.Empty(t, info.BlockEncoding)
+
+	// reopening with the default encoding keeps writing JSON
+	reopened, err := NewWithLevelDB(db)
+	require.NoError(t, err)
+	require.NoError(t, reopened.AddBlock("key6", []byte{6}))
+	stored, err = db.Get([]byte("6"), nil)
+	require.NoError(t, err)
+	require.Equal(t, byte('{'), stored[0])
+
+	// a new block matrix is binary and its replica takes the encoding with the blocks
+	binary, err := New(NewMemStore())
+	require.NoError(t, err)
+	require.NoError(t, createTestBlocks(binary, 5))
+	info, err = binary.GetBlockMatrixInfo()
+	require.NoError(t, err)
+	require.Equal(t, "binary", info.BlockEncoding)
+
+	require.NoError(t, binary.ReplicateTo(reopened))
+	require.NoError(t, reopened.AddBlock("key6", []byte{6}))
+	stored, err = db.Get([]byte("6"), nil)
+	require.NoError(t, err)
+	require.Equal(t, byte(binaryBlockVersion), stored[0])
+	ok, err := reopened.IsValid()
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	// an encoding that is not built in must be configured to open the block matrix
+	custom, err := New(NewMemStore(), WithBlockEncoding(reversedEncoding{}))
+	require.NoError(t, err)
+	require.NoError(t, createTestBlocks(custom, 3))
+	_, err = New(custom.store)
+	require.ErrorIs(t, err, ErrUnknownBlockEncoding)
+	custom, err = New(custom.store, WithBlockEncoding(reversedEncoding{}))
+	require.NoError(t, err)
+	block, err := custom.GetBlock("key3")
+	require.NoError(t, err)
+	require.Equal(t, []byte{3}, block.Data)
+}
+
+// reversedEncoding is the binary encoding with its bytes reversed.
+type reversedEncoding struct{}
+
+func (reversedEncoding) Name() string {
+	return "reversed"
+}
+
+func (reversedEncoding) Marshal(block *Block) ([]byte, error) {
+	encoded, err := BinaryBlockEncoding().Marshal(block)
+	return reverseBytes(encoded), err
+}
+
+func (reversedEncoding) Unmarshal(data []byte) (*Block, error) {
+	return BinaryBlockEncoding().Unmarshal(reverseBytes(data))
+}
+
+func reverseBytes(data []byte) []byte {
+	reversed := make([]byte, len(data))
+	for i, b := range data {
+		reversed[len(data)-1-i] = b
+	}
+
+	return reversed
+}
+
+// BenchmarkBlockEncodings reads and writes a block of 1 KiB with each block encoding and reports the encoded size.
+func BenchmarkBlockEncodings(b *testing.B) {
+	block := NewBlock(bytes.Repeat([]byte{0xab}, 1024))
+	block.Metadata = map[string]string{"content-type": "application/octet-stream"}
+
+	for _, encoding := range []BlockEncoding{JSONBlockEncoding(), BinaryBlockEncoding()} {
+		b.Run(fmt.Sprintf("encoding=%s", encoding.Name()), func(b *testing.B) {
+			var size int
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				encoded, err := encoding.Marshal(block)
+				require.NoError(b, err)
+				_, err = encoding.Unmarshal(encoded)
+				require.NoError(b, err)
+				size = len(encoded)
+			}
+			b.ReportMetric(float64(size), "bytes/block")
+		})
+	}
+}
@@ -0,0 +1,122 @@
+package blockmatrix
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestBlockTimestamp(t *testing.T) {
+	block := NewBlock([]byte("value"))
+	require.False(t, block.Timestamp.IsZero())
+	require.Equal(t, block.Hash, block.CalculateHash())
+	require.True(t, EmptyBlock().Timestamp.IsZero())
+
+	bytes, err := json.Marshal(block)
+	require.NoError(t, err)
+	decoded := &Block{}
+	require.NoError(t, json.Unmarshal(bytes, decoded))
+	require.Equal(t, block, decoded)
+	require.Equal(t, decoded.Hash, decoded.CalculateHash())
+
+	// changing the timestamp changes the hash
+	decoded.Timestamp = decoded.Timestamp.Add(time.Second)
+	require.NotEqual(t, decoded.Hash, decoded.CalculateHash())
+
+	// blocks stored before timestamps were recorded have a zero timestamp and hash to the hash of their data
+	decoded = &Block{}
+	require.NoError(t, json.Unmarshal([]byte(`{"data":"dmFsdWU=","hash":null}`), decoded))
+	require.Equal(t, []byte("value"), decoded.Data)
+	require.True(t, decoded.Timestamp.IsZero())
+	require.Equal(t, calculateHash([]byte("value")), decoded.CalculateHash())
+}
+
+func TestBlockTimestampValidation(t *testing.T) {
+	bm, err := NewWithLevelDB(newTestDB(t))
+	require.NoError(t, err)
+	require.NoError(t, createTestBlocks(bm, 4))
+
+	// a timestamped block written directly is valid until its timestamp is tampered with
+	block := NewBlock([]byte("timestamped"))
+	bytes, err := bm.encodeBlock(block)
+	require.NoError(t, err)
+	require.NoError(t, bm.put([]byte("2"), bytes))
+	stored, err := bm.GetBlockByNumber(2)
+	require.NoError(t, err)
+	require.Equal(t, block.Timestamp, stored.Timestamp)
+	require.Equal(t, stored.Hash, bm.blockHash(stored))
+
+	block.Timestamp = block.Timestamp.Add(-time.Hour)
+	bytes, err = bm.encodeBlock(block)
+	require.NoError(t, err)
+	require.NoError(t, bm.put([]byte("2"), bytes))
+	stored, err = bm.GetBlockByNumber(2)
+	require.NoError(t, err)
+	require.NotEqual(t, stored.Hash, bm.blockHash(stored))
+}
+
+func TestWithClock(t *testing.T) {
+	now := time.Date(2026, time.March, 4, 5, 6, 7, 0, time.FixedZone("UTC+2", 2*60*60))
+	bm, err := NewWithLevelDB(newTestDB(t), WithClock(func() time.Time {
+		return now
+	}))
+	require.NoError(t, err)
+	require.NoError(t, bm.AddBlock("key", []byte("value")))
+
+	block, err := bm.GetBlock("key")
+	require.NoError(t, err)
+	require.Equal(t, now.UTC(), block.Timestamp)
+	require.Equal(t, block.CalculateHash(), block.Hash)
+
+	now = now.Add(time.Minute)
+	require.NoError(t, bm.UpdateBlock("key", []byte("value")))
+	updated, err := bm.GetBlock("key")
+	require.NoError(t, err)
+	require.Equal(t, now.UTC(), updated.Timestamp)
+	require.NotEqual(t, block.Hash, updated.Hash)
+}
+
+func TestBlockMetadata(t *testing.T) {
+	bm, err := NewWithLevelDB(newTestDB(t))
+	require.NoError(t, err)
+	require.NoError(t, bm.AddBlock("plain", []byte("value")))
+	require.NoError(t, bm.AddBlockWithMetadata("json", []byte("value"), map[string]string{
+		"content-type": "application/json",
+		"source":       "billing",
+	}))
+	require.NoError(t, bm.AddBlockWithMetadata("text", []byte("value"), map[string]string{
+		"content-type": "text/plain",
+		"source":       "billing",
+	}))
+
+	plain, err := bm.GetBlock("plain")
+	require.NoError(t, err)
+	require.Nil(t, plain.Metadata)
+	require.Equal(t, plain.CalculateHash(), plain.Hash)
+
+	jsonBlock, err := bm.GetBlock("json")
+	require.NoError(t, err)
+	require.Equal(t, "application/json", jsonBlock.Metadata["content-type"])
+	textBlock, err := bm.GetBlock("text")
+	require.NoError(t, err)
+
+	require.NotEqual(t, plain.Hash, jsonBlock.Hash)
+	require.NotEqual(t, jsonBlock.Hash, textBlock.Hash)
+	require.Equal(t, jsonBlock.Hash, jsonBlock.CalculateHash())
+
+	// the hash does not depend on the order the metadata was built in
+	block := &Block{Data: []byte("value"), Timestamp: jsonBlock.Timestamp, Metadata: map[string]string{}}
+	block.Metadata["source"] = "billing"
+	block.Metadata["content-type"] = "application/json"
+	require.Equal(t, jsonBlock.Hash, block.CalculateHash())
+
+	// entries cannot run together
+	require.NotEqual(t,
+		Block{Data: []byte("value"), Metadata: map[string]string{"ab": "c"}}.CalculateHash(),
+		Block{Data: []byte("value"), Metadata: map[string]string{"a": "bc"}}.CalculateHash())
+
+	ok, err := bm.IsValid()
+	require.NoError(t, err)
+	require.True(t, ok)
+}
@@ -1,21 +1,101 @@
 package blockmatrix
 
 import (
+	"context"
 	"crypto/sha256"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/olekukonko/tablewriter"
 	"github.com/syndtr/goleveldb/leveldb"
+	"hash"
+	"io"
 	"math"
 	"os"
 	"reflect"
 	"strconv"
+	"sync"
+	"time"
 )
 
 type (
-	// BlockMatrix implementation that stores blocks in a leveldb key-value database
+	// BlockMatrix implementation that stores blocks in a key-value Store.  A BlockMatrix is safe for concurrent
+	// use: the methods that change it (AddBlock, UpdateBlock, EraseBlock, EraseBlocks, DeleteBlock, ReserveBlock,
+	// FillReserved, Grow, Quarantine, RepairSlots, and committing a Batch or Tx) are serialized, and GetBlock, Matrix,
+	// Validate, and IsValid never observe a change half applied.  Other read methods may observe a change in progress.
 	BlockMatrix struct {
-		db *leveldb.DB
+		store Store
+		// ownedDB is the database opened by NewInMemory, closed by Close, nil if the database is owned by the caller
+		ownedDB *leveldb.DB
+		// jsonIndent is the indentation used when exporting JSON, an empty string produces compact JSON
+		jsonIndent string
+		// keyNormalizer maps equivalent application keys to the same key, nil leaves keys as is
+		keyNormalizer func(string) string
+		// keyTransform maps application keys to the keys stored in the database, nil stores keys as is
+		keyTransform func(string) string
+		// journal receives a record of every write before it is applied to the database, nil disables journaling
+		journal io.Writer
+		// selfTest checks the block placement arithmetic when the block matrix is opened
+		selfTest bool
+		// tracer records spans around block matrix operations
+		tracer Tracer
+		// observer is notified of added and erased blocks, failed validations, and operation durations
+		observer Observer
+		// logger receives structured log lines about growth, rejected erases, and hash mismatches
+		logger Logger
+		// hasher creates the hash function used for block, row, and column hashes
+		hasher func() hash.Hash
+		// validityPolicy decides which erases are valid
+		validityPolicy ValidityPolicy
+		// codecs encode the data of blocks in order before it is stored
+		codecs []Codec
+		// blockEncoding serializes blocks for storage, it is replaced by the encoding recorded in the info on open
+		blockEncoding BlockEncoding
+		// hmacKey authenticates every block with an HMAC-SHA256 of its data, nil disables authentication
+		hmacKey []byte
+		// maxSize is the largest size the block matrix may grow to, 0 means unbounded
+		maxSize int
+		// commitmentHashLength is the number of bytes the hashes of commitments are truncated to, 0 means not truncated
+		commitmentHashLength int
+		// maxKeyLength is the length in bytes of the longest database key a block may be added with, 0 means unbounded
+		maxKeyLength int
+		// validationConcurrency is the number of goroutines blocks are checked with during validation, 0 means
+		// GOMAXPROCS
+		validationConcurrency int
+		// refuseAliasedErase makes erasing a block that has aliases fail instead of removing the aliases
+		refuseAliasedErase bool
+		// infoCache holds the decoded info, nil unless the block matrix was created WithInfoCache
+		infoCache *infoCache
+		// writeLimiter paces AddBlock and AddBlocks, nil unless the block matrix was created WithWriteRateLimit
+		writeLimiter *rateLimiter
+		// backgroundTasks are run periodically until the block matrix is closed
+		backgroundTasks []backgroundTask
+		// background coordinates the goroutines running the background tasks
+		background background
+		// changed holds the numbers of the blocks written since the info was last stored
+		changed map[int]bool
+		// changeLogSeq is the sequence number of the next change log entry, 0 until the change log is first scanned
+		changeLogSeq uint64
+		// eraseJournalSeq is the sequence number of the next erase journal entry, 0 until the journal is first scanned
+		eraseJournalSeq uint64
+		// keyIndexSeq is the sequence number of the next key indexed, 0 until the key index is first scanned
+		keyIndexSeq uint64
+		// validateOnImport checks the hashes of imported blocks, rows, and columns instead of trusting the input
+		validateOnImport bool
+		// hashCollisionCheck indexes block hashes to detect blocks with the same hash but different data
+		hashCollisionCheck bool
+		// hashCollisionHandler decides the error for a hash collision, nil returns an error wrapping ErrHashCollision
+		hashCollisionHandler func(hash []byte, existing int) error
+		// cleanlyClosed is the clean shutdown flag as read when the block matrix was opened
+		cleanlyClosed bool
+		// unclean is true while the stored clean shutdown flag is cleared, until Close sets it again
+		unclean bool
+		// lazyEmptyBlocks leaves the empty slots added by growth unwritten, missing slots are read as empty blocks
+		lazyEmptyBlocks bool
+		// clock returns the time blocks are stamped with, nil uses the current time
+		clock func() time.Time
+		// mu is held for writing by the methods that change the block matrix and for reading by the methods that need a
+		// consistent view of it
+		mu sync.RWMutex
 	}
 
 	// BlockMatrixInfo stores information about the block matrix
@@ -28,179 +108,765 @@ type (
 		Rows [][]byte `json:"rows"`
 		// Cols stores the hashes of each column in the block matrix
 		Cols [][]byte `json:"cols"`
+		// ID is a UUID generated when the block matrix is created, it identifies the block matrix across copies
+		ID string `json:"id,omitempty"`
+		// Hasher identifies the hash function of the block matrix, it is empty for SHA-256
+		Hasher string `json:"hasher,omitempty"`
+		// BlockEncoding names the encoding blocks are stored with, it is empty for JSON
+		BlockEncoding string `json:"block_encoding,omitempty"`
 	}
 )
 
 var (
 	InfoKey = []byte(fmt.Sprint("info"))
+
+	// ErrStorage matches every error caused by a failure of the database rather than by the state of the block matrix.
+	// Operations that fail with a storage error may succeed when retried.
+	ErrStorage = errors.New("storage error")
+
+	// ErrNotFound is returned when a key or block does not exist.
+	ErrNotFound = leveldb.ErrNotFound
+
+	// ErrOutOfRange is returned when a block number, row or column index, or byte range is outside the block matrix or
+	// block.
+	ErrOutOfRange = errors.New("out of range")
+
+	// ErrHashMismatch is returned when a block's hash does not match its data.
+	ErrHashMismatch = errors.New("hash mismatch")
+
+	// ErrMalformedJournal is returned when a journal record cannot be decoded.
+	ErrMalformedJournal = errors.New("malformed journal")
+
+	// ErrMalformedArchive is returned when a block matrix archive is missing entries or has entries that cannot be
+	// decoded.
+	ErrMalformedArchive = errors.New("malformed block matrix archive")
+
+	// ErrMatrixExists is returned when creating a block matrix in a database that already has one.
+	ErrMatrixExists = errors.New("database already has a block matrix")
+
+	// ErrAuthenticationFailed is returned when a block's MAC does not match its data.
+	ErrAuthenticationFailed = errors.New("block authentication failed")
+
+	// ErrInvalidErase is returned when the validity policy rejects an erase.
+	ErrInvalidErase = errors.New("invalid erase")
+
+	// ErrMaxSizeExceeded is returned when adding a block would grow the block matrix beyond its maximum size.
+	ErrMaxSizeExceeded = errors.New("maximum block matrix size exceeded")
+
+	// ErrUnknownRoot is returned when a root hash is not in the change log.
+	ErrUnknownRoot = errors.New("unknown root hash")
+
+	// ErrKeyCollision is returned when two different keys are transformed to the same database key.
+	ErrKeyCollision = errors.New("key collision")
+
+	// ErrNotReserved is returned when filling a block that was not reserved.
+	ErrNotReserved = errors.New("block not reserved")
+
+	// ErrHashCollision is returned when a block would be written with the same hash as another block with different
+	// data.
+	ErrHashCollision = errors.New("hash collision")
+
+	// ErrReplicaDiverged is returned when an incremental replication targets a block matrix that is not at the root the
+	// changes are replicated from.
+	ErrReplicaDiverged = errors.New("replica diverged")
+
+	// ErrHasherMismatch is returned when opening a block matrix with a different hash function than it was created with.
+	ErrHasherMismatch = errors.New("hash function does not match the block matrix")
+
+	// ErrKeyTooLong is returned when adding a block whose database key is longer than the maximum key length.
+	ErrKeyTooLong = errors.New("key too long")
+
+	// ErrMalformedBackup is returned when a backup file is truncated, corrupted, or cannot be decoded.
+	ErrMalformedBackup = errors.New("malformed backup")
+
+	// ErrKeyExists is returned when adding an alias that is already a key or an alias.
+	ErrKeyExists = errors.New("key already exists")
+
+	// ErrHasAliases is returned when erasing a block that has aliases with WithRefuseAliasedErase.
+	ErrHasAliases = errors.New("block has aliases")
+
+	// ErrUnknownBlockEncoding is returned when opening a block matrix created with a block encoding that is neither
+	// built in nor configured with WithBlockEncoding.
+	ErrUnknownBlockEncoding = errors.New("unknown block encoding")
+
+	// ErrMalformedFrozen is returned when a frozen block matrix file is truncated or its header is inconsistent.
+	ErrMalformedFrozen = errors.New("malformed frozen block matrix")
+
+	// ErrReservedKey is returned when adding a block or alias whose database key is a block number or has the prefix of
+	// an entry the block matrix stores for its own bookkeeping.
+	ErrReservedKey = errors.New("reserved key")
 )
 
-// New creates a new block matrix with the given leveldb database.  If the database does not yet have a block matrix,
-// the block matrix info entry is created for an empty block matrix.  An empty block matrix has a size of 1.
-func New(db *leveldb.DB) (*BlockMatrix, error) {
-	if ok, err := db.Has(InfoKey, nil); err != nil {
-		return nil, fmt.Errorf("error checking if database has block matrix info")
+// New creates a new block matrix with the given store and options.  If the store does not yet have a block matrix, the
+// block matrix info entry is created for an empty block matrix.  An empty block matrix has a size of 1.  Use
+// NewWithLevelDB for a block matrix stored in a leveldb database.
+func New(store Store, opts ...Option) (*BlockMatrix, error) {
+	bm := &BlockMatrix{
+		store:          store,
+		tracer:         noopTracer{},
+		observer:       noopObserver{},
+		logger:         noopLogger{},
+		hasher:         sha256.New,
+		validityPolicy: singleEraseValidityPolicy{},
+		maxKeyLength:   DefaultMaxKeyLength,
+		blockEncoding:  binaryBlockEncoding{},
+	}
+	for _, opt := range opts {
+		opt(bm)
+	}
+
+	if bm.commitmentHashLength < 0 {
+		return nil, fmt.Errorf("commitment hash length %d is negative", bm.commitmentHashLength)
+	}
+
+	var err error
+	if bm.cleanlyClosed, err = bm.readCleanShutdown(); err != nil {
+		return nil, fmt.Errorf("error reading clean shutdown flag: %w", err)
+	}
+	bm.unclean = !bm.cleanlyClosed
+
+	if ok, err := bm.has(InfoKey); err != nil {
+		return nil, fmt.Errorf("error checking if database has block matrix info: %w", err)
 	} else if !ok {
-		if err = initInfo(db); err != nil {
+		if err = bm.initInfo(); err != nil {
 			return nil, fmt.Errorf("error initializing block matrix info %w", err)
 		}
+	}
 
-		return &BlockMatrix{db: db}, nil
+	info, err := bm.GetBlockMatrixInfo()
+	if err != nil {
+		return nil, err
 	}
 
-	return &BlockMatrix{db: db}, nil
-}
+	if err = bm.checkHasher(info); err != nil {
+		return nil, err
+	}
 
-func initInfo(db *leveldb.DB) error {
-	info := &BlockMatrixInfo{
-		Size: 1,
-		Rows: make([][]byte, 1),
-		Cols: make([][]byte, 1),
+	if bm.blockEncoding, err = bm.recordedBlockEncoding(info); err != nil {
+		return nil, err
+	}
+
+	if err = bm.buildKeyIndex(info); err != nil {
+		return nil, fmt.Errorf("error building key index: %w", err)
+	}
+
+	if bm.selfTest {
+		if err = checkPlacement(info.Size); err != nil {
+			return nil, fmt.Errorf("block matrix self test failed: %w", err)
+		}
+
+		if err = checkCrossConsistency(info.Size); err != nil {
+			return nil, fmt.Errorf("block matrix self test failed: %w", err)
+		}
 	}
 
-	var (
-		bytes []byte
-		err   error
-	)
+	bm.startBackground()
 
-	if bytes, err = json.Marshal(info); err != nil {
-		return fmt.Errorf("error marshaling block matrix info: %w", err)
+	return bm, nil
+}
+
+func (b *BlockMatrix) initInfo() error {
+	id, err := newMatrixID()
+	if err != nil {
+		return fmt.Errorf("error generating block matrix id: %w", err)
+	}
+
+	info := &BlockMatrixInfo{
+		Size:          1,
+		Rows:          make([][]byte, 1),
+		Cols:          make([][]byte, 1),
+		ID:            id,
+		Hasher:        hasherID(b.hasher),
+		BlockEncoding: blockEncodingID(b.blockEncoding),
 	}
 
-	if err = db.Put([]byte("info"), bytes, nil); err != nil {
-		return fmt.Errorf("error putting block matrix info bytes: %w", err)
+	// store the hashes of the empty row and column so an empty block matrix is valid
+	if err := b.updateAllHashes(info); err != nil {
+		return fmt.Errorf("error putting block matrix info: %w", err)
 	}
 
 	return nil
 }
 
-// Size computes the size of a block matrix with the given block count.  To find the size of the block matrix square root
+// Size computes the size of a block matrix with the given block count, see MinSizeFor.
+func (b *BlockMatrix) Size(blockCount int) int {
+	return MinSizeFor(blockCount)
+}
+
+// MinSizeFor returns the smallest size of a block matrix that fits the given block count.  To find the size square root
 // the block count and round up.  It's possible the computed size does not have enough available blocks and in this case,
 // the size is incremented once to fit all blocks.
-func (b *BlockMatrix) Size(blockCount int) int {
+func MinSizeFor(blockCount int) int {
+	// an empty block matrix has a size of 1
+	if blockCount == 0 {
+		return 1
+	}
+
 	// calculate matrix size which is sqrt(blockCount) rounded up
 	size := int(math.Ceil(math.Sqrt(float64(blockCount))))
 	// if the number of available blocks (size^2 - size) is less than the block count increase the size by 1
-	if size*size-size < blockCount {
+	if MaxBlocksFor(size) < blockCount {
 		size++
 	}
 
 	return size
 }
 
+// MaxBlocksFor returns the number of blocks that fit in a block matrix of the given size, which is every cell except the
+// diagonal.
+func MaxBlocksFor(size int) int {
+	return size*size - size
+}
+
+// capacity returns the number of blocks that fit in a block matrix of the given size, see MaxBlocksFor.
+func capacity(size int) int {
+	return MaxBlocksFor(size)
+}
+
 // AddBlock adds a block to the block matrix with the given key and data.  A block effectively has two entries in the
-// key value database: key-> blockNumber, blockNumber -> Block.
+// key value database: key-> blockNumber, blockNumber -> Block.  Since they share the database with the block entries
+// and the block matrix's own bookkeeping, keys stored as a number or with an internal prefix such as "free:" are
+// rejected with ErrReservedKey.
 func (b *BlockMatrix) AddBlock(key string, data []byte) error {
-	info, err := b.GetBlockMatrixInfo()
+	return b.AddBlockWithMetadata(key, data, nil)
+}
+
+// AddBlockWithMetadata adds a block to the block matrix like AddBlock, tagging it with the given metadata.  The metadata
+// is part of the block's hash, so blocks with the same data and different metadata have different hashes.
+func (b *BlockMatrix) AddBlockWithMetadata(key string, data []byte, meta map[string]string) error {
+	return b.addBlockWithMetadata(context.Background(), key, data, meta)
+}
+
+// AddBlockContext adds a block to the block matrix like AddBlock.  If ctx is done before the block matrix's write lock
+// is acquired, including while waiting for WithWriteRateLimit, the block is not added and the context's error is
+// returned.  Once the block is being written the write
+// is not interrupted, so the block matrix is never left with a partially added block.
+func (b *BlockMatrix) AddBlockContext(ctx context.Context, key string, data []byte) error {
+	return b.addBlockWithMetadata(ctx, key, data, nil)
+}
+
+func (b *BlockMatrix) addBlockWithMetadata(ctx context.Context, key string, data []byte,
+	meta map[string]string) (err error) {
+	ctx, end := b.startSpanContext(ctx, "AddBlock")
+	defer end()
+	defer b.observeOperation("AddBlock")(&err)
+
+	if err = ctx.Err(); err != nil {
+		return err
+	}
+
+	if err = b.throttleWrites(ctx, 1); err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err = ctx.Err(); err != nil {
+		return err
+	}
+
+	blockNum, err := b.addBlock(key, b.newBlockWithMetadata(data, meta))
 	if err != nil {
+		b.logger.Error("error adding block", "key", key, "error", err)
 		return err
 	}
 
-	// increment block counter
-	info.BlockCount++
+	row, col := locateBlock(blockNum)
+	b.logger.Debug("added block", "key", key, "block", blockNum, "row", row, "col", col)
+	b.observer.BlockAdded(blockNum)
+	return nil
+}
 
-	// check if the block count causes the size to increase
-	newSize := b.Size(info.BlockCount)
-	if newSize > info.Size {
-		if err = b.updateBlockMatrixSize(info, newSize); err != nil {
-			return err
+// addBlock stores the block in the lowest free slot, or under the next block number if no slot is free, maps key to it,
+// and updates the hashes, growing the block matrix if needed.  The block, its key, the info, and the change log entry
+// are written together in a single atomic write, so a failure part way through leaves the block matrix unchanged.  The
+// number assigned to the block is returned.
+func (b *BlockMatrix) addBlock(key string, block *Block) (int, error) {
+	info, err := b.GetBlockMatrixInfo()
+	if err != nil {
+		return 0, err
+	}
+
+	state := &txState{bm: b, writes: make(map[string][]byte)}
+	blockNum, grew, err := state.add(info, key, block)
+	if err != nil {
+		return 0, err
+	}
+
+	row, col := locateBlock(blockNum)
+	if err = state.updateHashes(info, map[int]bool{row: true}, map[int]bool{col: true}, grew); err != nil {
+		return 0, err
+	}
+
+	if err = state.write(info); err != nil {
+		return 0, err
+	}
+
+	return blockNum, nil
+}
+
+// storeBlock stores the block in the lowest free slot, or under the next block number if no slot is free, and maps key
+// to it, growing the block matrix if needed.  The info is updated in place but neither the hashes nor the info are
+// stored, that is left to the caller.  The number assigned to the block is returned along with whether the block matrix
+// grew.
+func (b *BlockMatrix) storeBlock(info *BlockMatrixInfo, key string, block *Block) (int, bool, error) {
+	key = b.normalizeKey(key)
+	dbKey := b.dbKey(key)
+	if err := b.checkKeyLength(key, dbKey); err != nil {
+		return 0, false, err
+	}
+
+	if err := checkReservedKey(key, dbKey); err != nil {
+		return 0, false, err
+	}
+
+	if err := b.checkKeyCollision(key, dbKey); err != nil {
+		return 0, false, err
+	}
+
+	if err := b.checkHashCollision(block, b.get, b.getSlot); err != nil {
+		return 0, false, err
+	}
+
+	blockNum, err := b.lowestFreeSlot(func(int) bool { return false })
+	if err != nil {
+		return 0, false, err
+	}
+
+	grow := false
+	if blockNum > 0 {
+		if err = b.delete(freeEntry(blockNum)); err != nil {
+			return 0, false, err
 		}
+		info.BlockCount++
+	} else {
+		// check if the block count causes the size to increase
+		newSize := b.Size(info.BlockCount + 1)
+		grow = newSize > info.Size
+		if grow && b.maxSize > 0 && newSize > b.maxSize {
+			return 0, false, fmt.Errorf("%w: adding block %d requires size %d, the maximum is %d",
+				ErrMaxSizeExceeded, info.BlockCount+1, newSize, b.maxSize)
+		}
+
+		// increment block counter
+		info.BlockCount++
+		if grow {
+			if err = b.updateBlockMatrixSize(info, newSize); err != nil {
+				return 0, false, err
+			}
+		}
+
+		blockNum = info.BlockCount
 	}
 
 	// serialize block number to put in to db
-	blockNum := info.BlockCount
 	blockNumBytes := []byte(strconv.Itoa(blockNum))
 
-	// construct block
-	block := NewBlock(data)
-
 	// serialize block
-	bytes, err := json.Marshal(block)
+	bytes, err := b.encodeBlock(block)
 	if err != nil {
-		return err
+		return 0, false, err
 	}
 
 	// put key -> blockNum
-	if err = b.db.Put([]byte(key), blockNumBytes, nil); err != nil {
-		return err
+	if err = b.put(dbKey, blockNumBytes); err != nil {
+		return 0, false, err
+	}
+
+	// remember the original key so collisions between transformed keys can be detected
+	if b.keyTransform != nil {
+		if err = b.put(originalKeyEntry(dbKey), []byte(key)); err != nil {
+			return 0, false, err
+		}
+	}
+
+	if err = b.indexKey(key, dbKey); err != nil {
+		return 0, false, err
 	}
 
 	// put blockNum -> block
-	if err = b.db.Put(blockNumBytes, bytes, nil); err != nil {
-		return err
+	if err = b.put(blockNumBytes, bytes); err != nil {
+		return 0, false, err
 	}
 
-	// update row and col hashes
-	return b.updateBlockMatrixInfo(info, blockNum)
+	if err = b.indexBlockHash(blockNum, block); err != nil {
+		return 0, false, err
+	}
+
+	return blockNum, grow, nil
 }
 
 func (b *BlockMatrix) updateBlockMatrixInfo(info *BlockMatrixInfo, blockNum int) error {
-	row, col := b.locateBlock(blockNum)
+	row, col := locateBlock(blockNum)
 
 	var err error
 
 	// calculate row hash
-	info.Rows[row], err = b.calculateRowHash(row, info.BlockCount)
+	info.Rows[row], err = b.calculateRowHash(row, info.Size)
 	if err != nil {
 		return err
 	}
 
 	// calculate col hash
-	info.Cols[col], err = b.calculateColumnHash(col, info.BlockCount)
+	info.Cols[col], err = b.calculateColumnHash(col, info.Size)
 	if err != nil {
 		return err
 	}
 
-	var bytes []byte
-	if bytes, err = json.Marshal(info); err != nil {
+	return b.putBlockMatrixInfo(info)
+}
+
+// updateAllHashes recalculates the hash of every row and column in the block matrix and stores the updated info.
+func (b *BlockMatrix) updateAllHashes(info *BlockMatrixInfo) error {
+	var err error
+	for i := 0; i < info.Size; i++ {
+		if info.Rows[i], err = b.calculateRowHash(i, info.Size); err != nil {
+			return err
+		}
+
+		if info.Cols[i], err = b.calculateColumnHash(i, info.Size); err != nil {
+			return err
+		}
+	}
+
+	return b.putBlockMatrixInfo(info)
+}
+
+func (b *BlockMatrix) putBlockMatrixInfo(info *BlockMatrixInfo) error {
+	bytes, err := encodeInfo(info)
+	if err != nil {
 		return err
 	}
 
-	return b.db.Put([]byte("info"), bytes, nil)
+	if err = b.put([]byte("info"), bytes); err != nil {
+		return err
+	}
+	b.cacheInfo(info)
+
+	return b.recordChanges(info)
+}
+
+// put writes the key value pair to the database, recording it in the journal first if one is configured.
+func (b *BlockMatrix) put(key []byte, value []byte) error {
+	if err := b.markUnclean(); err != nil {
+		return err
+	}
+
+	if err := b.writeJournal(journalPut, key, value); err != nil {
+		return err
+	}
+
+	b.invalidateInfo(key)
+	defer b.invalidateInfo(key)
+
+	if err := b.store.Put(key, value); err != nil {
+		return storageErr(err)
+	}
+
+	b.trackChange(key)
+	return nil
+}
+
+// delete removes the key from the database, recording it in the journal first if one is configured.
+func (b *BlockMatrix) delete(key []byte) error {
+	if err := b.markUnclean(); err != nil {
+		return err
+	}
+
+	if err := b.writeJournal(journalDelete, key, nil); err != nil {
+		return err
+	}
+
+	b.invalidateInfo(key)
+	defer b.invalidateInfo(key)
+
+	if err := b.store.Delete(key); err != nil {
+		return storageErr(err)
+	}
+
+	b.trackChange(key)
+	return nil
+}
+
+// write applies the writes to the database atomically, recording them in the journal first if one is configured.
+func (b *BlockMatrix) write(writes []StoreWrite) error {
+	if err := b.markUnclean(); err != nil {
+		return err
+	}
+
+	for _, write := range writes {
+		var err error
+		if write.Delete {
+			err = b.writeJournal(journalDelete, write.Key, nil)
+		} else {
+			err = b.writeJournal(journalPut, write.Key, write.Value)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, write := range writes {
+		b.invalidateInfo(write.Key)
+		defer b.invalidateInfo(write.Key)
+	}
+
+	return storageErr(b.store.Write(writes))
+}
+
+// get reads the value of the key from the database.
+func (b *BlockMatrix) get(key []byte) ([]byte, error) {
+	value, err := b.store.Get(key)
+	return value, storageErr(err)
+}
+
+// has returns true if the database has the key.
+func (b *BlockMatrix) has(key []byte) (bool, error) {
+	ok, err := b.store.Has(key)
+	return ok, storageErr(err)
 }
 
-// GetBlock returns the block associated with the given key.
+// iterate calls fn with every entry whose key starts with prefix in ascending key order.  Errors returned by fn are
+// returned as is, errors from the store are wrapped as storage errors.
+func (b *BlockMatrix) iterate(prefix []byte, fn func(key []byte, value []byte) error) error {
+	var fnErr error
+	err := b.store.Iterate(prefix, func(key []byte, value []byte) error {
+		fnErr = fn(key, value)
+		return fnErr
+	})
+	if fnErr != nil {
+		return fnErr
+	}
+
+	return storageErr(err)
+}
+
+// GetBlock returns the block associated with the given key.  If a key transform is configured the block is looked up
+// by the transformed key.
 func (b *BlockMatrix) GetBlock(key string) (*Block, error) {
-	bytes, err := b.db.Get([]byte(key), nil)
+	defer b.startSpan("GetBlock")()
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	blockNum, err := b.BlockNumber(key)
 	if err != nil {
 		return nil, err
 	}
 
-	if bytes, err = b.db.Get(bytes, nil); err != nil {
-		return nil, err
+	return b.GetBlockByNumber(blockNum)
+}
+
+// GetBlockWithLocation returns the block associated with the given key along with its row and column in the block
+// matrix.
+func (b *BlockMatrix) GetBlockWithLocation(key string) (*Block, int, int, error) {
+	defer b.startSpan("GetBlockWithLocation")()
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	blockNum, err := b.BlockNumber(key)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+
+	block, err := b.GetBlockByNumber(blockNum)
+	if err != nil {
+		return nil, 0, 0, err
 	}
 
-	block := &Block{}
-	if err = json.Unmarshal(bytes, block); err != nil {
+	row, col := locateBlock(blockNum)
+	return block, row, col, nil
+}
+
+// GetBlockRange returns length bytes of the data of the block associated with the given key, starting at offset.  The
+// whole block is read and decoded with the block encoding and codecs of the block matrix, and its MAC is checked if
+// WithHMAC is configured, but the returned bytes share the decoded data instead of being copied.  An error is returned
+// if the range is not within the block's data.
+func (b *BlockMatrix) GetBlockRange(key string, offset, length int) ([]byte, error) {
+	block, err := b.GetBlock(key)
+	if err != nil {
 		return nil, err
 	}
 
-	return block, nil
+	if offset < 0 || length < 0 || offset+length > len(block.Data) {
+		return nil, fmt.Errorf("%w: range [%d, %d) of block %q with %d bytes", ErrOutOfRange, offset, offset+length, key,
+			len(block.Data))
+	}
+
+	return block.Data[offset : offset+length], nil
 }
 
 // GetBlockByNumber returns the block with the given block number.
 func (b *BlockMatrix) GetBlockByNumber(num int) (*Block, error) {
-	bytes, err := b.db.Get([]byte(fmt.Sprint(num)), nil)
+	if b.lazyEmptyBlocks {
+		// a missing slot is only an empty block if it is within the capacity of the block matrix
+		info, err := b.GetBlockMatrixInfo()
+		if err != nil {
+			return nil, err
+		}
+
+		if num < 1 || num > capacity(info.Size) {
+			return nil, fmt.Errorf("%w: block number %d for block matrix of size %d", ErrNotFound, num, info.Size)
+		}
+	}
+
+	return b.getSlot(num)
+}
+
+// getSlot reads the block with the given number, which must be within the capacity of the block matrix.
+func (b *BlockMatrix) getSlot(blockNum int) (*Block, error) {
+	return b.readBlock(b.get, blockNum)
+}
+
+// readBlock reads the block with the given number using get.  With WithLazyEmptyBlocks a slot that was never written is
+// read as an empty block, so callers must only read block numbers within the capacity of the block matrix.  A slot freed
+// by DeleteBlock has no block entry and is read as an empty block too.
+func (b *BlockMatrix) readBlock(get func(key []byte) ([]byte, error), blockNum int) (*Block, error) {
+	bytes, err := get([]byte(strconv.Itoa(blockNum)))
+	if errors.Is(err, ErrNotFound) && (b.lazyEmptyBlocks || isFreeSlot(get, blockNum)) {
+		return b.emptyBlock(), nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	return b.decodeBlock(bytes)
+}
+
+// GetBlocksByNumbers returns the blocks with the given block numbers, keyed by number.  The result is consistent even if
+// the block matrix is modified concurrently: if the store is a Snapshotter, such as the leveldb store, every block is
+// read from a single snapshot without waiting for writers, otherwise the blocks are read while holding the block
+// matrix's read lock.  An error is returned if any number is outside the capacity of the block matrix.
+func (b *BlockMatrix) GetBlocksByNumbers(nums []int) (map[int]*Block, error) {
+	if snapshotter, ok := b.store.(Snapshotter); ok {
+		return b.getBlocksFromSnapshot(snapshotter, nums)
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	return b.getBlocksByNumbers(nums)
+}
+
+// getBlocksFromSnapshot returns the blocks with the given block numbers like GetBlocksByNumbers, reading the info and
+// the blocks from a snapshot of the store.
+func (b *BlockMatrix) getBlocksFromSnapshot(snapshotter Snapshotter, nums []int) (map[int]*Block, error) {
+	snapshot, err := snapshotter.Snapshot()
+	if err != nil {
+		return nil, storageErr(err)
+	}
+	defer snapshot.Release()
+
+	get := func(key []byte) ([]byte, error) {
+		value, err := snapshot.Get(key)
+		return value, storageErr(err)
+	}
+
+	infoBytes, err := get(InfoKey)
 	if err != nil {
 		return nil, err
 	}
 
-	block := &Block{}
-	if err = json.Unmarshal(bytes, block); err != nil {
+	info, err := decodeInfo(infoBytes)
+	if err != nil {
 		return nil, err
 	}
 
-	return block, nil
+	return b.readBlocks(get, info, nums)
+}
+
+// getBlocksByNumbers returns the blocks with the given block numbers like GetBlocksByNumbers without locking the block
+// matrix.
+func (b *BlockMatrix) getBlocksByNumbers(nums []int) (map[int]*Block, error) {
+	info, err := b.GetBlockMatrixInfo()
+	if err != nil {
+		return nil, err
+	}
+
+	return b.readBlocks(b.get, info, nums)
+}
+
+// readBlocks reads the blocks with the given block numbers of the block matrix described by info using get.
+func (b *BlockMatrix) readBlocks(get func(key []byte) ([]byte, error), info *BlockMatrixInfo,
+	nums []int) (map[int]*Block, error) {
+	blocks := make(map[int]*Block, len(nums))
+	for _, num := range nums {
+		if num < 1 || num > capacity(info.Size) {
+			return nil, fmt.Errorf("%w: block number %d for block matrix of size %d", ErrOutOfRange, num, info.Size)
+		}
+
+		block, err := b.readBlock(get, num)
+		if err != nil {
+			return nil, err
+		}
+
+		blocks[num] = block
+	}
+
+	return blocks, nil
+}
+
+// ForEachBlock calls fn with every block numbered 1 through the last block in use that is not empty, in block number
+// order, skipping erased blocks and unused or freed slots.  Iteration stops at the first error returned by fn, which is
+// returned.  The block matrix's read lock is held while iterating so the blocks visited are a consistent snapshot, fn
+// must not modify the block matrix or call its methods that lock it.
+func (b *BlockMatrix) ForEachBlock(fn func(blockNum int, block *Block) error) error {
+	defer b.startSpan("ForEachBlock")()
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	return b.forEachBlock(fn)
+}
+
+// forEachBlock calls fn with every block that is not empty like ForEachBlock without locking the block matrix.
+func (b *BlockMatrix) forEachBlock(fn func(blockNum int, block *Block) error) error {
+	info, err := b.GetBlockMatrixInfo()
+	if err != nil {
+		return err
+	}
+
+	last, err := b.lastBlock(info)
+	if err != nil {
+		return err
+	}
+
+	for blockNum := 1; blockNum <= last; blockNum++ {
+		block, err := b.getSlot(blockNum)
+		if err != nil {
+			return err
+		}
+
+		if block.IsEmpty() {
+			continue
+		}
+
+		if err = fn(blockNum, block); err != nil {
+			return err
+		}
+	}
+
+	return nil
 }
 
 // BlockNumber returns the block number of the given key.
 func (b *BlockMatrix) BlockNumber(key string) (int, error) {
-	bytes, err := b.db.Get([]byte(key), nil)
+	bytes, err := b.get(b.dbKey(key))
+	if errors.Is(err, ErrNotFound) {
+		// the key may be an alias
+		var target string
+		if target, err = b.resolveAlias(key); err != nil {
+			return -1, err
+		}
+
+		bytes, err = b.get(b.dbKey(target))
+	}
 	if err != nil {
 		return -1, err
 	}
 
-	num, err := strconv.Atoi(string(bytes[0]))
+	num, err := strconv.Atoi(string(bytes))
 	if err != nil {
 		return -1, err
 	}
@@ -208,60 +874,233 @@ func (b *BlockMatrix) BlockNumber(key string) (int, error) {
 	return num, nil
 }
 
-// EraseBlock erases the data from the block associated with the given key.
-func (b *BlockMatrix) EraseBlock(key string) error {
-	blockNum, err := b.BlockNumber(key)
+// UpdateBlock replaces the data of the block associated with the given key and updates the hashes of its row and
+// column.  The block keeps its block number and its metadata and is no longer reserved afterwards.  The block, the info,
+// and the change log entry are written in a single atomic write like AddBlock.  An error is returned if the key does not
+// exist, a new block is never added.
+func (b *BlockMatrix) UpdateBlock(key string, data []byte) error {
+	defer b.startSpan("UpdateBlock")()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	key, err := b.resolveAlias(key)
 	if err != nil {
 		return err
 	}
 
-	// delete key
-	if err = b.db.Delete([]byte(key), nil); err != nil {
+	info, err := b.GetBlockMatrixInfo()
+	if err != nil {
 		return err
 	}
 
-	// erase block
-	bytes, err := json.Marshal(EmptyBlock())
+	state := &txState{bm: b, writes: make(map[string][]byte)}
+	blockNum, err := state.replace(key, data)
 	if err != nil {
 		return err
 	}
 
-	if err = b.db.Put([]byte(fmt.Sprint(blockNum)), bytes, nil); err != nil {
+	row, col := locateBlock(blockNum)
+	if err = state.updateHashes(info, map[int]bool{row: true}, map[int]bool{col: true}, false); err != nil {
 		return err
 	}
 
-	info, err := b.GetBlockMatrixInfo()
+	return state.write(info)
+}
+
+// EraseBlock erases the data from the block associated with the given key.
+func (b *BlockMatrix) EraseBlock(key string) error {
+	defer b.startSpan("EraseBlock")()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	_, err := b.eraseBlock(key)
+	return err
+}
+
+// EraseBlockAt erases the data from the block associated with the given key like EraseBlock and returns the row and
+// column of the erased block, which are the only row and column whose hashes the erase changed.  The coordinates are
+// returned even if the validity policy rejects the erase, in which case the block remains erased.
+func (b *BlockMatrix) EraseBlockAt(key string) (row int, col int, err error) {
+	defer b.startSpan("EraseBlockAt")()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	blockNum, err := b.eraseBlock(key)
+	if blockNum == 0 {
+		return -1, -1, err
+	}
+
+	row, col = locateBlock(blockNum)
+	return row, col, err
+}
+
+// eraseBlock erases the block associated with key, updates the hashes of its row and column, and checks the erase with
+// the validity policy.  The number of the erased block is returned, 0 if nothing was erased.
+func (b *BlockMatrix) eraseBlock(key string) (int, error) {
+	blockNum, err := b.clearBlock(key)
 	if err != nil {
-		return err
+		return 0, err
 	}
+	b.observer.BlockErased(blockNum)
 
-	oldRowHashes := make([][]byte, len(info.Rows))
-	oldColHashes := make([][]byte, len(info.Cols))
-	copy(oldRowHashes, info.Rows)
-	copy(oldColHashes, info.Cols)
+	info, err := b.GetBlockMatrixInfo()
+	if err != nil {
+		return blockNum, err
+	}
+	before := copyInfo(info)
 
 	// update row/col hashes
 	if err = b.updateBlockMatrixInfo(info, blockNum); err != nil {
+		return blockNum, err
+	}
+
+	if err = b.journalErase(before, info, []int{blockNum}); err != nil {
+		return blockNum, err
+	}
+
+	return blockNum, b.checkErase(before, info)
+}
+
+// EraseBlocks erases the data from the blocks associated with the given keys and updates the affected row and column
+// hashes once.  The erase is checked as a whole by the validity policy, so the default policy rejects erasing more
+// than one block at a time.  Like EraseBlock, the blocks remain erased if the policy rejects the erase.
+func (b *BlockMatrix) EraseBlocks(keys ...string) error {
+	defer b.startSpan("EraseBlocks")()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	info, err := b.GetBlockMatrixInfo()
+	if err != nil {
+		return err
+	}
+	before := copyInfo(info)
+
+	batch := b.NewBatch()
+	blockNums := make([]int, 0, len(keys))
+	for _, key := range keys {
+		batch.EraseBlock(key)
+
+		// a missing key fails the batch below
+		if blockNum, err := b.BlockNumber(key); err == nil {
+			blockNums = append(blockNums, blockNum)
+		}
+	}
+
+	if err = batch.commit(); err != nil {
 		return err
 	}
 
-	var ok bool
-	if ok, err = b.checkValidErase(info, oldRowHashes, oldColHashes); err != nil {
+	for _, blockNum := range blockNums {
+		b.observer.BlockErased(blockNum)
+	}
+
+	after, err := b.GetBlockMatrixInfo()
+	if err != nil {
+		return err
+	}
+
+	if err = b.journalErase(before, after, blockNums); err != nil {
+		return err
+	}
+
+	return b.checkErase(before, after)
+}
+
+// checkErase returns an error if the validity policy rejects the change from before to after.
+func (b *BlockMatrix) checkErase(before *BlockMatrixInfo, after *BlockMatrixInfo) error {
+	if ok, err := b.validityPolicy.CheckErase(before, after); err != nil {
 		return err
 	} else if !ok {
-		return fmt.Errorf("invalid erase, more than one row/column was affected")
+		b.logger.Warn("erase rejected by the validity policy", "rows", changedIndices(before.Rows, after.Rows),
+			"cols", changedIndices(before.Cols, after.Cols))
+		b.observer.ValidationFailed("erase rejected by the validity policy")
+		return fmt.Errorf("%w, the changed row and column hashes were rejected by the validity policy", ErrInvalidErase)
 	}
 
 	return nil
 }
 
-func (b *BlockMatrix) checkValidErase(info *BlockMatrixInfo, oldRowHashes [][]byte, oldColHashes [][]byte) (bool, error) {
+// clearBlock removes the key's entries and replaces its block with an empty block without updating any hashes.  The
+// number of the cleared block is returned.
+func (b *BlockMatrix) clearBlock(key string) (int, error) {
+	blockNum, err := b.removeKeyEntries(key)
+	if err != nil {
+		return 0, err
+	}
+
+	// erase block
+	bytes, err := b.encodeBlock(b.emptyBlock())
+	if err != nil {
+		return 0, err
+	}
+
+	if err = b.put([]byte(fmt.Sprint(blockNum)), bytes); err != nil {
+		return 0, err
+	}
+
+	return blockNum, nil
+}
+
+// removeKeyEntries removes the key's entries, its aliases, and the reservation of its block without touching the block.
+// The number of the key's block is returned.
+func (b *BlockMatrix) removeKeyEntries(key string) (int, error) {
+	key, err := b.resolveAlias(key)
+	if err != nil {
+		return 0, err
+	}
+
+	blockNum, err := b.BlockNumber(key)
+	if err != nil {
+		return 0, err
+	}
+
+	aliasEntries, err := b.erasedAliasEntries(key)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, entry := range aliasEntries {
+		if err = b.delete(entry); err != nil {
+			return 0, err
+		}
+	}
+
+	// delete key
+	dbKey := b.dbKey(key)
+	if err = b.delete(dbKey); err != nil {
+		return 0, err
+	}
+
+	if b.keyTransform != nil {
+		if err = b.delete(originalKeyEntry(dbKey)); err != nil {
+			return 0, err
+		}
+	}
+
+	if err = b.delete(keyIndexEntry(dbKey)); err != nil {
+		return 0, err
+	}
+
+	// an erased block is no longer reserved
+	if err = b.delete(reservedEntry(blockNum)); err != nil {
+		return 0, err
+	}
+
+	return blockNum, nil
+}
+
+func checkValidErase(info *BlockMatrixInfo, oldRowHashes [][]byte, oldColHashes [][]byte) (bool, error) {
 	var numRowChanged, numColChanged int
 
 	for i := 0; i < info.Size; i++ {
 		if !reflect.DeepEqual(oldRowHashes[i], info.Rows[i]) {
 			numRowChanged++
-		} else if !reflect.DeepEqual(oldColHashes[i], info.Cols[i]) {
+		}
+		if !reflect.DeepEqual(oldColHashes[i], info.Cols[i]) {
 			numColChanged++
 		}
 	}
@@ -271,28 +1110,38 @@ func (b *BlockMatrix) checkValidErase(info *BlockMatrixInfo, oldRowHashes [][]by
 
 // Matrix returns a 2D matrix of the blocks in the key value database.
 func (b *BlockMatrix) Matrix() ([][]*Block, error) {
+	return b.MatrixContext(context.Background())
+}
+
+// MatrixContext returns the blocks of the block matrix like Matrix, checking ctx before each block is read and returning
+// the context's error as soon as it is done.
+func (b *BlockMatrix) MatrixContext(ctx context.Context) ([][]*Block, error) {
+	ctx, end := b.startSpanContext(ctx, "Matrix")
+	defer end()
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
 	info, err := b.GetBlockMatrixInfo()
 	if err != nil {
 		return nil, err
 	}
 
-	size := b.Size(info.BlockCount)
 	// initialize the matrix
-	matrix := make([][]*Block, size)
-	for i := 0; i < size; i++ {
-		matrix[i] = make([]*Block, size)
+	matrix := make([][]*Block, info.Size)
+	for i := 0; i < info.Size; i++ {
+		matrix[i] = make([]*Block, info.Size)
 	}
 
 	// populate the matrix
-	for blockNum := 1; blockNum <= (info.Size*info.Size - info.Size); blockNum++ {
-		i, j := b.locateBlock(blockNum)
-		bytes, err := b.db.Get([]byte(fmt.Sprint(blockNum)), nil)
-		if err != nil {
+	for blockNum := 1; blockNum <= capacity(info.Size); blockNum++ {
+		if err = ctx.Err(); err != nil {
 			return nil, err
 		}
 
-		block := EmptyBlock()
-		if err = json.Unmarshal(bytes, block); err != nil {
+		i, j := locateBlock(blockNum)
+		block, err := b.getSlot(blockNum)
+		if err != nil {
 			return nil, err
 		}
 
@@ -344,8 +1193,13 @@ func (b *BlockMatrix) PrintBlockMatrixData() error {
 	return nil
 }
 
+// LocateBlock returns the row and column of the block with the given block number.
+func LocateBlock(blockNum int) (int, int) {
+	return locateBlock(blockNum)
+}
+
 // locateBlock returns the row and column of the block with the given block number
-func (b *BlockMatrix) locateBlock(blockNum int) (i int, j int) {
+func locateBlock(blockNum int) (i int, j int) {
 	// calculate row index
 	if blockNum%2 == 0 {
 		s := int(math.Floor(math.Sqrt(float64(blockNum))))
@@ -389,8 +1243,87 @@ func (b *BlockMatrix) locateBlock(blockNum int) (i int, j int) {
 	return
 }
 
-// rowBlockNumbers returns the block numbers for the row at the given index (row index is 0-based)
-func (b *BlockMatrix) rowBlockNumbers(rowIndex int, blockCount int) ([]int, error) {
+// RowBlockNumbers returns the numbers of the blocks in the row at the given index of the current block matrix, in the
+// order their hashes are written to the row hash (row index is 0-based).
+func (b *BlockMatrix) RowBlockNumbers(rowIndex int) ([]int, error) {
+	info, err := b.GetBlockMatrixInfo()
+	if err != nil {
+		return nil, err
+	}
+
+	if err = checkIndex("row", rowIndex, info.Size); err != nil {
+		return nil, err
+	}
+
+	return rowBlockNumbers(rowIndex, info.Size)
+}
+
+// ColumnBlockNumbers returns the numbers of the blocks in the column at the given index of the current block matrix, in
+// the order their hashes are written to the column hash (column index is 0-based).
+func (b *BlockMatrix) ColumnBlockNumbers(colIndex int) ([]int, error) {
+	info, err := b.GetBlockMatrixInfo()
+	if err != nil {
+		return nil, err
+	}
+
+	if err = checkIndex("column", colIndex, info.Size); err != nil {
+		return nil, err
+	}
+
+	return columnBlockNumbers(colIndex, info.Size)
+}
+
+// RowLeafOrder returns the canonical order of the leaves of the hash of the row at the given index: the numbers of the
+// blocks in the row sorted by column index, skipping the diagonal.  The row hash is the hash of the concatenated block
+// hashes in this order, using the hash function of the block matrix, so proof generators and external verifiers must
+// use it to agree on row hashes.  The order of the existing leaves does not change when the block matrix grows, new
+// leaves are appended.
+func (b *BlockMatrix) RowLeafOrder(rowIndex int) ([]int, error) {
+	return b.RowBlockNumbers(rowIndex)
+}
+
+// ColumnLeafOrder returns the canonical order of the leaves of the hash of the column at the given index: the numbers
+// of the blocks in the column sorted by row index, skipping the diagonal.  The column hash is the hash of the
+// concatenated block hashes in this order, using the hash function of the block matrix.  The order of the existing
+// leaves does not change when the block matrix grows, new leaves are appended.
+func (b *BlockMatrix) ColumnLeafOrder(colIndex int) ([]int, error) {
+	return b.ColumnBlockNumbers(colIndex)
+}
+
+// RowHashInputCount returns the number of block hashes that feed the hash of the row at the given index.  For a block
+// matrix of size n this should always be n-1, any other value indicates the geometry has drifted.
+func (b *BlockMatrix) RowHashInputCount(rowIndex int) (int, error) {
+	blockNums, err := b.RowBlockNumbers(rowIndex)
+	if err != nil {
+		return 0, err
+	}
+
+	return len(blockNums), nil
+}
+
+// ColumnHashInputCount returns the number of block hashes that feed the hash of the column at the given index.  For a
+// block matrix of size n this should always be n-1, any other value indicates the geometry has drifted.
+func (b *BlockMatrix) ColumnHashInputCount(colIndex int) (int, error) {
+	blockNums, err := b.ColumnBlockNumbers(colIndex)
+	if err != nil {
+		return 0, err
+	}
+
+	return len(blockNums), nil
+}
+
+// checkIndex returns an error if the given row or column index is not in a block matrix of the given size.
+func checkIndex(kind string, index int, size int) error {
+	if index < 0 || index >= size {
+		return fmt.Errorf("%w: %s index %d for block matrix of size %d", ErrOutOfRange, kind, index, size)
+	}
+
+	return nil
+}
+
+// rowBlockNumbers returns the block numbers for the row at the given index (row index is 0-based) in a block matrix of
+// the given size
+func rowBlockNumbers(rowIndex int, size int) ([]int, error) {
 	blocksNums := make([]int, 0)
 
 	// get the blocks under the diagonal
@@ -402,7 +1335,6 @@ func (b *BlockMatrix) rowBlockNumbers(rowIndex int, blockCount int) ([]int, erro
 	}
 
 	// get the blocks above the diagonal
-	size := b.Size(blockCount)
 	sub := 1
 	for col := rowIndex + 1; col < size; col++ {
 		blockNum := col*col + col - sub
@@ -413,8 +1345,9 @@ func (b *BlockMatrix) rowBlockNumbers(rowIndex int, blockCount int) ([]int, erro
 	return blocksNums, nil
 }
 
-// columnBlockNumbers returns the block numbers for the column at the given index (column index is 0-based)
-func (b *BlockMatrix) columnBlockNumbers(colIndex int, blockCount int) ([]int, error) {
+// columnBlockNumbers returns the block numbers for the column at the given index (column index is 0-based) in a block
+// matrix of the given size
+func columnBlockNumbers(colIndex int, size int) ([]int, error) {
 	blocksNums := make([]int, 0)
 
 	// get the blocks above the diagonal
@@ -426,7 +1359,6 @@ func (b *BlockMatrix) columnBlockNumbers(colIndex int, blockCount int) ([]int, e
 	}
 
 	// get the blocks under the diagonal
-	size := b.Size(blockCount)
 	add := 2*colIndex + 2
 	for row := colIndex + 1; row < size; row++ {
 		blockNum := row*row - row + add
@@ -437,67 +1369,131 @@ func (b *BlockMatrix) columnBlockNumbers(colIndex int, blockCount int) ([]int, e
 }
 
 func (b *BlockMatrix) GetBlockMatrixInfo() (*BlockMatrixInfo, error) {
-	if ok, err := b.db.Has([]byte("info"), nil); err != nil {
+	cached, generation := b.cachedInfo()
+	if cached != nil {
+		return cached, nil
+	}
+
+	if ok, err := b.has([]byte("info")); err != nil {
 		return nil, err
 	} else if !ok {
-		info := &BlockMatrixInfo{
-			Rows: make([][]byte, 1),
-			Cols: make([][]byte, 1),
-		}
-
-		var bytes []byte
-		if bytes, err = json.Marshal(info); err != nil {
+		// the info was removed after the block matrix was created, recreate it for an empty block matrix
+		if err = b.initInfo(); err != nil {
 			return nil, err
 		}
-
-		if err = b.db.Put([]byte("info"), bytes, nil); err != nil {
-			return nil, err
-		}
-
-		return info, nil
 	}
 
-	infoBytes, err := b.db.Get([]byte("info"), nil)
+	infoBytes, err := b.get([]byte("info"))
 	if err != nil {
 		return nil, err
 	}
 
-	info := &BlockMatrixInfo{}
-	if err = json.Unmarshal(infoBytes, info); err != nil {
+	info, err := decodeInfo(infoBytes)
+	if err != nil {
 		return nil, err
 	}
 
+	b.fillInfoCache(info, generation)
 	return info, nil
 }
 
-func (b *BlockMatrix) calculateRowHash(row int, blockCount int) ([]byte, error) {
-	h := sha256.New()
-	blocks, err := b.rowBlockNumbers(row, blockCount)
+// BlockCount returns the number of blocks in the block matrix, which includes erased blocks but not the slots freed by
+// DeleteBlock.  Only the header of the stored info is decoded, use GetBlockMatrixInfo for the row and column hashes.
+func (b *BlockMatrix) BlockCount() (int, error) {
+	header, err := b.infoHeader()
 	if err != nil {
-		return nil, err
+		return 0, err
 	}
 
-	for _, blockNum := range blocks {
-		block, err := b.GetBlockByNumber(blockNum)
+	return header.BlockCount, nil
+}
+
+// Dimension returns the size of the block matrix, the number of rows and columns.  Only the header of the stored info
+// is decoded, use GetBlockMatrixInfo for the row and column hashes.
+func (b *BlockMatrix) Dimension() (int, error) {
+	header, err := b.infoHeader()
+	if err != nil {
+		return 0, err
+	}
+
+	return header.Size, nil
+}
+
+// infoHeader returns the scalar fields of the stored info.
+func (b *BlockMatrix) infoHeader() (*packedInfo, error) {
+	if info, _ := b.cachedInfo(); info != nil {
+		return infoHeaderOf(info), nil
+	}
+
+	infoBytes, err := b.get(InfoKey)
+	if errors.Is(err, ErrNotFound) {
+		// GetBlockMatrixInfo recreates missing info
+		info, err := b.GetBlockMatrixInfo()
 		if err != nil {
 			return nil, err
 		}
 
-		h.Write(block.Hash)
+		return infoHeaderOf(info), nil
+	} else if err != nil {
+		return nil, err
 	}
 
-	return h.Sum(nil), nil
+	return decodeInfoHeader(infoBytes)
+}
+
+// RootHash returns a single digest over every row hash followed by every column hash.  Two block matrices with the same
+// root hash have the same row and column hashes, so the root can be shipped to other parties to compare state cheaply.
+func (b *BlockMatrix) RootHash() ([]byte, error) {
+	info, err := b.GetBlockMatrixInfo()
+	if err != nil {
+		return nil, err
+	}
+
+	return b.rootHash(info), nil
+}
+
+// rootHash returns the root hash of the block matrix described by info using the hash function of the block matrix.
+func (b *BlockMatrix) rootHash(info *BlockMatrixInfo) []byte {
+	return calculateRootHash(b.hasher, info)
+}
+
+// calculateRootHash returns the hash created by newHash of every row hash of info followed by every column hash.
+func calculateRootHash(newHash func() hash.Hash, info *BlockMatrixInfo) []byte {
+	h := newHash()
+	for _, row := range info.Rows {
+		h.Write(row)
+	}
+	for _, col := range info.Cols {
+		h.Write(col)
+	}
+
+	return h.Sum(nil)
 }
 
-func (b *BlockMatrix) calculateColumnHash(col int, blockCount int) ([]byte, error) {
-	h := sha256.New()
-	blocks, err := b.columnBlockNumbers(col, blockCount)
+func (b *BlockMatrix) calculateRowHash(row int, size int) ([]byte, error) {
+	blocks, err := rowBlockNumbers(row, size)
 	if err != nil {
 		return nil, err
 	}
 
-	for _, blockNum := range blocks {
-		block, err := b.GetBlockByNumber(blockNum)
+	return hashBlocks(b.hasher, blocks, b.getSlot)
+}
+
+func (b *BlockMatrix) calculateColumnHash(col int, size int) ([]byte, error) {
+	blocks, err := columnBlockNumbers(col, size)
+	if err != nil {
+		return nil, err
+	}
+
+	return hashBlocks(b.hasher, blocks, b.getSlot)
+}
+
+// hashBlocks returns the hash of the concatenated hashes of the blocks with the given numbers, in order, using the hash
+// function created by newHash.
+func hashBlocks(newHash func() hash.Hash, blockNums []int, getBlock func(int) (*Block, error)) ([]byte, error) {
+	h := newHash()
+	for _, blockNum := range blockNums {
+		block, err := getBlock(blockNum)
 		if err != nil {
 			return nil, err
 		}
@@ -511,69 +1507,47 @@ func (b *BlockMatrix) calculateColumnHash(col int, blockCount int) ([]byte, erro
 // updateBlockMatrixSize updates the size of the block matrix and creates empty entries for the new blocks added. This
 // prevents any nil pointer references for blocks that haven't been initialized with AddBlock but are still in the matrix.
 func (b *BlockMatrix) updateBlockMatrixSize(info *BlockMatrixInfo, newSize int) error {
-	numBlocksToAdd := 2 * info.Size
-	info.Size = newSize
-	for i := info.BlockCount; i < info.BlockCount+numBlocksToAdd; i++ {
-		bytes, err := json.Marshal(EmptyBlock())
+	b.logGrowth(info.Size, newSize)
+	for i := capacity(info.Size) + 1; i <= capacity(newSize) && !b.lazyEmptyBlocks; i++ {
+		bytes, err := b.encodeBlock(b.emptyBlock())
 		if err != nil {
 			return err
 		}
 
-		if err = b.db.Put([]byte(fmt.Sprint(i)), bytes, nil); err != nil {
+		if err = b.put([]byte(fmt.Sprint(i)), bytes); err != nil {
 			return err
 		}
 	}
 
-	info.Rows = append(info.Rows, make([]byte, 0))
-	info.Cols = append(info.Cols, make([]byte, 0))
+	for i := info.Size; i < newSize; i++ {
+		info.Rows = append(info.Rows, make([]byte, 0))
+		info.Cols = append(info.Cols, make([]byte, 0))
+	}
+	info.Size = newSize
 
 	return nil
 }
 
+// IsValid returns true if every block, row, and column hash in the block matrix is consistent with the stored data.  Use
+// Validate to find out which hashes are inconsistent.
 func (b *BlockMatrix) IsValid() (bool, error) {
-	info, err := b.GetBlockMatrixInfo()
-	if err != nil {
-		return false, err
-	}
-
-	// check block hashes
-	for i := 1; i <= info.BlockCount; i++ {
-		var block *Block
-		if block, err = b.GetBlockByNumber(i); err != nil {
-			return false, err
-		}
+	return b.IsValidContext(context.Background())
+}
 
-		if reflect.DeepEqual(block.Hash, block.CalculateHash()) {
-			return false, fmt.Errorf("hashes for block %d are not equal", i)
-		}
-	}
+// IsValidContext checks the block matrix like IsValid, checking ctx before each block is read and returning the
+// context's error as soon as it is done.
+func (b *BlockMatrix) IsValidContext(ctx context.Context) (ok bool, err error) {
+	ctx, end := b.startSpanContext(ctx, "IsValid")
+	defer end()
+	defer b.observeOperation("IsValid")(&err)
 
-	// check row hashes
-	size := b.Size(info.BlockCount)
-	for i := 0; i < size; i++ {
-		var hash []byte
-		if hash, err = b.calculateRowHash(i, info.BlockCount); err != nil {
-			return false, err
-		}
+	b.mu.RLock()
+	defer b.mu.RUnlock()
 
-		if reflect.DeepEqual(info.Rows[i], hash) {
-			return false, fmt.Errorf("hashes for row %d are not equal", i)
-		}
-	}
-
-	// check col hashes
-	for i := 0; i < size; i++ {
-		var hash []byte
-		if hash, err = b.calculateColumnHash(i, info.BlockCount); err != nil {
-			return false, err
-		}
-
-		if reflect.DeepEqual(info.Cols[i], hash) {
-			return false, fmt.Errorf("hashes for column %d are not equal", i)
-		}
+	result, err := b.validate(ctx)
+	if err != nil {
+		return false, err
 	}
 
-	// TODO check if there have been invalid deletions
-
-	return true, nil
+	return result.OK, nil
 }
@@ -1,79 +1,94 @@
 package blockmatrix
 
 import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
 	"fmt"
 	"github.com/stretchr/testify/require"
 	"github.com/syndtr/goleveldb/leveldb"
-	"io/ioutil"
-	"os"
+	"strconv"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
-var db *leveldb.DB
+// newTestDB opens a leveldb database in a temporary directory that is removed when the test finishes.  Each test gets
+// its own database so block matrices do not leak state between tests.
+func newTestDB(t *testing.T) *leveldb.DB {
+	db, err := leveldb.OpenFile(t.TempDir(), nil)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = db.Close()
+	})
 
-func TestMain(m *testing.M) {
-	var err error
-
-	dir, err := ioutil.TempDir("./db", "test_db")
-	if err != nil {
-		os.Exit(1)
-	}
-	defer func(path string) {
-		err := os.RemoveAll(path)
-		if err != nil {
-			fmt.Println(err)
-			os.Exit(2)
-		}
-	}(dir)
-
-	db, err = leveldb.OpenFile(dir, nil)
-	if err != nil {
-		os.Exit(3)
-	}
-
-	m.Run()
+	return db
 }
 
 func TestRowBlockNumbers(t *testing.T) {
-	bm, err := New(db)
+	bm, err := NewWithLevelDB(newTestDB(t))
 	require.NoError(t, err)
 
 	err = createTestBlocks(bm, 5)
 	require.NoError(t, err)
-	actual, err := bm.rowBlockNumbers(2, 5)
+	actual, err := bm.RowBlockNumbers(2)
 	require.NoError(t, err)
 	require.Equal(t, []int{4, 6}, actual)
 
 	err = createTestBlocks(bm, 20)
 	require.NoError(t, err)
-	actual, err = bm.rowBlockNumbers(0, 5)
+	actual, err = bm.RowBlockNumbers(0)
 	require.NoError(t, err)
 	require.Equal(t, []int{1, 3, 7, 13, 21}, actual)
-	actual, err = bm.rowBlockNumbers(3, 5)
+	actual, err = bm.RowBlockNumbers(3)
 	require.NoError(t, err)
 	require.Equal(t, []int{8, 10, 12, 19, 27}, actual)
 }
 
 func TestColumnBlockNumbers(t *testing.T) {
-	bm, err := New(db)
+	bm, err := NewWithLevelDB(newTestDB(t))
 	require.NoError(t, err)
 
 	err = createTestBlocks(bm, 5)
 	require.NoError(t, err)
-	actual, err := bm.columnBlockNumbers(1, 5)
+	actual, err := bm.ColumnBlockNumbers(1)
 	require.NoError(t, err)
 	require.Equal(t, []int{1, 6}, actual)
 
 	err = createTestBlocks(bm, 20)
 	require.NoError(t, err)
-	actual, err = bm.columnBlockNumbers(0, 5)
+	actual, err = bm.ColumnBlockNumbers(0)
 	require.NoError(t, err)
 	require.Equal(t, []int{2, 4, 8, 14, 22}, actual)
-	actual, err = bm.columnBlockNumbers(3, 5)
+	actual, err = bm.ColumnBlockNumbers(3)
 	require.NoError(t, err)
 	require.Equal(t, []int{7, 9, 11, 20, 28}, actual)
 }
 
+func TestHashInputCount(t *testing.T) {
+	bm, err := NewWithLevelDB(newTestDB(t))
+	require.NoError(t, err)
+	require.NoError(t, createTestBlocks(bm, 10))
+
+	info, err := bm.GetBlockMatrixInfo()
+	require.NoError(t, err)
+	for i := 0; i < info.Size; i++ {
+		count, err := bm.RowHashInputCount(i)
+		require.NoError(t, err)
+		require.Equal(t, info.Size-1, count)
+
+		count, err = bm.ColumnHashInputCount(i)
+		require.NoError(t, err)
+		require.Equal(t, info.Size-1, count)
+	}
+
+	_, err = bm.RowHashInputCount(info.Size)
+	require.Error(t, err)
+	_, err = bm.ColumnHashInputCount(-1)
+	require.Error(t, err)
+}
+
 func createTestBlocks(bm *BlockMatrix, num int) error {
 	for i := 1; i <= num; i++ {
 		err := bm.AddBlock(fmt.Sprintf("key%d", i), []byte{byte(i)})
@@ -85,8 +100,68 @@ func createTestBlocks(bm *BlockMatrix, num int) error {
 	return nil
 }
 
+// testClock returns a fixed time, block matrices built from the same data WithClock(testClock) are identical.
+func testClock() time.Time {
+	return time.Date(2026, time.January, 2, 3, 4, 5, 0, time.UTC)
+}
+
+func TestAddBlockGrowthUpdatesHashes(t *testing.T) {
+	bm, err := NewWithLevelDB(newTestDB(t))
+	require.NoError(t, err)
+
+	// the seventh block grows the matrix from size 3 to 4, which adds an empty block to every existing row and column
+	require.NoError(t, createTestBlocks(bm, 7))
+
+	info, err := bm.GetBlockMatrixInfo()
+	require.NoError(t, err)
+	require.Equal(t, 4, info.Size)
+	for i := 0; i < info.Size; i++ {
+		hash, err := bm.calculateRowHash(i, info.Size)
+		require.NoError(t, err)
+		require.Equal(t, hash, info.Rows[i], "row %d", i)
+
+		hash, err = bm.calculateColumnHash(i, info.Size)
+		require.NoError(t, err)
+		require.Equal(t, hash, info.Cols[i], "column %d", i)
+	}
+}
+
+func TestUpdateBlockMatrixSize(t *testing.T) {
+	bm, err := NewWithLevelDB(newTestDB(t))
+	require.NoError(t, err)
+
+	// an empty matrix stores the hashes of its empty row and column
+	result, err := bm.Validate()
+	require.NoError(t, err)
+	require.True(t, result.OK)
+
+	require.NoError(t, createTestBlocks(bm, 2))
+
+	// grow from size 2 to 4 in one step, ahead of the block count
+	info, err := bm.GetBlockMatrixInfo()
+	require.NoError(t, err)
+	require.NoError(t, bm.updateBlockMatrixSize(info, 4))
+	require.NoError(t, bm.updateAllHashes(info))
+	require.Equal(t, 4, info.Size)
+	require.Len(t, info.Rows, 4)
+	require.Len(t, info.Cols, 4)
+
+	block, err := bm.GetBlockByNumber(2)
+	require.NoError(t, err)
+	require.Equal(t, []byte{2}, block.Data)
+	for blockNum := 3; blockNum <= capacity(4); blockNum++ {
+		block, err = bm.GetBlockByNumber(blockNum)
+		require.NoError(t, err)
+		require.True(t, block.IsEmpty(), "block %d", blockNum)
+	}
+
+	result, err = bm.Validate()
+	require.NoError(t, err)
+	require.True(t, result.OK)
+}
+
 func TestPrintBlockMatrixData(t *testing.T) {
-	bm, err := New(db)
+	bm, err := NewWithLevelDB(newTestDB(t))
 	require.NoError(t, err)
 
 	err = bm.AddBlock("key1", []byte{1})
@@ -139,7 +214,7 @@ func TestPrintBlockMatrixData(t *testing.T) {
 }
 
 func TestEraseBlock(t *testing.T) {
-	bm, err := New(db)
+	bm, err := NewWithLevelDB(newTestDB(t))
 	require.NoError(t, err)
 
 	err = bm.AddBlock("key1", []byte{1})
@@ -156,3 +231,483 @@ func TestEraseBlock(t *testing.T) {
 	require.Equal(t, []byte{0}, block.Data)
 	require.Equal(t, calculateHash([]byte{0}), block.Hash)
 }
+
+func TestEraseBlockAt(t *testing.T) {
+	bm, err := NewWithLevelDB(newTestDB(t))
+	require.NoError(t, err)
+	require.NoError(t, createTestBlocks(bm, 12))
+
+	// blocks fill the cells above and below the diagonal of each new row and column in turn
+	expected := []Cell{{0, 1}, {1, 0}, {0, 2}, {2, 0}, {1, 2}, {2, 1}, {0, 3}, {3, 0}, {1, 3}, {3, 1}, {2, 3}, {3, 2}}
+	for i, cell := range expected {
+		before, err := bm.GetBlockMatrixInfo()
+		require.NoError(t, err)
+
+		row, col, err := bm.EraseBlockAt(fmt.Sprintf("key%d", i+1))
+		require.NoError(t, err)
+		require.Equal(t, cell, Cell{Row: row, Col: col})
+
+		after, err := bm.GetBlockMatrixInfo()
+		require.NoError(t, err)
+		for j := 0; j < after.Size; j++ {
+			require.Equal(t, j == row, !bytes.Equal(before.Rows[j], after.Rows[j]))
+			require.Equal(t, j == col, !bytes.Equal(before.Cols[j], after.Cols[j]))
+		}
+	}
+
+	row, col, err := bm.EraseBlockAt("key1")
+	require.Error(t, err)
+	require.Equal(t, -1, row)
+	require.Equal(t, -1, col)
+}
+
+func TestBlockNumberMultiDigit(t *testing.T) {
+	bm, err := NewWithLevelDB(newTestDB(t))
+	require.NoError(t, err)
+	require.NoError(t, createTestBlocks(bm, 16))
+
+	for i := 1; i <= 16; i++ {
+		key := fmt.Sprintf("key%d", i)
+		blockNum, err := bm.BlockNumber(key)
+		require.NoError(t, err)
+		require.Equal(t, i, blockNum)
+
+		block, err := bm.GetBlock(key)
+		require.NoError(t, err)
+		require.Equal(t, []byte{byte(i)}, block.Data)
+	}
+
+	require.NoError(t, bm.EraseBlock("key12"))
+
+	block, err := bm.GetBlockByNumber(12)
+	require.NoError(t, err)
+	require.True(t, block.IsEmpty())
+
+	block, err = bm.GetBlockByNumber(1)
+	require.NoError(t, err)
+	require.Equal(t, []byte{1}, block.Data)
+
+	result, err := bm.Validate()
+	require.NoError(t, err)
+	require.True(t, result.OK)
+}
+
+func TestUpdateBlock(t *testing.T) {
+	bm, err := NewWithLevelDB(newTestDB(t))
+	require.NoError(t, err)
+	require.NoError(t, createTestBlocks(bm, 12))
+
+	root, err := bm.RootHash()
+	require.NoError(t, err)
+
+	require.NoError(t, bm.UpdateBlock("key11", []byte("updated")))
+
+	block, err := bm.GetBlock("key11")
+	require.NoError(t, err)
+	require.Equal(t, []byte("updated"), block.Data)
+	require.False(t, block.Timestamp.IsZero())
+	require.Equal(t, block.CalculateHash(), block.Hash)
+
+	blockNum, err := bm.BlockNumber("key11")
+	require.NoError(t, err)
+	require.Equal(t, 11, blockNum)
+
+	info, err := bm.GetBlockMatrixInfo()
+	require.NoError(t, err)
+	require.Equal(t, 12, info.BlockCount)
+
+	updatedRoot, err := bm.RootHash()
+	require.NoError(t, err)
+	require.NotEqual(t, root, updatedRoot)
+
+	ok, err := bm.IsValid()
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	err = bm.UpdateBlock("missing", []byte("data"))
+	require.ErrorIs(t, err, ErrNotFound)
+
+	info, err = bm.GetBlockMatrixInfo()
+	require.NoError(t, err)
+	require.Equal(t, 12, info.BlockCount)
+}
+
+func TestUpdateBlockMetadata(t *testing.T) {
+	bm, err := NewWithLevelDB(newTestDB(t))
+	require.NoError(t, err)
+	require.NoError(t, createTestBlocks(bm, 3))
+	meta := map[string]string{"content-type": "application/json"}
+	require.NoError(t, bm.AddBlockWithMetadata("json", []byte(`{}`), meta))
+
+	require.NoError(t, bm.UpdateBlock("json", []byte(`{"updated":true}`)))
+	block, err := bm.GetBlock("json")
+	require.NoError(t, err)
+	require.Equal(t, []byte(`{"updated":true}`), block.Data)
+	require.Equal(t, meta, block.Metadata)
+	require.Equal(t, bm.blockHash(block), block.Hash)
+
+	tx := bm.Begin()
+	require.NoError(t, tx.UpdateBlock("json", []byte(`{"updated":2}`)))
+	require.NoError(t, tx.Commit())
+	block, err = bm.GetBlock("json")
+	require.NoError(t, err)
+	require.Equal(t, []byte(`{"updated":2}`), block.Data)
+	require.Equal(t, meta, block.Metadata)
+
+	result, err := bm.Validate()
+	require.NoError(t, err)
+	require.True(t, result.OK)
+}
+
+func TestMinSizeFor(t *testing.T) {
+	require.Equal(t, 1, MinSizeFor(0))
+	require.Equal(t, 2, MinSizeFor(1))
+	require.Equal(t, 2, MinSizeFor(2))
+	require.Equal(t, 3, MinSizeFor(3))
+	require.Equal(t, 3, MinSizeFor(6))
+	require.Equal(t, 4, MinSizeFor(7))
+	require.Equal(t, 0, MaxBlocksFor(1))
+	require.Equal(t, 12, MaxBlocksFor(4))
+
+	for count := 1; count <= 10000; count++ {
+		size := MinSizeFor(count)
+		require.GreaterOrEqual(t, MaxBlocksFor(size), count)
+		require.Less(t, MaxBlocksFor(size-1), count)
+	}
+
+	for size := 2; size <= 100; size++ {
+		require.Equal(t, size, MinSizeFor(MaxBlocksFor(size)))
+		require.Equal(t, size+1, MinSizeFor(MaxBlocksFor(size)+1))
+	}
+}
+
+func TestBlockCountAndDimension(t *testing.T) {
+	bm, err := NewWithLevelDB(newTestDB(t))
+	require.NoError(t, err)
+
+	expected := map[int]int{0: 1, 1: 2, 2: 2, 3: 3, 6: 3, 7: 4, 12: 4, 13: 5}
+	for count := 0; count <= 13; count++ {
+		if count > 0 {
+			require.NoError(t, bm.AddBlock(fmt.Sprintf("key%d", count), []byte{byte(count)}))
+		}
+
+		blockCount, err := bm.BlockCount()
+		require.NoError(t, err)
+		require.Equal(t, count, blockCount)
+
+		dimension, err := bm.Dimension()
+		require.NoError(t, err)
+		require.Equal(t, MinSizeFor(count), dimension)
+		if size, ok := expected[count]; ok {
+			require.Equal(t, size, dimension)
+		}
+	}
+
+	// erasing a block keeps its slot
+	require.NoError(t, bm.EraseBlock("key5"))
+	blockCount, err := bm.BlockCount()
+	require.NoError(t, err)
+	require.Equal(t, 13, blockCount)
+}
+
+func TestGetBlocksByNumbers(t *testing.T) {
+	bm, err := NewWithLevelDB(newTestDB(t))
+	require.NoError(t, err)
+	require.NoError(t, createTestBlocks(bm, 8))
+
+	nums := []int{1, 4, 8, 12}
+	blocks, err := bm.GetBlocksByNumbers(nums)
+	require.NoError(t, err)
+	require.Len(t, blocks, len(nums))
+	for _, num := range nums {
+		expected, err := bm.GetBlockByNumber(num)
+		require.NoError(t, err)
+		require.Equal(t, expected, blocks[num])
+	}
+
+	_, err = bm.GetBlocksByNumbers([]int{1, 13})
+	require.Error(t, err)
+	_, err = bm.GetBlocksByNumbers([]int{0})
+	require.Error(t, err)
+
+	// the leveldb store is read from a snapshot without waiting for writers
+	bm.mu.Lock()
+	blocks, err = bm.GetBlocksByNumbers(nums)
+	bm.mu.Unlock()
+	require.NoError(t, err)
+	require.Len(t, blocks, len(nums))
+}
+
+func TestGetBlockWithLocation(t *testing.T) {
+	bm, err := NewWithLevelDB(newTestDB(t))
+	require.NoError(t, err)
+	require.NoError(t, createTestBlocks(bm, 12))
+
+	for i := 1; i <= 12; i++ {
+		block, row, col, err := bm.GetBlockWithLocation(fmt.Sprintf("key%d", i))
+		require.NoError(t, err)
+		require.Equal(t, []byte{byte(i)}, block.Data)
+
+		expectedRow, expectedCol := LocateBlock(i)
+		require.Equal(t, expectedRow, row)
+		require.Equal(t, expectedCol, col)
+	}
+
+	_, _, _, err = bm.GetBlockWithLocation("missing")
+	require.Error(t, err)
+}
+
+func TestWithMaxSize(t *testing.T) {
+	bm, err := NewWithLevelDB(newTestDB(t), WithMaxSize(3))
+	require.NoError(t, err)
+
+	// a 3x3 block matrix holds 6 blocks
+	require.NoError(t, createTestBlocks(bm, 6))
+	require.ErrorIs(t, bm.AddBlock("key7", []byte{7}), ErrMaxSizeExceeded)
+
+	info, err := bm.GetBlockMatrixInfo()
+	require.NoError(t, err)
+	require.Equal(t, 3, info.Size)
+	require.Equal(t, 6, info.BlockCount)
+
+	_, err = bm.GetBlock("key7")
+	require.Error(t, err)
+
+	result, err := bm.Validate()
+	require.NoError(t, err)
+	require.True(t, result.OK)
+}
+
+func TestGetBlockRange(t *testing.T) {
+	bm, err := NewWithLevelDB(newTestDB(t))
+	require.NoError(t, err)
+	data := []byte("header:payload")
+	require.NoError(t, bm.AddBlock("key1", data))
+
+	header, err := bm.GetBlockRange("key1", 0, 6)
+	require.NoError(t, err)
+	require.Equal(t, data[:6], header)
+
+	payload, err := bm.GetBlockRange("key1", 7, 7)
+	require.NoError(t, err)
+	require.Equal(t, data[7:14], payload)
+
+	empty, err := bm.GetBlockRange("key1", len(data), 0)
+	require.NoError(t, err)
+	require.Empty(t, empty)
+
+	_, err = bm.GetBlockRange("key1", 10, 5)
+	require.Error(t, err)
+	_, err = bm.GetBlockRange("key1", -1, 2)
+	require.Error(t, err)
+	_, err = bm.GetBlockRange("missing", 0, 1)
+	require.Error(t, err)
+}
+
+func TestLeafOrder(t *testing.T) {
+	bm, err := NewWithLevelDB(newTestDB(t))
+	require.NoError(t, err)
+	require.NoError(t, createTestBlocks(bm, 9))
+
+	info, err := bm.GetBlockMatrixInfo()
+	require.NoError(t, err)
+
+	for i := 0; i < info.Size; i++ {
+		rowOrder, err := bm.RowLeafOrder(i)
+		require.NoError(t, err)
+		rowBlocks, err := bm.RowBlockNumbers(i)
+		require.NoError(t, err)
+		require.Equal(t, rowBlocks, rowOrder)
+
+		colOrder, err := bm.ColumnLeafOrder(i)
+		require.NoError(t, err)
+		colBlocks, err := bm.ColumnBlockNumbers(i)
+		require.NoError(t, err)
+		require.Equal(t, colBlocks, colOrder)
+
+		// an external verifier hashing the leaves in order arrives at the stored hashes
+		hashes, err := bm.blockHashes(rowOrder)
+		require.NoError(t, err)
+		sum := sha256.Sum256(bytes.Join(hashes, nil))
+		require.Equal(t, info.Rows[i], sum[:])
+	}
+
+	// growing the block matrix appends leaves without reordering the existing ones
+	rowOrder, err := bm.RowLeafOrder(1)
+	require.NoError(t, err)
+	require.NoError(t, bm.Grow(6))
+	grown, err := bm.RowLeafOrder(1)
+	require.NoError(t, err)
+	require.Equal(t, rowOrder, grown[:len(rowOrder)])
+}
+
+func TestForEachBlock(t *testing.T) {
+	bm, err := NewWithLevelDB(newTestDB(t))
+	require.NoError(t, err)
+	require.NoError(t, createTestBlocks(bm, 8))
+	require.NoError(t, bm.EraseBlock("key3"))
+	require.NoError(t, bm.EraseBlock("key6"))
+
+	// each block holds its number as data
+	sum := 0
+	blockNums := make([]int, 0)
+	require.NoError(t, bm.ForEachBlock(func(blockNum int, block *Block) error {
+		require.Equal(t, []byte{byte(blockNum)}, block.Data)
+		sum += int(block.Data[0])
+		blockNums = append(blockNums, blockNum)
+		return nil
+	}))
+	require.Equal(t, 1+2+4+5+7+8, sum)
+	require.Equal(t, []int{1, 2, 4, 5, 7, 8}, blockNums)
+
+	stop := errors.New("stop")
+	visited := 0
+	err = bm.ForEachBlock(func(blockNum int, block *Block) error {
+		visited++
+		if blockNum == 4 {
+			return stop
+		}
+
+		return nil
+	})
+	require.ErrorIs(t, err, stop)
+	require.Equal(t, 3, visited)
+}
+
+// cancelingStore cancels a context once a given number of blocks have been read.
+type cancelingStore struct {
+	Store
+	reads  int64
+	after  int64
+	cancel context.CancelFunc
+}
+
+func (s *cancelingStore) Get(key []byte) ([]byte, error) {
+	if _, err := strconv.Atoi(string(key)); err == nil && atomic.AddInt64(&s.reads, 1) == s.after {
+		s.cancel()
+	}
+
+	return s.Store.Get(key)
+}
+
+func TestContextCancellation(t *testing.T) {
+	mem := NewMemStore()
+	bm, err := New(mem)
+	require.NoError(t, err)
+	require.NoError(t, createTestBlocks(bm, 200))
+
+	run := func(fn func(ctx context.Context, bm *BlockMatrix) error) *cancelingStore {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		store := &cancelingStore{Store: mem, after: 50, cancel: cancel}
+		bm, err := New(store)
+		require.NoError(t, err)
+		require.ErrorIs(t, fn(ctx, bm), context.Canceled)
+		return store
+	}
+
+	store := run(func(ctx context.Context, bm *BlockMatrix) error {
+		_, err := bm.MatrixContext(ctx)
+		return err
+	})
+	// the matrix has 210 slots, reading stops right after the cancellation
+	require.Equal(t, int64(50), store.reads)
+
+	store = run(func(ctx context.Context, bm *BlockMatrix) error {
+		_, err := bm.IsValidContext(ctx)
+		return err
+	})
+	require.Less(t, store.reads, int64(200))
+
+	run(func(ctx context.Context, bm *BlockMatrix) error {
+		_, err := bm.CorruptBlocksContext(ctx)
+		return err
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	require.ErrorIs(t, bm.AddBlockContext(ctx, "canceled", []byte("canceled")), context.Canceled)
+	_, err = bm.GetBlock("canceled")
+	require.ErrorIs(t, err, ErrNotFound)
+
+	ok, err := bm.IsValidContext(context.Background())
+	require.NoError(t, err)
+	require.True(t, ok)
+}
+
+// faultyStore fails the write that would make the given number of puts, counting each put of a Write on its own, and
+// every write after it.
+type faultyStore struct {
+	Store
+	puts   int
+	failAt int
+}
+
+var errInjected = errors.New("injected fault")
+
+func (s *faultyStore) fail(puts int) bool {
+	s.puts += puts
+	return s.failAt > 0 && s.puts >= s.failAt
+}
+
+func (s *faultyStore) Put(key []byte, value []byte) error {
+	if s.fail(1) {
+		return errInjected
+	}
+
+	return s.Store.Put(key, value)
+}
+
+func (s *faultyStore) Write(writes []StoreWrite) error {
+	puts := 0
+	for _, write := range writes {
+		if !write.Delete {
+			puts++
+		}
+	}
+
+	if s.fail(puts) {
+		return errInjected
+	}
+
+	return s.Store.Write(writes)
+}
+
+func TestAddBlockAtomic(t *testing.T) {
+	store := &faultyStore{Store: NewMemStore()}
+	bm, err := New(store)
+	require.NoError(t, err)
+	require.NoError(t, createTestBlocks(bm, 5))
+
+	expected, err := bm.Matrix()
+	require.NoError(t, err)
+	expectedInfo, err := bm.GetBlockMatrixInfo()
+	require.NoError(t, err)
+
+	// fail the second put of the next add, once without growing the block matrix and once growing it
+	for _, key := range []string{"key6", "key7"} {
+		store.puts, store.failAt = 0, 2
+		require.ErrorIs(t, bm.AddBlock(key, []byte(key)), errInjected)
+
+		store.failAt = 0
+		actual, err := bm.Matrix()
+		require.NoError(t, err)
+		require.Equal(t, expected, actual)
+		actualInfo, err := bm.GetBlockMatrixInfo()
+		require.NoError(t, err)
+		require.Equal(t, expectedInfo, actualInfo)
+		_, err = bm.GetBlock(key)
+		require.ErrorIs(t, err, ErrNotFound)
+
+		require.NoError(t, bm.AddBlock(key, []byte(key)))
+		expected, err = bm.Matrix()
+		require.NoError(t, err)
+		expectedInfo, err = bm.GetBlockMatrixInfo()
+		require.NoError(t, err)
+	}
+
+	ok, err := bm.IsValid()
+	require.NoError(t, err)
+	require.True(t, ok)
+}
@@ -0,0 +1,121 @@
+package blockmatrix
+
+import (
+	"context"
+	"fmt"
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/storage"
+	"sort"
+)
+
+// NewWithData creates a new block matrix in the given database pre-loaded with the given key to data pairs.  Blocks are
+// added in sorted key order so the same data always produces the same block matrix, with the same hashes if the blocks
+// are stamped with the same times, see WithClock.  The blocks are first added to a
+// block matrix in memory and then written to the database in a single batch, so if anything fails the database is left
+// untouched.  An error is returned if the database already has a block matrix.
+func NewWithData(db *leveldb.DB, data map[string][]byte, opts ...Option) (*BlockMatrix, error) {
+	if err := checkNoMatrix(db); err != nil {
+		return nil, err
+	}
+
+	staging, err := newStaging()
+	if err != nil {
+		return nil, err
+	}
+	defer staging.Close()
+
+	bm, err := NewWithLevelDB(staging, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, key := range sortedKeys(data) {
+		if err = bm.AddBlock(key, data[key]); err != nil {
+			return nil, fmt.Errorf("error adding block %q: %w", key, err)
+		}
+	}
+
+	if err = copyStaged(staging, db); err != nil {
+		return nil, err
+	}
+
+	return NewWithLevelDB(db, opts...)
+}
+
+// AddBlocks adds a block for each key to data pair in a single transaction, see Tx.  Blocks are added in sorted key order
+// so the same data always produces the same block matrix, with the same hashes if the blocks are stamped with the same
+// times, see WithClock.  Every block, key, and the updated row and column hashes are
+// written in one batch with the info rewritten once, so adding many blocks is much faster than calling AddBlock for
+// each and a crash leaves either all of the blocks or none of them.  If any block cannot be added nothing is written.
+func (b *BlockMatrix) AddBlocks(data map[string][]byte) error {
+	return b.AddBlocksContext(context.Background(), data)
+}
+
+// AddBlocksContext adds the blocks like AddBlocks.  With WithWriteRateLimit it first waits for the limit to allow every
+// block, and if ctx is done while waiting nothing is written and the context's error is returned.
+func (b *BlockMatrix) AddBlocksContext(ctx context.Context, data map[string][]byte) error {
+	if err := b.throttleWrites(ctx, len(data)); err != nil {
+		return err
+	}
+
+	tx := b.Begin()
+	for _, key := range sortedKeys(data) {
+		if err := tx.AddBlock(key, data[key]); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// checkNoMatrix returns ErrMatrixExists if the database already has a block matrix.
+func checkNoMatrix(db *leveldb.DB) error {
+	if ok, err := db.Has(InfoKey, nil); err != nil {
+		return fmt.Errorf("error checking if database has block matrix info: %w", storageErr(err))
+	} else if ok {
+		return ErrMatrixExists
+	}
+
+	return nil
+}
+
+// newStaging opens an in memory database to build a block matrix in before it is written to its destination.
+func newStaging() (*leveldb.DB, error) {
+	staging, err := leveldb.Open(storage.NewMemStorage(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("error opening staging database: %w", err)
+	}
+
+	return staging, nil
+}
+
+// copyStaged writes every entry of the staging database to db in a single batch.
+func copyStaged(staging *leveldb.DB, db *leveldb.DB) error {
+	batch := new(leveldb.Batch)
+	iter := staging.NewIterator(nil, nil)
+	for iter.Next() {
+		batch.Put(iter.Key(), iter.Value())
+	}
+	iter.Release()
+	if err := iter.Error(); err != nil {
+		return storageErr(err)
+	}
+
+	if err := db.Write(batch, nil); err != nil {
+		return fmt.Errorf("error writing block matrix: %w", storageErr(err))
+	}
+
+	return nil
+}
+
+// sortedKeys returns the keys of the map in sorted order, map based bulk operations add blocks in this order so that
+// block numbers are assigned deterministically.
+func sortedKeys(data map[string][]byte) []string {
+	keys := make([]string, 0, len(data))
+	for key := range data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
@@ -0,0 +1,156 @@
+package blockmatrix
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/require"
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/storage"
+	"strings"
+	"testing"
+)
+
+func testData(num int) map[string][]byte {
+	data := make(map[string][]byte, num)
+	for i := 1; i <= num; i++ {
+		data[fmt.Sprintf("key%d", i)] = []byte{byte(i)}
+	}
+
+	return data
+}
+
+func TestNewWithData(t *testing.T) {
+	data := testData(8)
+	bm, err := NewWithData(newTestDB(t), data)
+	require.NoError(t, err)
+
+	for key, value := range data {
+		block, err := bm.GetBlock(key)
+		require.NoError(t, err)
+		require.Equal(t, value, block.Data)
+	}
+
+	info, err := bm.GetBlockMatrixInfo()
+	require.NoError(t, err)
+	require.Equal(t, 8, info.BlockCount)
+
+	result, err := bm.Validate()
+	require.NoError(t, err)
+	require.True(t, result.OK)
+}
+
+func TestNewWithDataDeterministic(t *testing.T) {
+	data := testData(20)
+	bm1, err := NewWithData(newTestDB(t), data, WithClock(testClock))
+	require.NoError(t, err)
+	bm2, err := NewWithData(newTestDB(t), data, WithClock(testClock))
+	require.NoError(t, err)
+
+	matrix1, err := bm1.Matrix()
+	require.NoError(t, err)
+	matrix2, err := bm2.Matrix()
+	require.NoError(t, err)
+	require.Equal(t, matrix1, matrix2)
+
+	root1, err := bm1.RootHash()
+	require.NoError(t, err)
+	root2, err := bm2.RootHash()
+	require.NoError(t, err)
+	require.Equal(t, root1, root2)
+}
+
+func TestNewWithDataFailure(t *testing.T) {
+	db := newTestDB(t)
+	truncate := func(key string) string {
+		return key[:3]
+	}
+
+	_, err := NewWithData(db, testData(3), WithKeyTransform(truncate))
+	require.Error(t, err)
+
+	iter := db.NewIterator(nil, nil)
+	defer iter.Release()
+	require.False(t, iter.Next())
+
+	bm, err := NewWithLevelDB(db)
+	require.NoError(t, err)
+	_, err = NewWithData(db, testData(3))
+	require.Error(t, err)
+	info, err := bm.GetBlockMatrixInfo()
+	require.NoError(t, err)
+	require.Equal(t, 0, info.BlockCount)
+}
+
+func TestAddBlocks(t *testing.T) {
+	data := testData(20)
+	sequential, err := New(NewMemStore(), WithClock(testClock))
+	require.NoError(t, err)
+	for _, key := range sortedKeys(data) {
+		require.NoError(t, sequential.AddBlock(key, data[key]))
+	}
+
+	batched, err := New(NewMemStore(), WithClock(testClock))
+	require.NoError(t, err)
+	require.NoError(t, batched.AddBlocks(data))
+
+	expected, err := sequential.Matrix()
+	require.NoError(t, err)
+	actual, err := batched.Matrix()
+	require.NoError(t, err)
+	require.Equal(t, expected, actual)
+
+	expectedRoot, err := sequential.RootHash()
+	require.NoError(t, err)
+	actualRoot, err := batched.RootHash()
+	require.NoError(t, err)
+	require.Equal(t, expectedRoot, actualRoot)
+
+	keys, err := batched.Keys()
+	require.NoError(t, err)
+	require.Equal(t, sortedKeys(data), keys)
+
+	// a key that cannot be added fails the whole batch
+	err = batched.AddBlocks(map[string][]byte{"new": []byte("new"), strings.Repeat("k", DefaultMaxKeyLength+1): {1}})
+	require.ErrorIs(t, err, ErrKeyTooLong)
+	_, err = batched.GetBlock("new")
+	require.ErrorIs(t, err, ErrNotFound)
+	count, err := batched.BlockCount()
+	require.NoError(t, err)
+	require.Equal(t, 20, count)
+}
+
+// BenchmarkAddBlocks loads 100 blocks into an empty block matrix with AddBlock and with AddBlocks and reports the number
+// of values written to the store.
+func BenchmarkAddBlocks(b *testing.B) {
+	data := testData(100)
+
+	for _, batched := range []bool{false, true} {
+		b.Run(fmt.Sprintf("batched=%t", batched), func(b *testing.B) {
+			puts := int64(0)
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				db, err := leveldb.Open(storage.NewMemStorage(), nil)
+				require.NoError(b, err)
+				store := NewCountingStore(&leveldbStore{db: db})
+				bm, err := New(store)
+				require.NoError(b, err)
+				store.Reset()
+				b.StartTimer()
+
+				if batched {
+					require.NoError(b, bm.AddBlocks(data))
+				} else {
+					for _, key := range sortedKeys(data) {
+						require.NoError(b, bm.AddBlock(key, data[key]))
+					}
+				}
+
+				b.StopTimer()
+				puts += store.Puts()
+				require.NoError(b, db.Close())
+				b.StartTimer()
+			}
+
+			b.ReportMetric(float64(puts)/float64(b.N), "puts/op")
+		})
+	}
+}
@@ -0,0 +1,185 @@
+package blockmatrix
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+// changeLogPrefix prefixes the change log entries.  Each entry records the root hash of the block matrix after a write,
+// the blocks written to reach it, and when it was written.  Entries are keyed by a zero padded sequence number so they
+// iterate in order.
+const changeLogPrefix = "changelog:"
+
+type changeLogEntry struct {
+	Root   []byte `json:"root"`
+	Blocks []int  `json:"blocks"`
+	// Time is when the entry was written in nanoseconds since the Unix epoch, 0 for entries written before it was recorded
+	Time int64 `json:"time,omitempty"`
+}
+
+func changeLogKey(seq uint64) []byte {
+	return []byte(fmt.Sprintf("%s%020d", changeLogPrefix, seq))
+}
+
+// trackChange remembers the block number if key is a block entry so the next change log entry includes it.
+func (b *BlockMatrix) trackChange(key []byte) {
+	blockNum, err := strconv.Atoi(string(key))
+	if err != nil {
+		return
+	}
+
+	if b.changed == nil {
+		b.changed = make(map[int]bool)
+	}
+	b.changed[blockNum] = true
+}
+
+// recordChanges appends an entry with the root hash of info and the blocks written since the last entry to the change
+// log.
+func (b *BlockMatrix) recordChanges(info *BlockMatrixInfo) error {
+	entry := changeLogEntry{
+		Root:   b.rootHash(info),
+		Blocks: sortedIndices(b.changed),
+		Time:   time.Now().UnixNano(),
+	}
+
+	bytes, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	seq, err := b.nextChangeLogSeq()
+	if err != nil {
+		return err
+	}
+
+	if err = b.put(changeLogKey(seq), bytes); err != nil {
+		return err
+	}
+
+	b.changed = nil
+	return nil
+}
+
+// nextChangeLogSeq returns the sequence number of the next change log entry.  The change log is scanned for the last
+// sequence number the first time, after which the sequence number is counted in memory, so only one BlockMatrix should
+// write to a store at a time.
+func (b *BlockMatrix) nextChangeLogSeq() (uint64, error) {
+	return b.nextSeq(changeLogPrefix, &b.changeLogSeq)
+}
+
+// nextSeq returns the next sequence number of the entries under prefix, scanning them for the last one if next is 0 and
+// counting in memory afterwards.
+func (b *BlockMatrix) nextSeq(prefix string, next *uint64) (uint64, error) {
+	if *next == 0 {
+		err := b.iterate([]byte(prefix), func(key []byte, value []byte) error {
+			seq, err := strconv.ParseUint(string(key[len(prefix):]), 10, 64)
+			if err != nil {
+				return err
+			}
+
+			*next = seq + 1
+			return nil
+		})
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	seq := *next
+	*next++
+	return seq, nil
+}
+
+// ChangesSince returns the sorted numbers of the blocks written since the block matrix had the given root hash.  It is
+// meant for followers that know the root they last synced and only need the blocks changed since.  If the root hash
+// occurs more than once, for example after a block is added and then erased, the changes since its latest occurrence
+// are returned.  An error wrapping ErrUnknownRoot is returned if the root hash is not in the change log.
+func (b *BlockMatrix) ChangesSince(lastRoot []byte) ([]int, error) {
+	defer b.startSpan("ChangesSince")()
+
+	var changed map[int]bool
+	err := b.iterate([]byte(changeLogPrefix), func(key []byte, value []byte) error {
+		entry := changeLogEntry{}
+		if err := json.Unmarshal(value, &entry); err != nil {
+			return err
+		}
+
+		if reflect.DeepEqual(entry.Root, lastRoot) {
+			changed = make(map[int]bool)
+			return nil
+		}
+
+		if changed == nil {
+			return nil
+		}
+
+		for _, blockNum := range entry.Blocks {
+			changed[blockNum] = true
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if changed == nil {
+		return nil, fmt.Errorf("%w: %x", ErrUnknownRoot, lastRoot)
+	}
+
+	return sortedIndices(changed), nil
+}
+
+// rootChain returns the root hashes recorded in the change log, oldest first.  Consecutive entries with the same root
+// are only included once.
+func (b *BlockMatrix) rootChain() ([][]byte, error) {
+	chain := make([][]byte, 0)
+	err := b.iterate([]byte(changeLogPrefix), func(key []byte, value []byte) error {
+		entry := changeLogEntry{}
+		if err := json.Unmarshal(value, &entry); err != nil {
+			return err
+		}
+
+		if len(chain) == 0 || !reflect.DeepEqual(chain[len(chain)-1], entry.Root) {
+			chain = append(chain, entry.Root)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return chain, nil
+}
+
+// lastWriteTimes returns when each block was last written according to the change log.  Blocks written before times
+// were recorded in the change log are missing.
+func (b *BlockMatrix) lastWriteTimes() (map[int]time.Time, error) {
+	times := make(map[int]time.Time)
+	err := b.iterate([]byte(changeLogPrefix), func(key []byte, value []byte) error {
+		entry := changeLogEntry{}
+		if err := json.Unmarshal(value, &entry); err != nil {
+			return err
+		}
+
+		for _, blockNum := range entry.Blocks {
+			if entry.Time == 0 {
+				delete(times, blockNum)
+			} else {
+				times[blockNum] = time.Unix(0, entry.Time)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return times, nil
+}
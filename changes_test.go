@@ -0,0 +1,49 @@
+package blockmatrix
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestChangesSince(t *testing.T) {
+	db := newTestDB(t)
+	bm, err := NewWithLevelDB(db)
+	require.NoError(t, err)
+
+	emptyRoot, err := bm.RootHash()
+	require.NoError(t, err)
+
+	require.NoError(t, createTestBlocks(bm, 4))
+	root, err := bm.RootHash()
+	require.NoError(t, err)
+
+	changes, err := bm.ChangesSince(root)
+	require.NoError(t, err)
+	require.Empty(t, changes)
+
+	require.NoError(t, bm.AddBlock("key5", []byte{5}))
+	require.NoError(t, bm.EraseBlock("key2"))
+
+	changes, err = bm.ChangesSince(root)
+	require.NoError(t, err)
+	require.Equal(t, []int{2, 5}, changes)
+
+	// growing the block matrix writes the empty blocks 5 and 6 which are included
+	changes, err = bm.ChangesSince(emptyRoot)
+	require.NoError(t, err)
+	require.Equal(t, []int{1, 2, 3, 4, 5, 6}, changes)
+
+	// the change log is persisted with the block matrix
+	reopened, err := NewWithLevelDB(db)
+	require.NoError(t, err)
+	root, err = reopened.RootHash()
+	require.NoError(t, err)
+	require.NoError(t, reopened.AddBlock("key6", []byte{6}))
+
+	changes, err = reopened.ChangesSince(root)
+	require.NoError(t, err)
+	require.Equal(t, []int{6}, changes)
+
+	_, err = bm.ChangesSince([]byte("unknown"))
+	require.ErrorIs(t, err, ErrUnknownRoot)
+}
@@ -0,0 +1,62 @@
+package blockmatrix
+
+import (
+	"reflect"
+)
+
+// Checkpoint is a snapshot of the root hash and the row and column hashes of a block matrix, taken with Checkpoint, that
+// DeltaSince compares the block matrix against later.
+type Checkpoint struct {
+	// Root is the root hash at the time of the checkpoint
+	Root []byte
+	// Size is the size of the block matrix at the time of the checkpoint
+	Size int
+	// Rows are the row hashes at the time of the checkpoint
+	Rows [][]byte
+	// Cols are the column hashes at the time of the checkpoint
+	Cols [][]byte
+}
+
+// Checkpoint captures the current root hash and row and column hashes of the block matrix.
+func (b *BlockMatrix) Checkpoint() (*Checkpoint, error) {
+	info, err := b.GetBlockMatrixInfo()
+	if err != nil {
+		return nil, err
+	}
+
+	info = copyInfo(info)
+	return &Checkpoint{
+		Root: b.rootHash(info),
+		Size: info.Size,
+		Rows: info.Rows,
+		Cols: info.Cols,
+	}, nil
+}
+
+// DeltaSince returns the indices of the rows and columns whose hashes changed since the checkpoint, in ascending order.
+// Only the stored hashes are compared, so detecting changes is cheap, but a row or column changed and then changed back
+// is not reported.  If the block matrix grew since the checkpoint, the rows and columns it grew by are reported as
+// changed.
+func (b *BlockMatrix) DeltaSince(cp *Checkpoint) (rows []int, cols []int, err error) {
+	info, err := b.GetBlockMatrixInfo()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rows = make([]int, 0)
+	cols = make([]int, 0)
+	if reflect.DeepEqual(b.rootHash(info), cp.Root) && info.Size == cp.Size {
+		return rows, cols, nil
+	}
+
+	for i := 0; i < info.Size; i++ {
+		if i >= len(cp.Rows) || !reflect.DeepEqual(info.Rows[i], cp.Rows[i]) {
+			rows = append(rows, i)
+		}
+		if i >= len(cp.Cols) || !reflect.DeepEqual(info.Cols[i], cp.Cols[i]) {
+			cols = append(cols, i)
+		}
+	}
+
+	return rows, cols, nil
+}
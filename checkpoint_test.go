@@ -0,0 +1,29 @@
+package blockmatrix
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestDeltaSince(t *testing.T) {
+	bm, err := NewWithLevelDB(newTestDB(t))
+	require.NoError(t, err)
+	require.NoError(t, createTestBlocks(bm, 4))
+
+	cp, err := bm.Checkpoint()
+	require.NoError(t, err)
+
+	rows, cols, err := bm.DeltaSince(cp)
+	require.NoError(t, err)
+	require.Empty(t, rows)
+	require.Empty(t, cols)
+
+	// block 5 fits in the size 3 block matrix
+	require.NoError(t, bm.AddBlock("key5", []byte("value5")))
+	row, col := locateBlock(5)
+
+	rows, cols, err = bm.DeltaSince(cp)
+	require.NoError(t, err)
+	require.Equal(t, []int{row}, rows)
+	require.Equal(t, []int{col}, cols)
+}
@@ -0,0 +1,198 @@
+package blockmatrix
+
+import (
+	"bytes"
+	"compress/flate"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+)
+
+// ErrUnknownCodec is returned when reading a block encoded with a codec the block matrix is not configured with.
+var ErrUnknownCodec = errors.New("unknown codec")
+
+// Codec transforms the data of blocks on its way to and from storage, for example to compress or encrypt it.  Codecs are
+// installed with WithCodecs.  Block hashes are always computed over the plain data so codecs do not change the row,
+// column, or root hashes.
+type Codec interface {
+	// Name identifies the codec in the codec chain recorded on every stored block, it must be unique among the codecs
+	// of a block matrix and must not change once blocks are written with it
+	Name() string
+	// Encode transforms data for storage
+	Encode(data []byte) ([]byte, error)
+	// Decode reverses Encode
+	Decode(data []byte) ([]byte, error)
+}
+
+type (
+	// flateCodec compresses data with DEFLATE.
+	flateCodec struct{}
+
+	// aesGCMCodec encrypts data with AES-GCM, prefixing the ciphertext with a random nonce.
+	aesGCMCodec struct {
+		aead cipher.AEAD
+	}
+
+	// crc32Codec appends the CRC-32 (IEEE) checksum of the data and verifies it on decode.
+	crc32Codec struct{}
+)
+
+// FlateCodec returns a codec that compresses block data with DEFLATE.
+func FlateCodec() Codec {
+	return flateCodec{}
+}
+
+func (flateCodec) Name() string {
+	return "flate"
+}
+
+func (flateCodec) Encode(data []byte) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	w, err := flate.NewWriter(buf, flate.DefaultCompression)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err = w.Write(data); err != nil {
+		return nil, err
+	}
+
+	if err = w.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (flateCodec) Decode(data []byte) ([]byte, error) {
+	r := flate.NewReader(bytes.NewReader(data))
+	defer r.Close()
+
+	return ioutil.ReadAll(r)
+}
+
+// AESGCMCodec returns a codec that encrypts block data with AES-GCM using the given 16, 24, or 32 byte key.
+func AESGCMCodec(key []byte) (Codec, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	return &aesGCMCodec{aead: aead}, nil
+}
+
+func (c *aesGCMCodec) Name() string {
+	return "aes-gcm"
+}
+
+func (c *aesGCMCodec) Encode(data []byte) ([]byte, error) {
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+
+	return c.aead.Seal(nonce, nonce, data, nil), nil
+}
+
+func (c *aesGCMCodec) Decode(data []byte) ([]byte, error) {
+	if len(data) < c.aead.NonceSize() {
+		return nil, fmt.Errorf("%w: ciphertext is shorter than the nonce", ErrAuthenticationFailed)
+	}
+
+	plain, err := c.aead.Open(nil, data[:c.aead.NonceSize()], data[c.aead.NonceSize():], nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrAuthenticationFailed, err)
+	}
+
+	return plain, nil
+}
+
+// CRC32Codec returns a codec that appends a CRC-32 checksum to block data and returns an error wrapping ErrHashMismatch
+// on decode if the checksum does not match, catching corruption before the block hash is checked.
+func CRC32Codec() Codec {
+	return crc32Codec{}
+}
+
+func (crc32Codec) Name() string {
+	return "crc32"
+}
+
+func (crc32Codec) Encode(data []byte) ([]byte, error) {
+	sum := make([]byte, crc32.Size)
+	binary.BigEndian.PutUint32(sum, crc32.ChecksumIEEE(data))
+
+	return append(append([]byte{}, data...), sum...), nil
+}
+
+func (crc32Codec) Decode(data []byte) ([]byte, error) {
+	if len(data) < crc32.Size {
+		return nil, fmt.Errorf("%w: data is shorter than its checksum", ErrHashMismatch)
+	}
+
+	data, sum := data[:len(data)-crc32.Size], data[len(data)-crc32.Size:]
+	if binary.BigEndian.Uint32(sum) != crc32.ChecksumIEEE(data) {
+		return nil, fmt.Errorf("%w: checksum does not match the data", ErrHashMismatch)
+	}
+
+	return data, nil
+}
+
+// encodeData encodes data with every configured codec in order and returns the encoded data with the names of the
+// codecs.
+func (b *BlockMatrix) encodeData(data []byte) ([]byte, []string, error) {
+	if len(b.codecs) == 0 {
+		return data, nil, nil
+	}
+
+	names := make([]string, 0, len(b.codecs))
+	for _, codec := range b.codecs {
+		var err error
+		if data, err = codec.Encode(data); err != nil {
+			return nil, nil, fmt.Errorf("error encoding block data with codec %s: %w", codec.Name(), err)
+		}
+
+		names = append(names, codec.Name())
+	}
+
+	return data, names, nil
+}
+
+// decodeData decodes data encoded with the named codecs, in reverse order.  The codecs are looked up by name so data is
+// decoded with the chain it was written with regardless of the order the codecs are configured in.
+func (b *BlockMatrix) decodeData(data []byte, names []string) ([]byte, error) {
+	for i := len(names) - 1; i >= 0; i-- {
+		codec := b.codec(names[i])
+		if codec == nil {
+			return nil, fmt.Errorf("%w: block data was encoded with codec %s", ErrUnknownCodec, names[i])
+		}
+
+		var err error
+		if data, err = codec.Decode(data); err != nil {
+			return nil, fmt.Errorf("error decoding block data with codec %s: %w", names[i], err)
+		}
+	}
+
+	return data, nil
+}
+
+// codec returns the configured codec with the given name, or nil if there is none.
+func (b *BlockMatrix) codec(name string) Codec {
+	for _, codec := range b.codecs {
+		if codec.Name() == name {
+			return codec
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,82 @@
+package blockmatrix
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestWithCodecs(t *testing.T) {
+	encryption, err := AESGCMCodec(bytes.Repeat([]byte{7}, 32))
+	require.NoError(t, err)
+
+	db := newTestDB(t)
+	plain, err := NewWithLevelDB(db)
+	require.NoError(t, err)
+	require.NoError(t, plain.AddBlock("plain", []byte("plain")))
+
+	bm, err := NewWithLevelDB(db, WithCodecs(FlateCodec(), encryption))
+	require.NoError(t, err)
+	data := bytes.Repeat([]byte("compressible "), 100)
+	require.NoError(t, bm.AddBlock("big", data))
+	require.NoError(t, createTestBlocks(bm, 3))
+
+	block, err := bm.GetBlock("big")
+	require.NoError(t, err)
+	require.Equal(t, data, block.Data)
+	require.Equal(t, block.CalculateHash(), block.Hash)
+	require.Nil(t, block.Codecs)
+
+	stored, err := db.Get([]byte("2"), nil)
+	require.NoError(t, err)
+	storedBlock, err := BinaryBlockEncoding().Unmarshal(stored)
+	require.NoError(t, err)
+	require.Equal(t, []string{"flate", "aes-gcm"}, storedBlock.Codecs)
+	require.NotContains(t, string(storedBlock.Data), "compressible")
+	require.Less(t, len(storedBlock.Data), len(data))
+
+	ok, err := bm.IsValid()
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	// the chain recorded on each block decides the decode order, not the configured order
+	reordered, err := NewWithLevelDB(db, WithCodecs(encryption, FlateCodec()))
+	require.NoError(t, err)
+	block, err = reordered.GetBlock("big")
+	require.NoError(t, err)
+	require.Equal(t, data, block.Data)
+
+	// blocks written without codecs remain readable
+	block, err = reordered.GetBlock("plain")
+	require.NoError(t, err)
+	require.Equal(t, []byte("plain"), block.Data)
+
+	_, err = plain.GetBlock("big")
+	require.ErrorIs(t, err, ErrUnknownCodec)
+
+	otherKey, err := AESGCMCodec(bytes.Repeat([]byte{8}, 32))
+	require.NoError(t, err)
+	wrongKey, err := NewWithLevelDB(db, WithCodecs(FlateCodec(), otherKey))
+	require.NoError(t, err)
+	_, err = wrongKey.GetBlock("big")
+	require.ErrorIs(t, err, ErrAuthenticationFailed)
+}
+
+func TestCRC32Codec(t *testing.T) {
+	db := newTestDB(t)
+	bm, err := NewWithLevelDB(db, WithCodecs(CRC32Codec()))
+	require.NoError(t, err)
+	require.NoError(t, bm.AddBlock("key1", []byte("data")))
+
+	stored, err := db.Get([]byte("1"), nil)
+	require.NoError(t, err)
+	storedBlock, err := BinaryBlockEncoding().Unmarshal(stored)
+	require.NoError(t, err)
+	storedBlock.Data[0] ^= 0xff
+	stored, err = BinaryBlockEncoding().Marshal(storedBlock)
+	require.NoError(t, err)
+	require.NoError(t, db.Put([]byte("1"), stored, nil))
+
+	_, err = bm.GetBlock("key1")
+	require.ErrorIs(t, err, ErrHashMismatch)
+}
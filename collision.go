@@ -0,0 +1,63 @@
+package blockmatrix
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// hashIndexPrefix prefixes the entries mapping a block hash to the number of the last block written with it, kept when
+// hash collisions are checked.
+const hashIndexPrefix = "hash:"
+
+func hashIndexEntry(hash []byte) []byte {
+	return []byte(fmt.Sprintf("%s%x", hashIndexPrefix, hash))
+}
+
+// checkHashCollision returns an error if the block collides with the indexed block with the same hash, i.e. the indexed
+// block still has that hash but different data.  The configured handler decides the error, by default it wraps
+// ErrHashCollision.  get and getBlock read the database the block is about to be written to.
+func (b *BlockMatrix) checkHashCollision(block *Block, get func(key []byte) ([]byte, error),
+	getBlock func(blockNum int) (*Block, error)) error {
+	if !b.hashCollisionCheck || block.IsEmpty() {
+		return nil
+	}
+
+	value, err := get(hashIndexEntry(block.Hash))
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	blockNum, err := strconv.Atoi(string(value))
+	if err != nil {
+		return err
+	}
+
+	existing, err := getBlock(blockNum)
+	if err != nil {
+		return err
+	}
+
+	if !bytes.Equal(existing.Hash, block.Hash) || bytes.Equal(existing.Data, block.Data) {
+		return nil
+	}
+
+	if b.hashCollisionHandler != nil {
+		return b.hashCollisionHandler(block.Hash, blockNum)
+	}
+
+	return fmt.Errorf("%w: block %d already has hash %x with different data", ErrHashCollision, blockNum, block.Hash)
+}
+
+// indexBlockHash records the block number as the last block written with the block's hash, if hash collisions are
+// checked.
+func (b *BlockMatrix) indexBlockHash(blockNum int, block *Block) error {
+	if !b.hashCollisionCheck || block.IsEmpty() {
+		return nil
+	}
+
+	return b.put(hashIndexEntry(block.Hash), []byte(strconv.Itoa(blockNum)))
+}
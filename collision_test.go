@@ -0,0 +1,76 @@
+package blockmatrix
+
+import (
+	"crypto/sha256"
+	"errors"
+	"github.com/stretchr/testify/require"
+	"hash"
+	"testing"
+)
+
+// constantHash is a hash function that hashes everything to the same value, so any two different blocks collide.
+type constantHash struct {
+	hash.Hash
+}
+
+func newConstantHash() hash.Hash {
+	return constantHash{Hash: sha256.New()}
+}
+
+func (constantHash) Sum(b []byte) []byte {
+	return append(b, make([]byte, sha256.Size)...)
+}
+
+func TestWithHashCollisionCheck(t *testing.T) {
+	bm, err := NewWithLevelDB(newTestDB(t), WithHasher(newConstantHash), WithHashCollisionCheck(nil))
+	require.NoError(t, err)
+	require.NoError(t, bm.AddBlock("key1", []byte{1}))
+	// the same data is not a collision
+	require.NoError(t, bm.AddBlock("key2", []byte{1}))
+
+	err = bm.AddBlock("key3", []byte{3})
+	require.ErrorIs(t, err, ErrHashCollision)
+	_, err = bm.GetBlock("key3")
+	require.ErrorIs(t, err, ErrNotFound)
+
+	err = bm.UpdateBlock("key1", []byte{3})
+	require.ErrorIs(t, err, ErrHashCollision)
+	block, err := bm.GetBlock("key1")
+	require.NoError(t, err)
+	require.Equal(t, []byte{1}, block.Data)
+
+	tx := bm.Begin()
+	require.NoError(t, tx.AddBlock("key3", []byte{3}))
+	require.ErrorIs(t, tx.Commit(), ErrHashCollision)
+
+	ok, err := bm.IsValid()
+	require.NoError(t, err)
+	require.True(t, ok)
+}
+
+func TestHashCollisionHandler(t *testing.T) {
+	errRejected := errors.New("rejected")
+	collisions := make([]int, 0)
+	bm, err := NewWithLevelDB(newTestDB(t), WithHasher(newConstantHash),
+		WithHashCollisionCheck(func(hash []byte, existing int) error {
+			collisions = append(collisions, existing)
+			if len(collisions) > 1 {
+				return errRejected
+			}
+
+			return nil
+		}))
+	require.NoError(t, err)
+	require.NoError(t, bm.AddBlock("key1", []byte{1}))
+
+	// the first collision is allowed by the handler, the second is rejected
+	require.NoError(t, bm.AddBlock("key2", []byte{2}))
+	require.ErrorIs(t, bm.AddBlock("key3", []byte{3}), errRejected)
+	require.Equal(t, []int{1, 2}, collisions)
+
+	// without the check the same blocks are written silently
+	bm, err = NewWithLevelDB(newTestDB(t), WithHasher(newConstantHash))
+	require.NoError(t, err)
+	require.NoError(t, bm.AddBlock("key1", []byte{1}))
+	require.NoError(t, bm.AddBlock("key2", []byte{2}))
+}
@@ -0,0 +1,279 @@
+package blockmatrix
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"reflect"
+)
+
+type (
+	// Commitment is a snapshot of the hashes of a block matrix that can be shared with remote verifiers.  It commits to
+	// the data of every block without containing any of it.
+	Commitment struct {
+		// Size of the block matrix (dimension)
+		Size int `json:"size"`
+		// BlockCount is the number of blocks in the block matrix
+		BlockCount int `json:"block_count"`
+		// Rows stores the hashes of each row in the block matrix
+		Rows [][]byte `json:"rows"`
+		// Cols stores the hashes of each column in the block matrix
+		Cols [][]byte `json:"cols"`
+		// Root is the hash of the row and column hashes, of the truncated ones if HashLength is not 0
+		Root []byte `json:"root"`
+		// RootChain is the history of root hashes the block matrix has had, oldest first and ending with its current
+		// root, which is Root unless HashLength is not 0
+		RootChain [][]byte `json:"root_chain"`
+		// HashLength is the number of bytes the row, column, and root hashes are truncated to, 0 if they are not
+		// truncated, see WithCommitmentHashLength
+		HashLength int `json:"hash_length,omitempty"`
+		// Hasher identifies the hash function of the block matrix, it is empty for SHA-256
+		Hasher string `json:"hasher,omitempty"`
+	}
+
+	// Bundle holds everything a remote verifier needs to check that a block is included in a block matrix given only a
+	// Commitment: the block itself and the hashes of every block in its row and column.
+	Bundle struct {
+		// BlockNumber is the number of the block being verified
+		BlockNumber int `json:"block_number"`
+		// Row is the index of the row the block is in
+		Row int `json:"row"`
+		// Col is the index of the column the block is in
+		Col int `json:"col"`
+		// Block is the block being verified
+		Block *Block `json:"block"`
+		// RowHashes stores the hashes of the blocks in the block's row, in row hash order
+		RowHashes [][]byte `json:"row_hashes"`
+		// ColumnHashes stores the hashes of the blocks in the block's column, in column hash order
+		ColumnHashes [][]byte `json:"column_hashes"`
+	}
+)
+
+// Commitment returns a commitment to the current state of the block matrix.
+func (b *BlockMatrix) Commitment() (*Commitment, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	info, err := b.GetBlockMatrixInfo()
+	if err != nil {
+		return nil, err
+	}
+
+	chain, err := b.rootChain()
+	if err != nil {
+		return nil, err
+	}
+
+	commitment := &Commitment{
+		Size:       info.Size,
+		BlockCount: info.BlockCount,
+		Rows:       info.Rows,
+		Cols:       info.Cols,
+		Root:       b.rootHash(info),
+		RootChain:  chain,
+		Hasher:     info.Hasher,
+	}
+
+	if n := b.commitmentHashLength; n > 0 && n < b.hasherSize() {
+		commitment.HashLength = n
+		commitment.Rows = truncateHashes(commitment.Rows, n)
+		commitment.Cols = truncateHashes(commitment.Cols, n)
+		commitment.RootChain = truncateHashes(commitment.RootChain, n)
+
+		// the root is recomputed over the truncated hashes so that it can be checked against them
+		commitment.Root = truncateHash(calculateRootHash(b.hasher,
+			&BlockMatrixInfo{Rows: commitment.Rows, Cols: commitment.Cols}), n)
+	}
+
+	return commitment, nil
+}
+
+// truncateHash returns the first n bytes of hash, or hash itself if it is not longer than n or n is 0.
+func truncateHash(hash []byte, n int) []byte {
+	if n <= 0 || len(hash) <= n {
+		return hash
+	}
+
+	return hash[:n]
+}
+
+func truncateHashes(hashes [][]byte, n int) [][]byte {
+	truncated := make([][]byte, len(hashes))
+	for i, hash := range hashes {
+		truncated[i] = truncateHash(hash, n)
+	}
+
+	return truncated
+}
+
+// IsAncestor reports whether the block matrix committed to by older could have become the one committed to by newer.
+// Root chains are append-only so older is an ancestor when its root chain is a prefix of newer's.  If the chains differ
+// before older's ends the two block matrices have forked.  An error is returned if either root chain does not end with
+// its commitment's root or if the commitments' hashes are truncated to different lengths.  The root chain of a truncated
+// commitment holds the truncated roots of the full row and column hashes, not the root of its truncated ones, so only
+// the chains of truncated commitments are compared.
+func IsAncestor(older, newer *Commitment) (bool, error) {
+	if older.HashLength != newer.HashLength {
+		return false, fmt.Errorf("commitments with hash lengths %d and %d cannot be compared", older.HashLength,
+			newer.HashLength)
+	}
+
+	for _, commitment := range []*Commitment{older, newer} {
+		if commitment.HashLength != 0 {
+			break
+		}
+
+		chain := commitment.RootChain
+		if len(chain) == 0 || !reflect.DeepEqual(chain[len(chain)-1], commitment.Root) {
+			return false, fmt.Errorf("root chain of commitment with root %x does not end with the root", commitment.Root)
+		}
+	}
+
+	if len(older.RootChain) > len(newer.RootChain) {
+		return false, nil
+	}
+
+	for i, root := range older.RootChain {
+		if !reflect.DeepEqual(root, newer.RootChain[i]) {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+// VerificationBundle returns the minimal set of data needed to verify that the block associated with the given key is
+// included in the block matrix: the block and the hashes of the other blocks in its row and column.  Row and column
+// hashes only depend on block hashes so the data of the other blocks is not needed.
+func (b *BlockMatrix) VerificationBundle(key string) (*Bundle, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	blockNum, err := b.BlockNumber(key)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := b.GetBlockByNumber(blockNum)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := b.GetBlockMatrixInfo()
+	if err != nil {
+		return nil, err
+	}
+
+	row, col := locateBlock(blockNum)
+	bundle := &Bundle{
+		BlockNumber: blockNum,
+		Row:         row,
+		Col:         col,
+		Block:       block,
+	}
+
+	rowBlocks, err := rowBlockNumbers(row, info.Size)
+	if err != nil {
+		return nil, err
+	}
+
+	if bundle.RowHashes, err = b.blockHashes(rowBlocks); err != nil {
+		return nil, err
+	}
+
+	colBlocks, err := columnBlockNumbers(col, info.Size)
+	if err != nil {
+		return nil, err
+	}
+
+	if bundle.ColumnHashes, err = b.blockHashes(colBlocks); err != nil {
+		return nil, err
+	}
+
+	return bundle, nil
+}
+
+// blockHashes returns the hashes of the blocks with the given numbers, in order.
+func (b *BlockMatrix) blockHashes(blockNums []int) ([][]byte, error) {
+	hashes := make([][]byte, 0, len(blockNums))
+	for _, blockNum := range blockNums {
+		block, err := b.GetBlockByNumber(blockNum)
+		if err != nil {
+			return nil, err
+		}
+
+		hashes = append(hashes, block.Hash)
+	}
+
+	return hashes, nil
+}
+
+// Verify checks that the bundle's block is included in the block matrix described by the commitment.  The block's hash
+// must match its data and appear at the block's position in its row and column, the row and column hashes recomputed
+// from the bundle must match the commitment, and the commitment's root must match its row and column hashes.  If the
+// commitment's hashes are truncated the recomputed row and column hashes are truncated the same way before they are
+// compared, and the root is checked against the truncated row and column hashes.  Commitments of block matrices created
+// WithHasher are rejected since the hash function cannot be recovered from its fingerprint.
+func (bundle *Bundle) Verify(commitment *Commitment) (bool, error) {
+	if commitment.Hasher != "" {
+		return false, fmt.Errorf("bundles are verified with SHA-256, cannot verify a commitment using hasher %s",
+			commitment.Hasher)
+	}
+
+	if len(commitment.Rows) != commitment.Size || len(commitment.Cols) != commitment.Size {
+		return false, fmt.Errorf("commitment has %d rows and %d columns for size %d", len(commitment.Rows),
+			len(commitment.Cols), commitment.Size)
+	}
+
+	root := calculateRootHash(sha256.New, &BlockMatrixInfo{Rows: commitment.Rows, Cols: commitment.Cols})
+	if !reflect.DeepEqual(truncateHash(root, commitment.HashLength), commitment.Root) {
+		return false, nil
+	}
+
+	if bundle.Block == nil || !reflect.DeepEqual(bundle.Block.Hash, bundle.Block.CalculateHash()) {
+		return false, nil
+	}
+
+	if row, col := locateBlock(bundle.BlockNumber); row != bundle.Row || col != bundle.Col || row >= commitment.Size ||
+		col >= commitment.Size {
+		return false, nil
+	}
+
+	rowBlocks, err := rowBlockNumbers(bundle.Row, commitment.Size)
+	if err != nil {
+		return false, err
+	}
+
+	if !verifyBundleHashes(bundle.BlockNumber, bundle.Block.Hash, rowBlocks, bundle.RowHashes,
+		commitment.Rows[bundle.Row], commitment.HashLength) {
+		return false, nil
+	}
+
+	colBlocks, err := columnBlockNumbers(bundle.Col, commitment.Size)
+	if err != nil {
+		return false, err
+	}
+
+	return verifyBundleHashes(bundle.BlockNumber, bundle.Block.Hash, colBlocks, bundle.ColumnHashes,
+		commitment.Cols[bundle.Col], commitment.HashLength), nil
+}
+
+// verifyBundleHashes checks that blockHash is at the position of blockNum in hashes and that the hash of hashes,
+// truncated to hashLength bytes if it is not 0, is the expected row or column hash.
+func verifyBundleHashes(blockNum int, blockHash []byte, blockNums []int, hashes [][]byte, expected []byte,
+	hashLength int) bool {
+	if len(blockNums) != len(hashes) {
+		return false
+	}
+
+	h := sha256.New()
+	found := false
+	for i, num := range blockNums {
+		if num == blockNum {
+			found = reflect.DeepEqual(hashes[i], blockHash)
+		}
+
+		h.Write(hashes[i])
+	}
+
+	return found && reflect.DeepEqual(truncateHash(h.Sum(nil), hashLength), expected)
+}
@@ -0,0 +1,155 @@
+package blockmatrix
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestVerificationBundle(t *testing.T) {
+	bm, err := NewWithLevelDB(newTestDB(t))
+	require.NoError(t, err)
+	require.NoError(t, createTestBlocks(bm, 9))
+
+	commitment, err := bm.Commitment()
+	require.NoError(t, err)
+	bundle, err := bm.VerificationBundle("key5")
+	require.NoError(t, err)
+	require.Len(t, bundle.RowHashes, commitment.Size-1)
+	require.Len(t, bundle.ColumnHashes, commitment.Size-1)
+
+	// the remote verifier only receives the serialized bundle and commitment
+	bundleBytes, err := json.Marshal(bundle)
+	require.NoError(t, err)
+	commitmentBytes, err := json.Marshal(commitment)
+	require.NoError(t, err)
+
+	remoteBundle := &Bundle{}
+	require.NoError(t, json.Unmarshal(bundleBytes, remoteBundle))
+	remoteCommitment := &Commitment{}
+	require.NoError(t, json.Unmarshal(commitmentBytes, remoteCommitment))
+
+	ok, err := remoteBundle.Verify(remoteCommitment)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	// tampered block data
+	remoteBundle.Block.Data = []byte{50}
+	ok, err = remoteBundle.Verify(remoteCommitment)
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	// a block with a consistent hash that is not the committed block
+	remoteBundle.Block = NewBlock([]byte{50})
+	ok, err = remoteBundle.Verify(remoteCommitment)
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	// a commitment from after a block in the same row (block 9) was erased
+	require.NoError(t, bm.EraseBlock("key9"))
+	newCommitment, err := bm.Commitment()
+	require.NoError(t, err)
+	ok, err = bundle.Verify(newCommitment)
+	require.NoError(t, err)
+	require.False(t, ok)
+}
+
+func TestIsAncestor(t *testing.T) {
+	db := newTestDB(t)
+	bm, err := NewWithLevelDB(db)
+	require.NoError(t, err)
+	require.NoError(t, createTestBlocks(bm, 5))
+
+	older, err := bm.Commitment()
+	require.NoError(t, err)
+
+	// a copy of the block matrix that diverges from the original
+	fork, err := NewWithLevelDB(copyTestDB(t, db))
+	require.NoError(t, err)
+	require.NoError(t, fork.AddBlock("fork", []byte("fork")))
+	forked, err := fork.Commitment()
+	require.NoError(t, err)
+
+	require.NoError(t, bm.AddBlock("key6", []byte{6}))
+	require.NoError(t, bm.EraseBlock("key2"))
+	newer, err := bm.Commitment()
+	require.NoError(t, err)
+
+	ok, err := IsAncestor(older, newer)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	ok, err = IsAncestor(newer, older)
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	ok, err = IsAncestor(older, forked)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	ok, err = IsAncestor(newer, forked)
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	ok, err = IsAncestor(forked, newer)
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	newer.RootChain = newer.RootChain[:len(newer.RootChain)-1]
+	_, err = IsAncestor(older, newer)
+	require.Error(t, err)
+}
+
+func TestWithCommitmentHashLength(t *testing.T) {
+	bm, err := NewWithLevelDB(newTestDB(t), WithCommitmentHashLength(16))
+	require.NoError(t, err)
+	require.NoError(t, createTestBlocks(bm, 9))
+
+	commitment, err := bm.Commitment()
+	require.NoError(t, err)
+	require.Equal(t, 16, commitment.HashLength)
+	require.Len(t, commitment.Root, 16)
+	for i := 0; i < commitment.Size; i++ {
+		require.Len(t, commitment.Rows[i], 16)
+		require.Len(t, commitment.Cols[i], 16)
+	}
+
+	info, err := bm.GetBlockMatrixInfo()
+	require.NoError(t, err)
+	require.Len(t, info.Rows[0], 32)
+
+	bundle, err := bm.VerificationBundle("key5")
+	require.NoError(t, err)
+	ok, err := bundle.Verify(commitment)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	// the truncated root is checked
+	tampered := *commitment
+	tampered.Root = append([]byte{}, commitment.Root...)
+	tampered.Root[0] ^= 0xff
+	ok, err = bundle.Verify(&tampered)
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	// a bundle for a block that is not the committed one
+	require.NoError(t, bm.UpdateBlock("key5", []byte("updated")))
+	updated, err := bm.VerificationBundle("key5")
+	require.NoError(t, err)
+	ok, err = updated.Verify(commitment)
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	// truncated and full commitments cannot be compared
+	full, err := NewWithLevelDB(newTestDB(t))
+	require.NoError(t, err)
+	fullCommitment, err := full.Commitment()
+	require.NoError(t, err)
+	_, err = IsAncestor(fullCommitment, commitment)
+	require.Error(t, err)
+}
+
+func TestWithCommitmentHashLengthNegative(t *testing.T) {
+	_, err := NewWithLevelDB(newTestDB(t), WithCommitmentHashLength(-1))
+	require.Error(t, err)
+}
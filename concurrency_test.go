@@ -0,0 +1,48 @@
+package blockmatrix
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/require"
+	"sync"
+	"testing"
+)
+
+func TestConcurrentAddBlock(t *testing.T) {
+	bm, err := NewWithLevelDB(newTestDB(t))
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 100)
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs <- bm.AddBlock(fmt.Sprintf("key%d", i), []byte{byte(i)})
+
+			// readers run alongside the writers
+			if _, err := bm.Matrix(); err != nil {
+				errs <- err
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		require.NoError(t, err)
+	}
+
+	info, err := bm.GetBlockMatrixInfo()
+	require.NoError(t, err)
+	require.Equal(t, 50, info.BlockCount)
+
+	for i := 0; i < 50; i++ {
+		block, err := bm.GetBlock(fmt.Sprintf("key%d", i))
+		require.NoError(t, err)
+		require.Equal(t, []byte{byte(i)}, block.Data)
+	}
+
+	ok, err := bm.IsValid()
+	require.NoError(t, err)
+	require.True(t, ok)
+}
@@ -0,0 +1,81 @@
+package blockmatrix
+
+import (
+	"sync/atomic"
+)
+
+// CountingStore is a Store that counts the reads and writes made to the store it wraps, for measuring how many storage
+// operations each block matrix operation triggers.  Create the block matrix on the counting store, call Reset, run the
+// operation, and read the counts, for example Puts after AddBlock or Gets after IsValid.  Has calls are counted as gets
+// and every put and delete applied by Write is counted on its own, so the counts are the same whether or not the block
+// matrix batches its writes.  Iterate calls are not counted.  A CountingStore is safe for concurrent use if the store
+// it wraps is.
+type CountingStore struct {
+	store   Store
+	gets    int64
+	puts    int64
+	deletes int64
+}
+
+// NewCountingStore returns a store that counts the operations made to store.
+func NewCountingStore(store Store) *CountingStore {
+	return &CountingStore{store: store}
+}
+
+// Gets returns the number of Get and Has calls since the store was created or last reset.
+func (s *CountingStore) Gets() int64 {
+	return atomic.LoadInt64(&s.gets)
+}
+
+// Puts returns the number of values written since the store was created or last reset.
+func (s *CountingStore) Puts() int64 {
+	return atomic.LoadInt64(&s.puts)
+}
+
+// Deletes returns the number of keys deleted since the store was created or last reset.
+func (s *CountingStore) Deletes() int64 {
+	return atomic.LoadInt64(&s.deletes)
+}
+
+// Reset sets every count back to 0.
+func (s *CountingStore) Reset() {
+	atomic.StoreInt64(&s.gets, 0)
+	atomic.StoreInt64(&s.puts, 0)
+	atomic.StoreInt64(&s.deletes, 0)
+}
+
+func (s *CountingStore) Get(key []byte) ([]byte, error) {
+	atomic.AddInt64(&s.gets, 1)
+	return s.store.Get(key)
+}
+
+func (s *CountingStore) Has(key []byte) (bool, error) {
+	atomic.AddInt64(&s.gets, 1)
+	return s.store.Has(key)
+}
+
+func (s *CountingStore) Put(key []byte, value []byte) error {
+	atomic.AddInt64(&s.puts, 1)
+	return s.store.Put(key, value)
+}
+
+func (s *CountingStore) Delete(key []byte) error {
+	atomic.AddInt64(&s.deletes, 1)
+	return s.store.Delete(key)
+}
+
+func (s *CountingStore) Write(writes []StoreWrite) error {
+	for _, write := range writes {
+		if write.Delete {
+			atomic.AddInt64(&s.deletes, 1)
+		} else {
+			atomic.AddInt64(&s.puts, 1)
+		}
+	}
+
+	return s.store.Write(writes)
+}
+
+func (s *CountingStore) Iterate(prefix []byte, fn func(key []byte, value []byte) error) error {
+	return s.store.Iterate(prefix, fn)
+}
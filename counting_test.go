@@ -0,0 +1,43 @@
+package blockmatrix
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestCountingStore(t *testing.T) {
+	store := NewCountingStore(NewMemStore())
+	bm, err := New(store)
+	require.NoError(t, err)
+
+	// the first block grows the block matrix to size 2: the key, its key index entry, the block, an empty block in the
+	// other slot, the info, and the change log entry are written
+	store.Reset()
+	require.NoError(t, bm.AddBlock("key1", []byte("data1")))
+	require.Equal(t, int64(6), store.Puts())
+
+	// the second block fits without growing so only its own entries, the info, and the change log entry are written
+	store.Reset()
+	require.NoError(t, bm.AddBlock("key2", []byte("data2")))
+	require.Equal(t, int64(5), store.Puts())
+	require.Equal(t, int64(0), store.Deletes())
+
+	// the info is checked for and read twice, then each of the 2 blocks is read to check its hash and again for each of
+	// its row and column hashes
+	store.Reset()
+	ok, err := bm.IsValid()
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, int64(4+2*3), store.Gets())
+	require.Equal(t, int64(0), store.Puts())
+
+	store.Reset()
+	require.NoError(t, store.Write([]StoreWrite{
+		{Key: []byte("a"), Value: []byte{1}},
+		{Key: []byte("b"), Value: []byte{2}},
+		{Key: []byte("a"), Delete: true},
+	}))
+	require.Equal(t, int64(2), store.Puts())
+	require.Equal(t, int64(1), store.Deletes())
+	require.Equal(t, int64(0), store.Gets())
+}
@@ -0,0 +1,114 @@
+package blockmatrix
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// freePrefix prefixes the entries marking block numbers freed by DeleteBlock that the next added block may reuse.
+const freePrefix = "free:"
+
+func freeEntry(blockNum int) []byte {
+	return []byte(fmt.Sprintf("%s%d", freePrefix, blockNum))
+}
+
+// DeleteBlock removes the block associated with the given key and frees its slot for reuse.  Like EraseBlock, the key
+// entry is removed and the change is checked by the validity policy.  Unlike EraseBlock, which leaves an empty block in
+// the slot and counts it forever, the block entry is removed too, the block count is decremented, and the slot is
+// recorded as free.  A free slot is hashed as an empty block, and the next block added with AddBlock, a Batch,
+// ReserveBlock, or a Tx is stored in the lowest free slot instead of a new one, so a block matrix whose keys churn does
+// not grow.
+func (b *BlockMatrix) DeleteBlock(key string) error {
+	defer b.startSpan("DeleteBlock")()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	blockNum, err := b.removeKeyEntries(key)
+	if err != nil {
+		return err
+	}
+	b.observer.BlockErased(blockNum)
+
+	if err = b.put(freeEntry(blockNum), nil); err != nil {
+		return err
+	}
+
+	if err = b.delete([]byte(strconv.Itoa(blockNum))); err != nil {
+		return err
+	}
+
+	info, err := b.GetBlockMatrixInfo()
+	if err != nil {
+		return err
+	}
+	before := copyInfo(info)
+
+	info.BlockCount--
+	if err = b.updateBlockMatrixInfo(info, blockNum); err != nil {
+		return err
+	}
+
+	if err = b.journalErase(before, info, []int{blockNum}); err != nil {
+		return err
+	}
+
+	return b.checkErase(before, info)
+}
+
+// IsFree returns true if the block number was freed by DeleteBlock and has not been reused since.
+func (b *BlockMatrix) IsFree(blockNum int) (bool, error) {
+	return b.has(freeEntry(blockNum))
+}
+
+// isFreeSlot returns true if get finds the free entry of the block number.
+func isFreeSlot(get func(key []byte) ([]byte, error), blockNum int) bool {
+	_, err := get(freeEntry(blockNum))
+	return err == nil
+}
+
+// freeSlots returns the block numbers freed by DeleteBlock that have not been reused.
+func (b *BlockMatrix) freeSlots() (map[int]bool, error) {
+	free := make(map[int]bool)
+	err := b.iterate([]byte(freePrefix), func(key []byte, value []byte) error {
+		blockNum, err := strconv.Atoi(strings.TrimPrefix(string(key), freePrefix))
+		if err != nil {
+			return err
+		}
+
+		free[blockNum] = true
+		return nil
+	})
+
+	return free, err
+}
+
+// lastBlock returns the highest block number in use or freed, which is the block count plus the number of free slots.
+// The slots after it are empty.
+func (b *BlockMatrix) lastBlock(info *BlockMatrixInfo) (int, error) {
+	free, err := b.freeSlots()
+	if err != nil {
+		return 0, err
+	}
+
+	return info.BlockCount + len(free), nil
+}
+
+// lowestFreeSlot returns the lowest block number freed by DeleteBlock for which skip returns false, or 0 if there is
+// none.
+func (b *BlockMatrix) lowestFreeSlot(skip func(blockNum int) bool) (int, error) {
+	free, err := b.freeSlots()
+	if err != nil {
+		return 0, err
+	}
+
+	lowest := 0
+	for blockNum := range free {
+		if (lowest == 0 || blockNum < lowest) && !skip(blockNum) {
+			lowest = blockNum
+		}
+	}
+
+	return lowest, nil
+}
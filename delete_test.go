@@ -0,0 +1,154 @@
+package blockmatrix
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/require"
+	"strconv"
+	"testing"
+)
+
+func TestDeleteBlock(t *testing.T) {
+	bm, err := NewWithLevelDB(newTestDB(t))
+	require.NoError(t, err)
+	require.NoError(t, createTestBlocks(bm, 6))
+
+	require.NoError(t, bm.DeleteBlock("key2"))
+	_, err = bm.GetBlock("key2")
+	require.ErrorIs(t, err, ErrNotFound)
+
+	free, err := bm.IsFree(2)
+	require.NoError(t, err)
+	require.True(t, free)
+	block, err := bm.GetBlockByNumber(2)
+	require.NoError(t, err)
+	require.True(t, block.IsEmpty())
+
+	// the block entry is removed and the block is no longer counted
+	ok, err := bm.has([]byte("2"))
+	require.NoError(t, err)
+	require.False(t, ok)
+	count, err := bm.BlockCount()
+	require.NoError(t, err)
+	require.Equal(t, 5, count)
+
+	ok, err = bm.IsValid()
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	// the freed slot is reused instead of growing the block matrix
+	require.NoError(t, bm.AddBlock("key7", []byte{7}))
+	blockNum, err := bm.BlockNumber("key7")
+	require.NoError(t, err)
+	require.Equal(t, 2, blockNum)
+	free, err = bm.IsFree(2)
+	require.NoError(t, err)
+	require.False(t, free)
+
+	info, err := bm.GetBlockMatrixInfo()
+	require.NoError(t, err)
+	require.Equal(t, 3, info.Size)
+	require.Equal(t, 6, info.BlockCount)
+
+	// with no slot free the next block number is used
+	require.NoError(t, bm.AddBlock("key8", []byte{8}))
+	blockNum, err = bm.BlockNumber("key8")
+	require.NoError(t, err)
+	require.Equal(t, 7, blockNum)
+
+	ok, err = bm.IsValid()
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	require.Error(t, bm.DeleteBlock("key2"))
+}
+
+func TestDeleteBlockReuseLowest(t *testing.T) {
+	bm, err := NewWithLevelDB(newTestDB(t))
+	require.NoError(t, err)
+	require.NoError(t, createTestBlocks(bm, 12))
+
+	for _, key := range []string{"key11", "key3", "key5"} {
+		require.NoError(t, bm.DeleteBlock(key))
+	}
+
+	batch := bm.NewBatch()
+	batch.AddBlock("batch", []byte("batch"))
+	require.NoError(t, batch.Commit())
+	blockNum, err := bm.BlockNumber("batch")
+	require.NoError(t, err)
+	require.Equal(t, 3, blockNum)
+
+	tx := bm.Begin()
+	require.NoError(t, tx.AddBlock("tx1", []byte("tx1")))
+	require.NoError(t, tx.AddBlock("tx2", []byte("tx2")))
+	require.NoError(t, tx.AddBlock("tx3", []byte("tx3")))
+	require.NoError(t, tx.Commit())
+
+	for key, expected := range map[string]int{"tx1": 5, "tx2": 11, "tx3": 13} {
+		blockNum, err = bm.BlockNumber(key)
+		require.NoError(t, err)
+		require.Equal(t, expected, blockNum, key)
+	}
+
+	ok, err := bm.IsValid()
+	require.NoError(t, err)
+	require.True(t, ok)
+}
+
+func TestDeleteBlockBookkeeping(t *testing.T) {
+	bm, err := NewWithLevelDB(newTestDB(t))
+	require.NoError(t, err)
+	require.NoError(t, createTestBlocks(bm, 6))
+	require.NoError(t, bm.DeleteBlock("key2"))
+	require.NoError(t, bm.DeleteBlock("key6"))
+
+	ok, err := bm.VerifyReconstructable()
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	// repair and recovery leave the free slots without block entries
+	require.NoError(t, bm.RepairSlots())
+	require.NoError(t, bm.RecoverLatestConsistent())
+	count, err := bm.BlockCount()
+	require.NoError(t, err)
+	require.Equal(t, 4, count)
+	for _, blockNum := range []int{2, 6} {
+		ok, err = bm.has([]byte(strconv.Itoa(blockNum)))
+		require.NoError(t, err)
+		require.False(t, ok)
+	}
+
+	corrupt, err := bm.CorruptBlocks()
+	require.NoError(t, err)
+	require.Empty(t, corrupt)
+	hashes, err := bm.ExportHashes()
+	require.NoError(t, err)
+	require.Len(t, hashes, 6)
+	blockNums := make([]int, 0)
+	require.NoError(t, bm.ForEachBlock(func(blockNum int, block *Block) error {
+		blockNums = append(blockNums, blockNum)
+		return nil
+	}))
+	require.Equal(t, []int{1, 3, 4, 5}, blockNums)
+
+	// an imported copy reuses slots without overwriting blocks
+	exported := &bytes.Buffer{}
+	require.NoError(t, bm.Export(exported))
+	imported, err := NewWithLevelDB(newTestDB(t))
+	require.NoError(t, err)
+	require.NoError(t, imported.Import(exported))
+
+	for i, matrix := range []*BlockMatrix{bm, imported} {
+		require.NoError(t, matrix.AddBlock("new1", []byte{7}))
+		require.NoError(t, matrix.AddBlock("new2", []byte{8}))
+		for key, expected := range map[string]int{"key1": 1, "new1": 2, "key5": 5, "new2": 6} {
+			blockNum, err := matrix.BlockNumber(key)
+			require.NoError(t, err)
+			require.Equal(t, expected, blockNum, "matrix %d key %s", i, key)
+		}
+
+		ok, err = matrix.IsValid()
+		require.NoError(t, err)
+		require.True(t, ok)
+	}
+}
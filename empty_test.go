@@ -0,0 +1,125 @@
+package blockmatrix
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestEmptyMatrix(t *testing.T) {
+	bm, err := NewWithLevelDB(newTestDB(t))
+	require.NoError(t, err)
+
+	info, err := bm.GetBlockMatrixInfo()
+	require.NoError(t, err)
+	require.Equal(t, 1, info.Size)
+	require.Equal(t, 0, info.BlockCount)
+	require.Equal(t, 1, bm.Size(0))
+
+	matrix, err := bm.Matrix()
+	require.NoError(t, err)
+	require.Equal(t, [][]*Block{{nil}}, matrix)
+
+	require.NoError(t, bm.PrintBlockMatrixData())
+
+	ok, err := bm.IsValid()
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	result, err := bm.Validate()
+	require.NoError(t, err)
+	require.True(t, result.OK)
+
+	corrupt, err := bm.CorruptBlocks()
+	require.NoError(t, err)
+	require.Empty(t, corrupt)
+
+	root, err := bm.RootHash()
+	require.NoError(t, err)
+	require.NotEmpty(t, root)
+
+	rowBlocks, err := bm.RowBlockNumbers(0)
+	require.NoError(t, err)
+	require.Empty(t, rowBlocks)
+	colBlocks, err := bm.ColumnBlockNumbers(0)
+	require.NoError(t, err)
+	require.Empty(t, colBlocks)
+
+	count, err := bm.RowHashInputCount(0)
+	require.NoError(t, err)
+	require.Equal(t, 0, count)
+	count, err = bm.ColumnHashInputCount(0)
+	require.NoError(t, err)
+	require.Equal(t, 0, count)
+
+	rows, err := bm.RowFillCounts()
+	require.NoError(t, err)
+	require.Equal(t, []int{0}, rows)
+	cols, err := bm.ColumnFillCounts()
+	require.NoError(t, err)
+	require.Equal(t, []int{0}, cols)
+
+	blocks, err := bm.GetBlocksByNumbers(nil)
+	require.NoError(t, err)
+	require.Empty(t, blocks)
+
+	ok, discrepancies, err := bm.VerifyContents(nil)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Empty(t, discrepancies)
+
+	geometry, err := bm.ExportGeometry()
+	require.NoError(t, err)
+	require.Equal(t, 0, geometry.Capacity)
+	require.Empty(t, geometry.Live)
+
+	_, err = bm.InfoJSON()
+	require.NoError(t, err)
+
+	commitment, err := bm.Commitment()
+	require.NoError(t, err)
+	require.Equal(t, root, commitment.Root)
+
+	stale, err := bm.StalenessAgainst(root)
+	require.NoError(t, err)
+	require.False(t, stale)
+
+	quarantined, err := bm.Quarantine()
+	require.NoError(t, err)
+	require.Empty(t, quarantined)
+
+	require.NoError(t, bm.RepairSlots())
+
+	buf := &bytes.Buffer{}
+	require.NoError(t, bm.Freeze(buf))
+	frozen, err := OpenFrozen(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+	require.Equal(t, root, frozen.RootHash())
+
+	_, err = bm.GetBlock("key1")
+	require.Error(t, err)
+	_, err = bm.BlockNumber("key1")
+	require.Error(t, err)
+	_, err = bm.VerificationBundle("key1")
+	require.Error(t, err)
+	_, err = bm.GetLargeBlock("key1")
+	require.Error(t, err)
+	require.Error(t, bm.EraseBlock("key1"))
+}
+
+func TestMissingInfo(t *testing.T) {
+	db := newTestDB(t)
+	bm, err := NewWithLevelDB(db)
+	require.NoError(t, err)
+	require.NoError(t, db.Delete(InfoKey, nil))
+
+	info, err := bm.GetBlockMatrixInfo()
+	require.NoError(t, err)
+	require.Equal(t, 1, info.Size)
+	require.Len(t, info.Rows, 1)
+	require.Len(t, info.Cols, 1)
+
+	result, err := bm.Validate()
+	require.NoError(t, err)
+	require.True(t, result.OK)
+}
@@ -0,0 +1,140 @@
+package blockmatrix
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// eraseJournalPrefix prefixes the erase journal entries.  Each entry records the blocks erased by one EraseBlock,
+// EraseBlocks, DeleteBlock, or Tx and the rows and columns whose hashes changed as a result.  Entries are keyed by a
+// zero padded sequence number so they iterate in order.
+const eraseJournalPrefix = "erase_journal:"
+
+type (
+	// ErasureViolation is an erase recorded in the erase journal that cannot be shown to be valid, because it did not
+	// change exactly the one row hash and the one column hash of the single block it erased.
+	ErasureViolation struct {
+		// Blocks are the numbers of the blocks erased
+		Blocks []int
+		// Rows are the indices of the rows whose hashes changed
+		Rows []int
+		// Cols are the indices of the columns whose hashes changed
+		Cols []int
+		// Time is when the erase was recorded
+		Time time.Time
+		// Reason describes why the erase is not valid
+		Reason string
+	}
+
+	eraseJournalEntry struct {
+		Blocks []int `json:"blocks"`
+		Rows   []int `json:"rows"`
+		Cols   []int `json:"cols"`
+		Time   int64 `json:"time"`
+	}
+)
+
+func eraseJournalKey(seq uint64) []byte {
+	return []byte(fmt.Sprintf("%s%020d", eraseJournalPrefix, seq))
+}
+
+// newEraseJournalEntry returns the journal entry for erasing the given blocks, which changed the block matrix described
+// by before into the one described by after.
+func newEraseJournalEntry(before *BlockMatrixInfo, after *BlockMatrixInfo, blockNums []int) eraseJournalEntry {
+	entry := eraseJournalEntry{Blocks: blockNums, Rows: []int{}, Cols: []int{}, Time: time.Now().UnixNano()}
+	for i := 0; i < before.Size && i < after.Size; i++ {
+		if !reflect.DeepEqual(before.Rows[i], after.Rows[i]) {
+			entry.Rows = append(entry.Rows, i)
+		}
+		if !reflect.DeepEqual(before.Cols[i], after.Cols[i]) {
+			entry.Cols = append(entry.Cols, i)
+		}
+	}
+
+	return entry
+}
+
+// journalErase records erasing the given blocks in the erase journal.
+func (b *BlockMatrix) journalErase(before *BlockMatrixInfo, after *BlockMatrixInfo, blockNums []int) error {
+	key, value, err := b.eraseJournalWrite(before, after, blockNums)
+	if err != nil {
+		return err
+	}
+
+	return b.put(key, value)
+}
+
+// eraseJournalWrite returns the key and value of the erase journal entry for erasing the given blocks.
+func (b *BlockMatrix) eraseJournalWrite(before *BlockMatrixInfo, after *BlockMatrixInfo,
+	blockNums []int) ([]byte, []byte, error) {
+	value, err := json.Marshal(newEraseJournalEntry(before, after, blockNums))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	seq, err := b.nextSeq(eraseJournalPrefix, &b.eraseJournalSeq)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return eraseJournalKey(seq), value, nil
+}
+
+// VerifyAllErases checks every erase recorded in the erase journal and returns the ones that cannot be shown to be
+// valid.  An erase is valid if it erased a single block and changed exactly the hash of that block's row and the hash
+// of its column, which is what lets a verifier holding the row and column hashes from before and after prove that only
+// that block was removed.  Erases rejected by the validity policy are journaled too, since EraseBlock leaves the block
+// erased, so they are reported here.
+func (b *BlockMatrix) VerifyAllErases() ([]ErasureViolation, error) {
+	defer b.startSpan("VerifyAllErases")()
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	violations := make([]ErasureViolation, 0)
+	err := b.iterate([]byte(eraseJournalPrefix), func(key []byte, value []byte) error {
+		var entry eraseJournalEntry
+		if err := json.Unmarshal(value, &entry); err != nil {
+			return fmt.Errorf("error decoding erase journal entry %q: %w", key, err)
+		}
+
+		if reason := checkJournaledErase(entry); reason != "" {
+			violations = append(violations, ErasureViolation{
+				Blocks: entry.Blocks,
+				Rows:   entry.Rows,
+				Cols:   entry.Cols,
+				Time:   time.Unix(0, entry.Time),
+				Reason: reason,
+			})
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return violations, nil
+}
+
+// checkJournaledErase returns why the journaled erase is not valid, or an empty string if it is.
+func checkJournaledErase(entry eraseJournalEntry) string {
+	if len(entry.Blocks) != 1 {
+		return fmt.Sprintf("%d blocks were erased at once", len(entry.Blocks))
+	}
+
+	if len(entry.Rows) != 1 || len(entry.Cols) != 1 {
+		return fmt.Sprintf("erasing block %d changed %d rows and %d columns", entry.Blocks[0], len(entry.Rows),
+			len(entry.Cols))
+	}
+
+	row, col := locateBlock(entry.Blocks[0])
+	if entry.Rows[0] != row || entry.Cols[0] != col {
+		return fmt.Sprintf("erasing block %d at row %d, column %d changed row %d and column %d", entry.Blocks[0],
+			row, col, entry.Rows[0], entry.Cols[0])
+	}
+
+	return ""
+}
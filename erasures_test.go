@@ -0,0 +1,43 @@
+package blockmatrix
+
+import (
+	"github.com/stretchr/testify/require"
+	"strconv"
+	"testing"
+)
+
+func TestVerifyAllErases(t *testing.T) {
+	bm, err := NewWithLevelDB(newTestDB(t))
+	require.NoError(t, err)
+	require.NoError(t, createTestBlocks(bm, 9))
+	require.NoError(t, bm.EraseBlock("key1"))
+	require.NoError(t, bm.DeleteBlock("key5"))
+
+	tx := bm.Begin()
+	require.NoError(t, tx.EraseBlock("key7"))
+	require.NoError(t, tx.Commit())
+
+	violations, err := bm.VerifyAllErases()
+	require.NoError(t, err)
+	require.Empty(t, violations)
+
+	// wipe blocks 2 and 6, which are in different rows and columns, and record it as a single erase
+	info, err := bm.GetBlockMatrixInfo()
+	require.NoError(t, err)
+	before := copyInfo(info)
+	for _, blockNum := range []int{2, 6} {
+		bytes, err := bm.encodeBlock(bm.emptyBlock())
+		require.NoError(t, err)
+		require.NoError(t, bm.put([]byte(strconv.Itoa(blockNum)), bytes))
+	}
+	require.NoError(t, bm.updateAllHashes(info))
+	require.NoError(t, bm.journalErase(before, info, []int{2, 6}))
+
+	violations, err = bm.VerifyAllErases()
+	require.NoError(t, err)
+	require.Len(t, violations, 1)
+	require.Equal(t, []int{2, 6}, violations[0].Blocks)
+	require.Len(t, violations[0].Rows, 2)
+	require.Len(t, violations[0].Cols, 2)
+	require.NotEmpty(t, violations[0].Reason)
+}
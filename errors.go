@@ -0,0 +1,61 @@
+package blockmatrix
+
+import (
+	"errors"
+	"fmt"
+	"github.com/syndtr/goleveldb/leveldb"
+)
+
+// storageError wraps an error returned by the database so that it matches ErrStorage with errors.Is while the
+// underlying error can still be inspected.
+type storageError struct {
+	err error
+}
+
+func (e *storageError) Error() string {
+	return fmt.Sprintf("%s: %s", ErrStorage, e.err)
+}
+
+func (e *storageError) Unwrap() error {
+	return e.err
+}
+
+func (e *storageError) Is(target error) bool {
+	return target == ErrStorage
+}
+
+// storageErr wraps err as a storage error.  Missing keys are reported by the database as errors too but are not
+// storage failures, so ErrNotFound is returned as is.
+func storageErr(err error) error {
+	if err == nil || errors.Is(err, leveldb.ErrNotFound) {
+		return err
+	}
+
+	return &storageError{err: err}
+}
+
+// ImportError is returned by an import with WithValidateOnImport when the input is inconsistent.  It matches
+// ErrHashMismatch with errors.Is.
+type ImportError struct {
+	// BlockNumber is the block whose hash does not match its data, 0 if the mismatch is in a row or column hash
+	BlockNumber int
+	// Row is the row whose hash does not match its blocks, -1 if the mismatch is not in a row hash
+	Row int
+	// Column is the column whose hash does not match its blocks, -1 if the mismatch is not in a column hash
+	Column int
+}
+
+func (e *ImportError) Error() string {
+	switch {
+	case e.BlockNumber > 0:
+		return fmt.Sprintf("%s: block %d", ErrHashMismatch, e.BlockNumber)
+	case e.Row >= 0:
+		return fmt.Sprintf("%s: row %d", ErrHashMismatch, e.Row)
+	default:
+		return fmt.Sprintf("%s: column %d", ErrHashMismatch, e.Column)
+	}
+}
+
+func (e *ImportError) Is(target error) bool {
+	return target == ErrHashMismatch
+}
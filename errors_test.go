@@ -0,0 +1,47 @@
+package blockmatrix
+
+import (
+	"errors"
+	"github.com/stretchr/testify/require"
+	"github.com/syndtr/goleveldb/leveldb"
+	"testing"
+)
+
+func TestErrStorage(t *testing.T) {
+	db := newTestDB(t)
+	bm, err := NewWithLevelDB(db)
+	require.NoError(t, err)
+	require.NoError(t, createTestBlocks(bm, 5))
+
+	// logic errors are not storage errors
+	_, err = bm.GetBlock("missing")
+	require.ErrorIs(t, err, ErrNotFound)
+	require.False(t, errors.Is(err, ErrStorage))
+
+	_, err = bm.RowBlockNumbers(10)
+	require.ErrorIs(t, err, ErrOutOfRange)
+	require.False(t, errors.Is(err, ErrStorage))
+
+	require.ErrorIs(t, bm.EraseBlocks("key1", "key5"), ErrInvalidErase)
+
+	// a closed database fails every operation with a storage error that still wraps the database error
+	require.NoError(t, db.Close())
+
+	err = bm.AddBlock("key6", []byte{6})
+	require.ErrorIs(t, err, ErrStorage)
+	require.ErrorIs(t, err, leveldb.ErrClosed)
+
+	_, err = bm.GetBlock("key1")
+	require.ErrorIs(t, err, ErrStorage)
+
+	require.ErrorIs(t, bm.EraseBlock("key2"), ErrStorage)
+
+	_, err = bm.IsValid()
+	require.ErrorIs(t, err, ErrStorage)
+
+	_, err = bm.CorruptBlocks()
+	require.ErrorIs(t, err, ErrStorage)
+
+	_, err = bm.ChangesSince(nil)
+	require.ErrorIs(t, err, ErrStorage)
+}
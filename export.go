@@ -0,0 +1,179 @@
+package blockmatrix
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+const (
+	// exportFormat identifies a JSON document written by Export
+	exportFormat = "blockmatrix"
+	// exportVersion is the version of the layout of the documents written by Export
+	exportVersion = 1
+)
+
+// exportDocument is the JSON document written by Export.  Blocks are keyed by their number as a string since JSON
+// object keys are strings.
+type exportDocument struct {
+	Format  string            `json:"format"`
+	Version int               `json:"version"`
+	Info    *BlockMatrixInfo  `json:"info"`
+	Keys    map[string]int    `json:"keys"`
+	Blocks  map[string]*Block `json:"blocks"`
+}
+
+// InfoJSON returns the block matrix info encoded as JSON.  The output is compact unless the block matrix was created
+// with WithJSONIndent.
+func (b *BlockMatrix) InfoJSON() ([]byte, error) {
+	info, err := b.GetBlockMatrixInfo()
+	if err != nil {
+		return nil, err
+	}
+
+	return b.marshalJSON(info)
+}
+
+// marshalJSON encodes v for export using the configured JSON indentation.
+func (b *BlockMatrix) marshalJSON(v interface{}) ([]byte, error) {
+	if b.jsonIndent == "" {
+		return json.Marshal(v)
+	}
+
+	return json.MarshalIndent(v, "", b.jsonIndent)
+}
+
+// Export writes the whole block matrix to w as a single self-describing JSON object holding a format name and version,
+// the block matrix info, the key to block number index, and every block up to the capacity of the block matrix keyed
+// by its number.  Object keys are written in sorted order so two exports of the same block matrix are byte identical.
+// The output is compact unless the block matrix was created with WithJSONIndent.  Use Import to load the document into
+// a fresh block matrix.
+func (b *BlockMatrix) Export(w io.Writer) error {
+	defer b.startSpan("Export")()
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	archive, err := b.readArchive()
+	if err != nil {
+		return err
+	}
+
+	doc := exportDocument{
+		Format:  exportFormat,
+		Version: exportVersion,
+		Info:    archive.info,
+		Keys:    archive.keys,
+		Blocks:  make(map[string]*Block, len(archive.blocks)),
+	}
+	for blockNum, block := range archive.blocks {
+		doc.Blocks[strconv.Itoa(blockNum)] = block
+	}
+
+	bytes, err := b.marshalJSON(doc)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(bytes)
+	return err
+}
+
+// Import loads a document written by Export into the block matrix, which must not have any blocks yet, otherwise
+// ErrMatrixExists is returned.  The document is first imported into a block matrix in memory configured like this one
+// and checked with IsValid, an error wrapping ErrHashMismatch is returned if it is not valid.  Only then is it written
+// to the block matrix, atomically, replacing its info.  ErrMalformedArchive is returned if the document cannot be
+// decoded or is missing blocks.  Slots freed by DeleteBlock are exported as empty blocks and imported as free slots, but
+// which of the empty slots are free is derived from the block count and may differ from the exporting block matrix.
+func (b *BlockMatrix) Import(r io.Reader) error {
+	return b.ImportContext(context.Background(), r)
+}
+
+// ImportContext loads a document written by Export like Import, returning the context's error as soon as it is done.
+// The block matrix is not changed unless the whole document has been imported and checked before ctx is done.
+func (b *BlockMatrix) ImportContext(ctx context.Context, r io.Reader) error {
+	ctx, end := b.startSpanContext(ctx, "Import")
+	defer end()
+
+	doc := exportDocument{}
+	if err := json.NewDecoder(r).Decode(&doc); err != nil {
+		return fmt.Errorf("%w: error decoding export: %v", ErrMalformedArchive, err)
+	}
+
+	if doc.Format != exportFormat || doc.Version != exportVersion {
+		return fmt.Errorf("%w: unsupported export format %q version %d", ErrMalformedArchive, doc.Format, doc.Version)
+	}
+
+	if doc.Info == nil {
+		return fmt.Errorf("%w: missing info", ErrMalformedArchive)
+	}
+
+	archive := &matrixArchive{info: doc.Info, keys: doc.Keys, blocks: make(map[int]*Block, len(doc.Blocks))}
+	for num, block := range doc.Blocks {
+		blockNum, err := strconv.Atoi(num)
+		if err != nil || block == nil {
+			return fmt.Errorf("%w: invalid block %q", ErrMalformedArchive, num)
+		}
+
+		archive.blocks[blockNum] = block
+	}
+
+	if err := archive.checkBlocks(); err != nil {
+		return err
+	}
+
+	staging, err := b.newStagingMatrix()
+	if err != nil {
+		return err
+	}
+
+	if err = ctx.Err(); err != nil {
+		return err
+	}
+
+	if err = staging.importArchive(archive); err != nil {
+		return err
+	}
+
+	if ok, err := staging.IsValidContext(ctx); err != nil {
+		return err
+	} else if !ok {
+		return fmt.Errorf("%w: the imported block matrix is not valid", ErrHashMismatch)
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err = ctx.Err(); err != nil {
+		return err
+	}
+
+	if blockCount, err := b.BlockCount(); err != nil {
+		return err
+	} else if blockCount > 0 {
+		return fmt.Errorf("%w: cannot import into a block matrix with %d blocks", ErrMatrixExists, blockCount)
+	}
+
+	return staging.replicateTo(b)
+}
+
+// newStagingMatrix returns an empty block matrix in memory that stores blocks like this one, for building a block
+// matrix before it is written to this one.  It has none of the journal, tracer, or background tasks of this block
+// matrix.
+func (b *BlockMatrix) newStagingMatrix() (*BlockMatrix, error) {
+	return New(NewMemStore(), func(staging *BlockMatrix) {
+		staging.keyNormalizer = b.keyNormalizer
+		staging.keyTransform = b.keyTransform
+		staging.hasher = b.hasher
+		staging.validityPolicy = b.validityPolicy
+		staging.codecs = b.codecs
+		staging.hmacKey = b.hmacKey
+		staging.maxSize = b.maxSize
+		staging.maxKeyLength = b.maxKeyLength
+		staging.lazyEmptyBlocks = b.lazyEmptyBlocks
+		staging.hashCollisionCheck = b.hashCollisionCheck
+		staging.hashCollisionHandler = b.hashCollisionHandler
+	})
+}
@@ -0,0 +1,85 @@
+package blockmatrix
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestInfoJSONIndent(t *testing.T) {
+	db := newTestDB(t)
+	bm, err := NewWithLevelDB(db)
+	require.NoError(t, err)
+	require.NoError(t, createTestBlocks(bm, 5))
+
+	compact, err := bm.InfoJSON()
+	require.NoError(t, err)
+	require.False(t, bytes.Contains(compact, []byte("\n")))
+
+	indented, err := NewWithLevelDB(db, WithJSONIndent("  "))
+	require.NoError(t, err)
+	pretty, err := indented.InfoJSON()
+	require.NoError(t, err)
+	require.True(t, bytes.Contains(pretty, []byte("\n  ")))
+
+	expected, err := bm.GetBlockMatrixInfo()
+	require.NoError(t, err)
+	actual := &BlockMatrixInfo{}
+	require.NoError(t, json.Unmarshal(pretty, actual))
+	require.Equal(t, expected, actual)
+}
+
+func TestExportImport(t *testing.T) {
+	bm, err := NewWithLevelDB(newTestDB(t))
+	require.NoError(t, err)
+	require.NoError(t, createTestBlocks(bm, 7))
+	require.NoError(t, bm.AddBlockWithMetadata("tagged", []byte("tagged"), map[string]string{"source": "test"}))
+	require.NoError(t, bm.EraseBlock("key3"))
+
+	var first, second bytes.Buffer
+	require.NoError(t, bm.Export(&first))
+	require.NoError(t, bm.Export(&second))
+	require.Equal(t, first.Bytes(), second.Bytes())
+
+	imported, err := New(NewMemStore())
+	require.NoError(t, err)
+	require.NoError(t, imported.Import(bytes.NewReader(first.Bytes())))
+
+	expected, err := bm.Matrix()
+	require.NoError(t, err)
+	actual, err := imported.Matrix()
+	require.NoError(t, err)
+	require.Equal(t, expected, actual)
+
+	expectedInfo, err := bm.GetBlockMatrixInfo()
+	require.NoError(t, err)
+	actualInfo, err := imported.GetBlockMatrixInfo()
+	require.NoError(t, err)
+	require.Equal(t, expectedInfo, actualInfo)
+
+	block, err := imported.GetBlock("tagged")
+	require.NoError(t, err)
+	require.Equal(t, "test", block.Metadata["source"])
+	_, err = imported.GetBlock("key3")
+	require.ErrorIs(t, err, ErrNotFound)
+
+	var reexported bytes.Buffer
+	require.NoError(t, imported.Export(&reexported))
+	require.Equal(t, first.Bytes(), reexported.Bytes())
+
+	// the block matrix must be fresh
+	require.ErrorIs(t, imported.Import(bytes.NewReader(first.Bytes())), ErrMatrixExists)
+
+	// a tampered document is rejected before anything is written
+	tampered := bytes.Replace(first.Bytes(), []byte(`"dGFnZ2Vk"`), []byte(`"Zm9yZ2Vk"`), 1)
+	require.NotEqual(t, first.Bytes(), tampered)
+	fresh, err := New(NewMemStore())
+	require.NoError(t, err)
+	require.ErrorIs(t, fresh.Import(bytes.NewReader(tampered)), ErrHashMismatch)
+	blockCount, err := fresh.BlockCount()
+	require.NoError(t, err)
+	require.Equal(t, 0, blockCount)
+
+	require.ErrorIs(t, fresh.Import(bytes.NewReader([]byte(`{"format":"other"}`))), ErrMalformedArchive)
+}
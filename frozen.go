@@ -0,0 +1,277 @@
+package blockmatrix
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+)
+
+// frozenMagic identifies a frozen block matrix file.
+var frozenMagic = []byte("BMFROZ01")
+
+type (
+	// FrozenMatrix is an immutable, read-only block matrix read from a file written by Freeze.
+	FrozenMatrix struct {
+		r          io.ReaderAt
+		header     *frozenHeader
+		dataOffset int64
+	}
+
+	// frozenHeader is stored at the start of a frozen block matrix file.
+	frozenHeader struct {
+		// Info stores the size, block count, and row and column hashes of the block matrix
+		Info *BlockMatrixInfo `json:"info"`
+		// Root is the root hash of the block matrix
+		Root []byte `json:"root"`
+		// Keys maps each key to its block number
+		Keys map[string]int `json:"keys"`
+		// Index stores the location of each block in the data section, block n is at index n-1
+		Index []frozenSpan `json:"index"`
+	}
+
+	// frozenSpan is the location of a block in the data section of a frozen block matrix file.
+	frozenSpan struct {
+		Offset int64 `json:"offset"`
+		Length int64 `json:"length"`
+	}
+)
+
+// Freeze writes an immutable, read optimized snapshot of the block matrix to w.  The file starts with a magic string and
+// the length of a header holding the block matrix info, root hash, key to block number index, and the location of every
+// block, followed by every block up to the capacity of the block matrix in block number order.  Use OpenFrozen to read
+// the snapshot.
+func (b *BlockMatrix) Freeze(w io.Writer) error {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	info, err := b.GetBlockMatrixInfo()
+	if err != nil {
+		return err
+	}
+
+	if info.Hasher != "" {
+		return fmt.Errorf("frozen block matrices are verified with SHA-256, cannot freeze a block matrix using hasher %s",
+			info.Hasher)
+	}
+
+	keys, err := b.blockKeys()
+	if err != nil {
+		return err
+	}
+
+	header := &frozenHeader{
+		Info:  info,
+		Root:  calculateRootHash(sha256.New, info),
+		Keys:  make(map[string]int, len(keys)),
+		Index: make([]frozenSpan, 0, capacity(info.Size)),
+	}
+	for blockNum, key := range keys {
+		header.Keys[key] = blockNum
+	}
+
+	data := &bytes.Buffer{}
+	for blockNum := 1; blockNum <= capacity(info.Size); blockNum++ {
+		// blocks are frozen decoded since a frozen block matrix is read without the codecs
+		block, err := b.getSlot(blockNum)
+		if err != nil {
+			return err
+		}
+
+		bytes, err := json.Marshal(block)
+		if err != nil {
+			return err
+		}
+
+		header.Index = append(header.Index, frozenSpan{Offset: int64(data.Len()), Length: int64(len(bytes))})
+		data.Write(bytes)
+	}
+
+	headerBytes, err := json.Marshal(header)
+	if err != nil {
+		return err
+	}
+
+	headerLen := make([]byte, 8)
+	binary.BigEndian.PutUint64(headerLen, uint64(len(headerBytes)))
+
+	for _, bytes := range [][]byte{frozenMagic, headerLen, headerBytes, data.Bytes()} {
+		if _, err = w.Write(bytes); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// OpenFrozen opens a block matrix written by Freeze from r, which holds size bytes.  The header is checked against the
+// size of the file and its root hash when opened and every block is checked against its hash when read, so tampering
+// with the file is detected.  ErrMalformedFrozen is returned if the file is truncated or its header is inconsistent.
+func OpenFrozen(r io.ReaderAt, size int64) (*FrozenMatrix, error) {
+	prefix := make([]byte, len(frozenMagic)+8)
+	if size < int64(len(prefix)) {
+		return nil, fmt.Errorf("%w: file of %d bytes is too short", ErrMalformedFrozen, size)
+	}
+
+	if _, err := r.ReadAt(prefix, 0); err != nil {
+		return nil, fmt.Errorf("error reading frozen block matrix header: %w", err)
+	}
+
+	if !bytes.Equal(prefix[:len(frozenMagic)], frozenMagic) {
+		return nil, fmt.Errorf("%w: not a frozen block matrix", ErrMalformedFrozen)
+	}
+
+	headerLen := binary.BigEndian.Uint64(prefix[len(frozenMagic):])
+	if headerLen > uint64(size-int64(len(prefix))) {
+		return nil, fmt.Errorf("%w: header of %d bytes in a file of %d bytes", ErrMalformedFrozen, headerLen, size)
+	}
+
+	headerBytes := make([]byte, headerLen)
+	if _, err := r.ReadAt(headerBytes, int64(len(prefix))); err != nil {
+		return nil, fmt.Errorf("error reading frozen block matrix header: %w", err)
+	}
+
+	header := &frozenHeader{}
+	if err := json.Unmarshal(headerBytes, header); err != nil {
+		return nil, fmt.Errorf("%w: error decoding header: %v", ErrMalformedFrozen, err)
+	}
+
+	dataOffset := int64(len(prefix) + len(headerBytes))
+	if err := header.check(size - dataOffset); err != nil {
+		return nil, err
+	}
+
+	if header.Info.Hasher != "" {
+		return nil, fmt.Errorf("frozen block matrices are verified with SHA-256, cannot open a block matrix using hasher %s",
+			header.Info.Hasher)
+	}
+
+	if !reflect.DeepEqual(calculateRootHash(sha256.New, header.Info), header.Root) {
+		return nil, fmt.Errorf("frozen block matrix root hash does not match its row and column hashes")
+	}
+
+	return &FrozenMatrix{
+		r:          r,
+		header:     header,
+		dataOffset: dataOffset,
+	}, nil
+}
+
+// check returns ErrMalformedFrozen if the header does not describe a block matrix whose blocks are all within a data
+// section of dataLen bytes.
+func (h *frozenHeader) check(dataLen int64) error {
+	if h.Info == nil || h.Info.Size < 1 {
+		return fmt.Errorf("%w: missing info", ErrMalformedFrozen)
+	}
+
+	info := h.Info
+	if len(info.Rows) != info.Size || len(info.Cols) != info.Size {
+		return fmt.Errorf("%w: %d row and %d column hashes for size %d", ErrMalformedFrozen, len(info.Rows),
+			len(info.Cols), info.Size)
+	}
+
+	// every block takes at least one byte, checking the size first keeps the capacity from overflowing
+	if int64(info.Size-1) > dataLen || len(h.Index) != capacity(info.Size) {
+		return fmt.Errorf("%w: %d blocks indexed for size %d", ErrMalformedFrozen, len(h.Index), info.Size)
+	}
+
+	for i, span := range h.Index {
+		if span.Offset < 0 || span.Length < 0 || span.Offset > dataLen || span.Length > dataLen-span.Offset {
+			return fmt.Errorf("%w: block %d at offset %d with length %d is outside the data section of %d bytes",
+				ErrMalformedFrozen, i+1, span.Offset, span.Length, dataLen)
+		}
+	}
+
+	for key, blockNum := range h.Keys {
+		if blockNum < 1 || blockNum > len(h.Index) {
+			return fmt.Errorf("%w: key %q maps to block %d", ErrMalformedFrozen, key, blockNum)
+		}
+	}
+
+	return nil
+}
+
+// Info returns a copy of the info of the frozen block matrix.
+func (f *FrozenMatrix) Info() *BlockMatrixInfo {
+	info := *f.header.Info
+	return &info
+}
+
+// RootHash returns the root hash of the frozen block matrix.
+func (f *FrozenMatrix) RootHash() []byte {
+	return f.header.Root
+}
+
+// GetBlock returns the block associated with the given key.
+func (f *FrozenMatrix) GetBlock(key string) (*Block, error) {
+	blockNum, ok := f.header.Keys[key]
+	if !ok {
+		return nil, fmt.Errorf("%w: key %q in frozen block matrix", ErrNotFound, key)
+	}
+
+	return f.GetBlockByNumber(blockNum)
+}
+
+// GetBlockByNumber returns the block with the given block number.  An error is returned if the block's hash does not
+// match its data.
+func (f *FrozenMatrix) GetBlockByNumber(num int) (*Block, error) {
+	if num < 1 || num > len(f.header.Index) {
+		return nil, fmt.Errorf("%w: block number %d for block matrix of size %d", ErrOutOfRange, num,
+			f.header.Info.Size)
+	}
+
+	span := f.header.Index[num-1]
+	bytes := make([]byte, span.Length)
+	if _, err := f.r.ReadAt(bytes, f.dataOffset+span.Offset); err != nil {
+		return nil, fmt.Errorf("error reading block %d: %w", num, err)
+	}
+
+	block := &Block{}
+	if err := json.Unmarshal(bytes, block); err != nil {
+		return nil, err
+	}
+
+	if !reflect.DeepEqual(block.Hash, block.CalculateHash()) {
+		return nil, fmt.Errorf("%w: block %d", ErrHashMismatch, num)
+	}
+
+	return block, nil
+}
+
+// VerifyBlock proves that the block associated with the given key is part of the frozen block matrix by recomputing the
+// hashes of its row and column from the stored blocks and comparing them to the row and column hashes committed to by
+// the root hash.
+func (f *FrozenMatrix) VerifyBlock(key string) (bool, error) {
+	blockNum, ok := f.header.Keys[key]
+	if !ok {
+		return false, fmt.Errorf("%w: key %q in frozen block matrix", ErrNotFound, key)
+	}
+
+	info := f.header.Info
+	row, col := locateBlock(blockNum)
+
+	rowBlocks, err := rowBlockNumbers(row, info.Size)
+	if err != nil {
+		return false, err
+	}
+
+	rowHash, err := hashBlocks(sha256.New, rowBlocks, f.GetBlockByNumber)
+	if err != nil {
+		return false, err
+	}
+
+	colBlocks, err := columnBlockNumbers(col, info.Size)
+	if err != nil {
+		return false, err
+	}
+
+	colHash, err := hashBlocks(sha256.New, colBlocks, f.GetBlockByNumber)
+	if err != nil {
+		return false, err
+	}
+
+	return reflect.DeepEqual(rowHash, info.Rows[row]) && reflect.DeepEqual(colHash, info.Cols[col]), nil
+}
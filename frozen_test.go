@@ -0,0 +1,114 @@
+package blockmatrix
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestFreeze(t *testing.T) {
+	bm, err := NewWithLevelDB(newTestDB(t))
+	require.NoError(t, err)
+	require.NoError(t, createTestBlocks(bm, 9))
+	require.NoError(t, bm.EraseBlock("key4"))
+
+	buf := &bytes.Buffer{}
+	require.NoError(t, bm.Freeze(buf))
+
+	frozen, err := OpenFrozen(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+
+	root, err := bm.RootHash()
+	require.NoError(t, err)
+	require.Equal(t, root, frozen.RootHash())
+
+	block, err := frozen.GetBlock("key7")
+	require.NoError(t, err)
+	require.Equal(t, []byte{7}, block.Data)
+	_, err = frozen.GetBlock("key4")
+	require.Error(t, err)
+
+	ok, err := frozen.VerifyBlock("key7")
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	// tampering with a block in the frozen file is detected when it is read
+	tampered := bytes.Replace(buf.Bytes(), []byte(`"data":"Bw=="`), []byte(`"data":"CA=="`), 1)
+	require.NotEqual(t, buf.Bytes(), tampered)
+	frozen, err = OpenFrozen(bytes.NewReader(tampered), int64(len(tampered)))
+	require.NoError(t, err)
+	_, err = frozen.VerifyBlock("key7")
+	require.Error(t, err)
+}
+
+func TestOpenFrozenMalformed(t *testing.T) {
+	bm, err := NewWithLevelDB(newTestDB(t))
+	require.NoError(t, err)
+	require.NoError(t, createTestBlocks(bm, 4))
+
+	buf := &bytes.Buffer{}
+	require.NoError(t, bm.Freeze(buf))
+	file := buf.Bytes()
+
+	prefixLen := len(frozenMagic) + 8
+	headerLen := int(binary.BigEndian.Uint64(file[len(frozenMagic):prefixLen]))
+	data := file[prefixLen+headerLen:]
+
+	// rewrite returns the frozen file with its header changed by fn
+	rewrite := func(fn func(header *frozenHeader)) []byte {
+		header := &frozenHeader{}
+		require.NoError(t, json.Unmarshal(file[prefixLen:prefixLen+headerLen], header))
+		fn(header)
+
+		headerBytes, err := json.Marshal(header)
+		require.NoError(t, err)
+
+		rewritten := append([]byte{}, frozenMagic...)
+		rewritten = append(rewritten, make([]byte, 8)...)
+		binary.BigEndian.PutUint64(rewritten[len(frozenMagic):], uint64(len(headerBytes)))
+		rewritten = append(rewritten, headerBytes...)
+		return append(rewritten, data...)
+	}
+
+	hugeHeader := append([]byte{}, file...)
+	binary.BigEndian.PutUint64(hugeHeader[len(frozenMagic):], 1<<62)
+
+	for name, file := range map[string][]byte{
+		"truncated":   file[:prefixLen-1],
+		"not frozen":  append([]byte("NOTFROZN"), file[len(frozenMagic):]...),
+		"huge header": hugeHeader,
+		"no info": rewrite(func(header *frozenHeader) {
+			header.Info = nil
+		}),
+		"missing row hash": rewrite(func(header *frozenHeader) {
+			header.Info.Rows = header.Info.Rows[1:]
+		}),
+		"missing column hash": rewrite(func(header *frozenHeader) {
+			header.Info.Cols = header.Info.Cols[1:]
+		}),
+		"missing block": rewrite(func(header *frozenHeader) {
+			header.Index = header.Index[1:]
+		}),
+		"huge block": rewrite(func(header *frozenHeader) {
+			header.Index[0].Length = 1 << 62
+		}),
+		"block past the end": rewrite(func(header *frozenHeader) {
+			header.Index[0].Offset = int64(len(data))
+		}),
+		"negative offset": rewrite(func(header *frozenHeader) {
+			header.Index[0].Offset = -1
+		}),
+		"key out of range": rewrite(func(header *frozenHeader) {
+			header.Keys["key1"] = len(header.Index) + 1
+		}),
+	} {
+		_, err := OpenFrozen(bytes.NewReader(file), int64(len(file)))
+		require.ErrorIs(t, err, ErrMalformedFrozen, name)
+	}
+
+	// a reader claimed to be larger than it is fails to read instead of panicking
+	_, err = OpenFrozen(bytes.NewReader(file[:prefixLen+headerLen/2]), int64(len(file)))
+	require.Error(t, err)
+}
@@ -0,0 +1,251 @@
+package blockmatrix
+
+import (
+	"bytes"
+	"fmt"
+)
+
+type (
+	// Cell is the position of a block in the block matrix.
+	Cell struct {
+		Row int `json:"row"`
+		Col int `json:"col"`
+	}
+
+	// Geometry describes the shape of a block matrix without any of its data.
+	Geometry struct {
+		// Size of the block matrix (dimension)
+		Size int `json:"size"`
+		// BlockCount is the number of blocks that have been added to the block matrix and not deleted
+		BlockCount int `json:"block_count"`
+		// Capacity is the number of blocks that fit in the block matrix without growing it
+		Capacity int `json:"capacity"`
+		// Live stores the cells of blocks that hold data
+		Live []Cell `json:"live"`
+		// Erased stores the cells of blocks that were added and then erased
+		Erased []Cell `json:"erased"`
+		// Empty stores the cells that no block has been added to yet
+		Empty []Cell `json:"empty"`
+	}
+
+	// CellChange is a cell whose block changed between two snapshots of the block matrix.
+	CellChange struct {
+		// Row is the row of the cell
+		Row int `json:"row"`
+		// Col is the column of the cell
+		Col int `json:"col"`
+		// OldHash is the hash of the block in the previous snapshot, nil if the cell was not in it
+		OldHash []byte `json:"old_hash"`
+		// NewHash is the hash of the block now
+		NewHash []byte `json:"new_hash"`
+	}
+)
+
+// ExportGeometry returns the shape of the block matrix: its size, block count, capacity, and which cells are live,
+// erased, or empty.  Cells are listed in block number order.
+func (b *BlockMatrix) ExportGeometry() (*Geometry, error) {
+	info, err := b.GetBlockMatrixInfo()
+	if err != nil {
+		return nil, err
+	}
+
+	geometry := &Geometry{
+		Size:       info.Size,
+		BlockCount: info.BlockCount,
+		Capacity:   capacity(info.Size),
+		Live:       make([]Cell, 0),
+		Erased:     make([]Cell, 0),
+		Empty:      make([]Cell, 0),
+	}
+
+	last, err := b.lastBlock(info)
+	if err != nil {
+		return nil, err
+	}
+
+	for blockNum := 1; blockNum <= geometry.Capacity; blockNum++ {
+		row, col := locateBlock(blockNum)
+		cell := Cell{Row: row, Col: col}
+
+		if blockNum > last {
+			geometry.Empty = append(geometry.Empty, cell)
+			continue
+		}
+
+		block, err := b.GetBlockByNumber(blockNum)
+		if err != nil {
+			return nil, err
+		}
+
+		if block.IsEmpty() {
+			geometry.Erased = append(geometry.Erased, cell)
+		} else {
+			geometry.Live = append(geometry.Live, cell)
+		}
+	}
+
+	return geometry, nil
+}
+
+// HashGrid returns the block hashes arranged by cell, the hash of the block at row i and column j is at [i][j], for
+// tooling that works on the block matrix as a dense size x size array.  Diagonal cells, which never hold a block, are
+// nil and cells with an empty or erased block are empty but not nil, so only cells with data hold a hash.
+func (b *BlockMatrix) HashGrid() ([][][]byte, error) {
+	matrix, err := b.Matrix()
+	if err != nil {
+		return nil, err
+	}
+
+	grid := make([][][]byte, len(matrix))
+	for i := range matrix {
+		grid[i] = make([][]byte, len(matrix))
+		for j, block := range matrix[i] {
+			if i == j {
+				continue
+			}
+
+			if block.IsEmpty() {
+				grid[i][j] = []byte{}
+			} else {
+				grid[i][j] = block.Hash
+			}
+		}
+	}
+
+	return grid, nil
+}
+
+// MatrixDelta compares the block matrix to a previous snapshot taken with Matrix and returns the cells whose block hash
+// changed, in row then column order, so that a view of the block matrix only needs to redraw those cells.  If the
+// block matrix grew since the snapshot, every cell the snapshot did not have is reported with a nil OldHash.
+func (b *BlockMatrix) MatrixDelta(prev [][]*Block) ([]CellChange, error) {
+	matrix, err := b.Matrix()
+	if err != nil {
+		return nil, err
+	}
+
+	changes := make([]CellChange, 0)
+	for i := range matrix {
+		for j, block := range matrix[i] {
+			if i == j {
+				continue
+			}
+
+			var oldHash []byte
+			if i < len(prev) && j < len(prev[i]) && prev[i][j] != nil {
+				oldHash = prev[i][j].Hash
+			}
+
+			if oldHash == nil || !bytes.Equal(oldHash, block.Hash) {
+				changes = append(changes, CellChange{Row: i, Col: j, OldHash: oldHash, NewHash: block.Hash})
+			}
+		}
+	}
+
+	return changes, nil
+}
+
+// ApplyGeometry preallocates the block matrix to the size of the given geometry, creating the empty blocks up front so
+// that adding blocks does not grow the matrix until its capacity is exceeded.  The block count and data of the block
+// matrix are not changed.  An error is returned if the geometry is smaller than the block matrix.
+func (b *BlockMatrix) ApplyGeometry(geometry *Geometry) error {
+	return b.Grow(geometry.Size)
+}
+
+// Grow preallocates the block matrix to the given size, creating the empty blocks of the new cells and updating every
+// row and column hash.  The block count and data of the block matrix are not changed.  An error is returned if the new
+// size is smaller than the block matrix or larger than its maximum size.
+func (b *BlockMatrix) Grow(newSize int) error {
+	defer b.startSpan("Grow")()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	info, err := b.GetBlockMatrixInfo()
+	if err != nil {
+		return err
+	}
+
+	if newSize < info.Size {
+		return fmt.Errorf("cannot shrink block matrix of size %d to size %d", info.Size, newSize)
+	} else if newSize == info.Size {
+		return nil
+	} else if b.maxSize > 0 && newSize > b.maxSize {
+		return fmt.Errorf("%w: cannot grow to size %d, the maximum is %d", ErrMaxSizeExceeded, newSize, b.maxSize)
+	}
+
+	if err = b.updateBlockMatrixSize(info, newSize); err != nil {
+		return err
+	}
+
+	return b.updateAllHashes(info)
+}
+
+// GrowthImpact returns the row and column each block number would occupy after growing the block matrix to newSize,
+// for the block numbers affected by the growth.  A block's position only depends on its number so the blocks already
+// in the block matrix never move, the affected block numbers are the ones of the cells the growth adds.  An error is
+// returned if newSize is smaller than the block matrix.
+func (b *BlockMatrix) GrowthImpact(newSize int) (map[int][2]int, error) {
+	info, err := b.GetBlockMatrixInfo()
+	if err != nil {
+		return nil, err
+	}
+
+	if newSize < info.Size {
+		return nil, fmt.Errorf("cannot shrink block matrix of size %d to size %d", info.Size, newSize)
+	}
+
+	impact := make(map[int][2]int)
+	for blockNum := capacity(info.Size) + 1; blockNum <= capacity(newSize); blockNum++ {
+		row, col := locateBlock(blockNum)
+		impact[blockNum] = [2]int{row, col}
+	}
+
+	return impact, nil
+}
+
+// IntersectionBlock returns the block in the row of keyA and the column of keyB along with its block number.  For two
+// keys in the same column this is the block that shares a row with keyA and a column with both, and likewise for two
+// keys in the same row.  An error is returned if the intersection is on the diagonal, which holds no block.
+func (b *BlockMatrix) IntersectionBlock(keyA string, keyB string) (*Block, int, error) {
+	blockNumA, err := b.BlockNumber(keyA)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	blockNumB, err := b.BlockNumber(keyB)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	row, _ := locateBlock(blockNumA)
+	_, col := locateBlock(blockNumB)
+	if row == col {
+		return nil, 0, fmt.Errorf("%w: the row of %q and the column of %q intersect on the diagonal at %d",
+			ErrOutOfRange, keyA, keyB, row)
+	}
+
+	info, err := b.GetBlockMatrixInfo()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	blockNums, err := rowBlockNumbers(row, info.Size)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// a row lists the blocks left of the diagonal followed by the blocks right of it
+	index := col
+	if col > row {
+		index--
+	}
+	blockNum := blockNums[index]
+
+	block, err := b.GetBlockByNumber(blockNum)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return block, blockNum, nil
+}
@@ -0,0 +1,188 @@
+package blockmatrix
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestGeometry(t *testing.T) {
+	bm, err := NewWithLevelDB(newTestDB(t))
+	require.NoError(t, err)
+	require.NoError(t, createTestBlocks(bm, 9))
+	require.NoError(t, bm.EraseBlock("key2"))
+
+	geometry, err := bm.ExportGeometry()
+	require.NoError(t, err)
+	require.Equal(t, 4, geometry.Size)
+	require.Equal(t, 9, geometry.BlockCount)
+	require.Equal(t, 12, geometry.Capacity)
+	require.Len(t, geometry.Live, 8)
+	require.Equal(t, []Cell{{Row: 1, Col: 0}}, geometry.Erased)
+	require.Len(t, geometry.Empty, 3)
+
+	bytes, err := json.Marshal(geometry)
+	require.NoError(t, err)
+	loaded := &Geometry{}
+	require.NoError(t, json.Unmarshal(bytes, loaded))
+	require.Equal(t, geometry, loaded)
+
+	preallocated, err := NewWithLevelDB(newTestDB(t))
+	require.NoError(t, err)
+	require.NoError(t, preallocated.ApplyGeometry(loaded))
+
+	actual, err := preallocated.ExportGeometry()
+	require.NoError(t, err)
+	require.Equal(t, geometry.Size, actual.Size)
+	require.Equal(t, geometry.Capacity, actual.Capacity)
+	require.Equal(t, 0, actual.BlockCount)
+	require.Len(t, actual.Empty, geometry.Capacity)
+
+	result, err := preallocated.Validate()
+	require.NoError(t, err)
+	require.True(t, result.OK)
+
+	// adding blocks up to the capacity does not grow the preallocated matrix
+	require.NoError(t, createTestBlocks(preallocated, 12))
+	info, err := preallocated.GetBlockMatrixInfo()
+	require.NoError(t, err)
+	require.Equal(t, 4, info.Size)
+	result, err = preallocated.Validate()
+	require.NoError(t, err)
+	require.True(t, result.OK)
+
+	require.Error(t, bm.ApplyGeometry(&Geometry{Size: 2}))
+}
+
+func TestGrowthImpact(t *testing.T) {
+	bm, err := NewWithLevelDB(newTestDB(t))
+	require.NoError(t, err)
+	require.NoError(t, createTestBlocks(bm, 5))
+
+	before, err := bm.ExportGeometry()
+	require.NoError(t, err)
+
+	impact, err := bm.GrowthImpact(5)
+	require.NoError(t, err)
+	require.Len(t, impact, capacity(5)-capacity(3))
+
+	require.NoError(t, bm.Grow(5))
+
+	after, err := bm.ExportGeometry()
+	require.NoError(t, err)
+	require.Equal(t, 5, after.Size)
+	require.Equal(t, before.Live, after.Live)
+
+	// the new cells are exactly the predicted ones and hold the predicted block numbers
+	predicted := make(map[Cell]bool)
+	for blockNum, cell := range impact {
+		predicted[Cell{Row: cell[0], Col: cell[1]}] = true
+
+		row, err := bm.RowBlockNumbers(cell[0])
+		require.NoError(t, err)
+		require.Contains(t, row, blockNum)
+		col, err := bm.ColumnBlockNumbers(cell[1])
+		require.NoError(t, err)
+		require.Contains(t, col, blockNum)
+	}
+
+	added := make(map[Cell]bool)
+	for _, cell := range after.Empty[len(before.Empty):] {
+		added[cell] = true
+	}
+	require.Equal(t, predicted, added)
+
+	result, err := bm.Validate()
+	require.NoError(t, err)
+	require.True(t, result.OK)
+
+	_, err = bm.GrowthImpact(4)
+	require.Error(t, err)
+	require.Error(t, bm.Grow(4))
+}
+
+func TestIntersectionBlock(t *testing.T) {
+	bm, err := NewWithLevelDB(newTestDB(t))
+	require.NoError(t, err)
+	require.NoError(t, createTestBlocks(bm, 9))
+
+	// key3 is at (0, 2) and key9 at (1, 3), the intersection (0, 3) is block 7
+	block, blockNum, err := bm.IntersectionBlock("key3", "key9")
+	require.NoError(t, err)
+	require.Equal(t, 7, blockNum)
+	require.Equal(t, []byte{7}, block.Data)
+
+	// key4 is at (2, 0), the intersection (2, 3) with key9 is block 11 which is still empty
+	block, blockNum, err = bm.IntersectionBlock("key4", "key9")
+	require.NoError(t, err)
+	require.Equal(t, 11, blockNum)
+	require.True(t, block.IsEmpty())
+
+	// key5 is at (1, 2) and key6 at (2, 1)
+	_, _, err = bm.IntersectionBlock("key5", "key6")
+	require.ErrorIs(t, err, ErrOutOfRange)
+
+	_, _, err = bm.IntersectionBlock("key3", "missing")
+	require.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestHashGrid(t *testing.T) {
+	bm, err := NewWithLevelDB(newTestDB(t))
+	require.NoError(t, err)
+	require.NoError(t, createTestBlocks(bm, 9))
+	require.NoError(t, bm.EraseBlock("key2"))
+
+	grid, err := bm.HashGrid()
+	require.NoError(t, err)
+	require.Len(t, grid, 4)
+	for i := range grid {
+		require.Len(t, grid[i], 4)
+		require.Nil(t, grid[i][i])
+	}
+
+	block, err := bm.GetBlock("key5")
+	require.NoError(t, err)
+	row, col := locateBlock(5)
+	require.Equal(t, block.Hash, grid[row][col])
+
+	// the erased block and the cells no block was added to are empty
+	row, col = locateBlock(2)
+	require.NotNil(t, grid[row][col])
+	require.Empty(t, grid[row][col])
+	row, col = locateBlock(12)
+	require.NotNil(t, grid[row][col])
+	require.Empty(t, grid[row][col])
+}
+
+func TestMatrixDelta(t *testing.T) {
+	bm, err := NewWithLevelDB(newTestDB(t), WithClock(testClock))
+	require.NoError(t, err)
+	require.NoError(t, createTestBlocks(bm, 5))
+
+	prev, err := bm.Matrix()
+	require.NoError(t, err)
+	changes, err := bm.MatrixDelta(prev)
+	require.NoError(t, err)
+	require.Empty(t, changes)
+
+	require.NoError(t, bm.UpdateBlock("key4", []byte("updated")))
+	row, col := locateBlock(4)
+
+	changes, err = bm.MatrixDelta(prev)
+	require.NoError(t, err)
+	require.Len(t, changes, 1)
+	require.Equal(t, row, changes[0].Row)
+	require.Equal(t, col, changes[0].Col)
+	require.Equal(t, prev[row][col].Hash, changes[0].OldHash)
+	updated := &Block{Data: []byte("updated"), Timestamp: testClock()}
+	require.Equal(t, updated.CalculateHash(), changes[0].NewHash)
+
+	// growing adds cells the snapshot does not have
+	require.NoError(t, bm.Grow(4))
+	changes, err = bm.MatrixDelta(prev)
+	require.NoError(t, err)
+	require.Len(t, changes, 1+2*3)
+	for _, change := range changes {
+		require.Equal(t, change.Row == 3 || change.Col == 3, change.OldHash == nil)
+	}
+}
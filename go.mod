@@ -4,6 +4,8 @@ go 1.16
 
 require (
 	github.com/olekukonko/tablewriter v0.0.5
+	github.com/prometheus/client_golang v1.11.1
 	github.com/stretchr/testify v1.7.0
 	github.com/syndtr/goleveldb v1.0.0
+	modernc.org/sqlite v1.17.3
 )
@@ -0,0 +1,145 @@
+package blockmatrix
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"time"
+)
+
+const (
+	// hasherBenchmarkBufferSize is the size of the buffer hashed on each iteration of BenchmarkHasher
+	hasherBenchmarkBufferSize = 1 << 20
+	// hasherBenchmarkDuration is the minimum amount of time BenchmarkHasher spends hashing
+	hasherBenchmarkDuration = 50 * time.Millisecond
+)
+
+// BenchmarkHasher measures the throughput of the block matrix hash function by repeatedly hashing a fixed 1 MiB buffer
+// for a short period of time, returning the number of bytes hashed per second.  This is meant to help choose a hash
+// function for a deployment and is never called by the block matrix itself.
+func (b *BlockMatrix) BenchmarkHasher() (float64, error) {
+	buf := make([]byte, hasherBenchmarkBufferSize)
+	for i := range buf {
+		buf[i] = byte(i)
+	}
+
+	var total int
+	start := time.Now()
+	for time.Since(start) < hasherBenchmarkDuration {
+		h := b.hasher()
+		n, err := h.Write(buf)
+		if err != nil {
+			return 0, err
+		}
+
+		h.Sum(nil)
+		total += n
+	}
+
+	return float64(total) / time.Since(start).Seconds(), nil
+}
+
+// hasherFingerprintInput is hashed to tell hash functions apart, see hasherID.
+var hasherFingerprintInput = []byte("blockmatrix hasher")
+
+// hasherID identifies the hash function created by newHash with a fingerprint of its output, so that a block matrix
+// opened with a different hash function can be detected without naming hash functions.  SHA-256, the default, is
+// identified by an empty string so block matrices created before the hash function was configurable match it.
+func hasherID(newHash func() hash.Hash) string {
+	h := newHash()
+	h.Write(hasherFingerprintInput)
+	fingerprint := h.Sum(nil)
+
+	if bytes.Equal(fingerprint, calculateHash(hasherFingerprintInput)) {
+		return ""
+	}
+
+	return hex.EncodeToString(fingerprint)
+}
+
+// hash returns the hash of data using the hash function of the block matrix.
+func (b *BlockMatrix) hash(data []byte) []byte {
+	h := b.hasher()
+	h.Write(data)
+	return h.Sum(nil)
+}
+
+// newBlock returns a block holding data, stamped with the time from timestamp and hashed with the hash function of the
+// block matrix.
+func (b *BlockMatrix) newBlock(data []byte) *Block {
+	return b.newBlockWithMetadata(data, nil)
+}
+
+// newBlockWithMetadata returns a block holding data tagged with a copy of meta, hashed with the hash function of the
+// block matrix like newBlock.
+func (b *BlockMatrix) newBlockWithMetadata(data []byte, meta map[string]string) *Block {
+	block := &Block{Data: data, Timestamp: b.timestamp()}
+	if len(meta) > 0 {
+		block.Metadata = make(map[string]string, len(meta))
+		for key, value := range meta {
+			block.Metadata[key] = value
+		}
+	}
+
+	block.Hash = b.blockHash(block)
+	return block
+}
+
+// timestamp returns the time blocks written to the block matrix are stamped with, the current time unless a clock is
+// set WithClock.  Like newTimestamp it is in UTC without a monotonic clock reading.
+func (b *BlockMatrix) timestamp() time.Time {
+	if b.clock == nil {
+		return newTimestamp()
+	}
+
+	return b.clock().UTC().Round(0)
+}
+
+// emptyBlock returns an empty block hashed with the hash function of the block matrix.  Empty blocks have a zero
+// timestamp so that every empty slot has the same hash, whether it is stored or, WithLazyEmptyBlocks, missing.
+func (b *BlockMatrix) emptyBlock() *Block {
+	block := EmptyBlock()
+	block.Hash = b.blockHash(block)
+	return block
+}
+
+// blockHash returns the hash of the block's data, timestamp, and metadata using the hash function of the block matrix.
+func (b *BlockMatrix) blockHash(block *Block) []byte {
+	return hashBlock(b.hasher(), block)
+}
+
+// checkHasher returns ErrHasherMismatch if the block matrix described by info was created with a different hash
+// function than the one configured.
+func (b *BlockMatrix) checkHasher(info *BlockMatrixInfo) error {
+	if id := hasherID(b.hasher); info.Hasher != id {
+		return fmt.Errorf("%w: the block matrix was created with hasher %s, opened with %s", ErrHasherMismatch,
+			describeHasherID(info.Hasher), describeHasherID(id))
+	}
+
+	return nil
+}
+
+// hasherSize returns the length of the hashes of the block matrix's hash function.
+func (b *BlockMatrix) hasherSize() int {
+	return b.hasher().Size()
+}
+
+// hasherIDSize returns the length of the hashes of the hash function with the given ID.  The fingerprint is a hash
+// itself so its length is the hash length.
+func hasherIDSize(id string) int {
+	if id == "" {
+		return sha256.Size
+	}
+
+	return hex.DecodedLen(len(id))
+}
+
+func describeHasherID(id string) string {
+	if id == "" {
+		return "SHA-256"
+	}
+
+	return id
+}
@@ -0,0 +1,100 @@
+package blockmatrix
+
+import (
+	"bytes"
+	"crypto/sha512"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestBenchmarkHasher(t *testing.T) {
+	bm, err := NewWithLevelDB(newTestDB(t))
+	require.NoError(t, err)
+
+	rate, err := bm.BenchmarkHasher()
+	require.NoError(t, err)
+	require.Greater(t, rate, float64(0))
+}
+
+func TestWithHasher(t *testing.T) {
+	db := newTestDB(t)
+	bm, err := NewWithLevelDB(db, WithHasher(sha512.New))
+	require.NoError(t, err)
+	require.NoError(t, createTestBlocks(bm, 9))
+	require.NoError(t, bm.EraseBlock("key4"))
+	require.NoError(t, bm.UpdateBlock("key5", []byte("updated")))
+
+	block, err := bm.GetBlock("key5")
+	require.NoError(t, err)
+	require.Equal(t, hashBlock(sha512.New(), block), block.Hash)
+	require.Len(t, block.Hash, sha512.Size)
+
+	info, err := bm.GetBlockMatrixInfo()
+	require.NoError(t, err)
+	require.NotEmpty(t, info.Hasher)
+	for i := 0; i < info.Size; i++ {
+		require.Len(t, info.Rows[i], sha512.Size)
+		require.Len(t, info.Cols[i], sha512.Size)
+	}
+	require.NoError(t, ValidateInfo(info))
+
+	ok, err := bm.IsValid()
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	buf := &bytes.Buffer{}
+	require.NoError(t, bm.ExportTar(buf))
+	imported, err := ImportTar(newTestDB(t), bytes.NewReader(buf.Bytes()), WithHasher(sha512.New),
+		WithValidateOnImport())
+	require.NoError(t, err)
+	ok, err = imported.IsValid()
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	reopened, err := NewWithLevelDB(db, WithHasher(sha512.New))
+	require.NoError(t, err)
+	ok, err = reopened.IsValid()
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	root, err := bm.RootHash()
+	require.NoError(t, err)
+	require.Len(t, root, sha512.Size)
+}
+
+func TestWithHasherCommitment(t *testing.T) {
+	bm, err := NewWithLevelDB(newTestDB(t), WithHasher(sha512.New), WithCommitmentHashLength(48))
+	require.NoError(t, err)
+	require.NoError(t, createTestBlocks(bm, 6))
+
+	// SHA-512 hashes are longer than 48 bytes so they are truncated
+	commitment, err := bm.Commitment()
+	require.NoError(t, err)
+	require.NotEmpty(t, commitment.Hasher)
+	require.Equal(t, 48, commitment.HashLength)
+	require.Len(t, commitment.Root, 48)
+
+	bundle, err := bm.VerificationBundle("key3")
+	require.NoError(t, err)
+	_, err = bundle.Verify(commitment)
+	require.Error(t, err)
+}
+
+func TestWithHasherMismatch(t *testing.T) {
+	db := newTestDB(t)
+	_, err := NewWithLevelDB(db, WithHasher(sha512.New))
+	require.NoError(t, err)
+
+	_, err = NewWithLevelDB(db)
+	require.ErrorIs(t, err, ErrHasherMismatch)
+	require.Contains(t, err.Error(), "opened with SHA-256")
+
+	db = newTestDB(t)
+	_, err = NewWithLevelDB(db)
+	require.NoError(t, err)
+
+	_, err = NewWithLevelDB(db, WithHasher(sha512.New))
+	require.ErrorIs(t, err, ErrHasherMismatch)
+	_, err = NewWithLevelDB(db, WithHasher(sha512.New384))
+	require.ErrorIs(t, err, ErrHasherMismatch)
+}
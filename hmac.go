@@ -0,0 +1,56 @@
+package blockmatrix
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"fmt"
+)
+
+// calculateMAC returns the HMAC-SHA256 of data with the given key.
+func calculateMAC(key []byte, data []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(data)
+	return mac.Sum(nil)
+}
+
+// encodeBlock serializes the block for storage with the block encoding, setting its MAC first if an HMAC key is
+// configured.  The stored data is encoded with the configured codecs.
+func (b *BlockMatrix) encodeBlock(block *Block) ([]byte, error) {
+	if b.hmacKey != nil {
+		block.MAC = calculateMAC(b.hmacKey, block.Data)
+	}
+
+	if len(b.codecs) == 0 {
+		return b.blockEncoding.Marshal(block)
+	}
+
+	stored := *block
+	var err error
+	if stored.Data, stored.Codecs, err = b.encodeData(block.Data); err != nil {
+		return nil, err
+	}
+
+	return b.blockEncoding.Marshal(&stored)
+}
+
+// decodeBlock deserializes a stored block, decoding its data with the codecs it was written with.  If an HMAC key is
+// configured, an error wrapping ErrAuthenticationFailed is returned when the block's MAC does not match its data.
+func (b *BlockMatrix) decodeBlock(bytes []byte) (*Block, error) {
+	block, err := b.unmarshalBlock(bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(block.Codecs) > 0 {
+		if block.Data, err = b.decodeData(block.Data, block.Codecs); err != nil {
+			return nil, err
+		}
+		block.Codecs = nil
+	}
+
+	if b.hmacKey != nil && !hmac.Equal(block.MAC, calculateMAC(b.hmacKey, block.Data)) {
+		return nil, fmt.Errorf("%w: block MAC does not match its data", ErrAuthenticationFailed)
+	}
+
+	return block, nil
+}
@@ -0,0 +1,51 @@
+package blockmatrix
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestWithHMAC(t *testing.T) {
+	db := newTestDB(t)
+	bm, err := NewWithLevelDB(db, WithHMAC([]byte("secret")))
+	require.NoError(t, err)
+	require.NoError(t, createTestBlocks(bm, 5))
+	require.NoError(t, bm.EraseBlock("key2"))
+
+	block, err := bm.GetBlock("key3")
+	require.NoError(t, err)
+	require.Equal(t, []byte{3}, block.Data)
+	require.NotEmpty(t, block.MAC)
+
+	result, err := bm.Validate()
+	require.NoError(t, err)
+	require.True(t, result.OK)
+
+	// a forged block with a valid plain hash but no MAC, and one with a MAC computed with the wrong key
+	forged, err := json.Marshal(NewBlock([]byte("forged")))
+	require.NoError(t, err)
+	require.NoError(t, db.Put([]byte("3"), forged, nil))
+
+	wrongKey := NewBlock([]byte("forged"))
+	wrongKey.MAC = calculateMAC([]byte("guess"), wrongKey.Data)
+	wrongKeyBytes, err := json.Marshal(wrongKey)
+	require.NoError(t, err)
+	require.NoError(t, db.Put([]byte("4"), wrongKeyBytes, nil))
+
+	_, err = bm.GetBlock("key3")
+	require.ErrorIs(t, err, ErrAuthenticationFailed)
+	_, err = bm.GetBlockByNumber(4)
+	require.ErrorIs(t, err, ErrAuthenticationFailed)
+
+	corrupt, err := bm.CorruptBlocks()
+	require.NoError(t, err)
+	require.Equal(t, []int{3, 4}, corrupt)
+
+	// without the key the forged block is accepted
+	plain, err := NewWithLevelDB(db)
+	require.NoError(t, err)
+	block, err = plain.GetBlock("key3")
+	require.NoError(t, err)
+	require.Equal(t, []byte("forged"), block.Data)
+}
@@ -0,0 +1,47 @@
+package blockmatrix
+
+import (
+	"crypto/rand"
+	"fmt"
+)
+
+// newMatrixID returns a random version 4 UUID.
+func newMatrixID() (string, error) {
+	var uuid [16]byte
+	if _, err := rand.Read(uuid[:]); err != nil {
+		return "", err
+	}
+
+	// set the version and variant bits
+	uuid[6] = (uuid[6] & 0x0f) | 0x40
+	uuid[8] = (uuid[8] & 0x3f) | 0x80
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", uuid[0:4], uuid[4:6], uuid[6:8], uuid[8:10], uuid[10:]), nil
+}
+
+// MatrixID returns the UUID identifying this block matrix.  The ID is generated when the block matrix is created and
+// stored in the block matrix info, so it is the same for every copy of the database.  Block matrices created before IDs
+// were introduced are assigned one the first time it is requested.
+func (b *BlockMatrix) MatrixID() (string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	info, err := b.GetBlockMatrixInfo()
+	if err != nil {
+		return "", err
+	}
+
+	if info.ID != "" {
+		return info.ID, nil
+	}
+
+	if info.ID, err = newMatrixID(); err != nil {
+		return "", fmt.Errorf("error generating block matrix id: %w", err)
+	}
+
+	if err = b.putBlockMatrixInfo(info); err != nil {
+		return "", err
+	}
+
+	return info.ID, nil
+}
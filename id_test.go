@@ -0,0 +1,56 @@
+package blockmatrix
+
+import (
+	"github.com/stretchr/testify/require"
+	"regexp"
+	"testing"
+)
+
+func TestMatrixID(t *testing.T) {
+	db := newTestDB(t)
+	bm, err := NewWithLevelDB(db)
+	require.NoError(t, err)
+
+	id, err := bm.MatrixID()
+	require.NoError(t, err)
+	require.Regexp(t, regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`), id)
+
+	require.NoError(t, createTestBlocks(bm, 5))
+	require.NoError(t, bm.EraseBlock("key2"))
+
+	reopened, err := NewWithLevelDB(db)
+	require.NoError(t, err)
+	reopenedID, err := reopened.MatrixID()
+	require.NoError(t, err)
+	require.Equal(t, id, reopenedID)
+
+	copied, err := NewWithLevelDB(copyTestDB(t, db))
+	require.NoError(t, err)
+	copiedID, err := copied.MatrixID()
+	require.NoError(t, err)
+	require.Equal(t, id, copiedID)
+
+	other, err := NewWithLevelDB(newTestDB(t))
+	require.NoError(t, err)
+	otherID, err := other.MatrixID()
+	require.NoError(t, err)
+	require.NotEqual(t, id, otherID)
+}
+
+func TestMatrixIDAssignedToExistingMatrix(t *testing.T) {
+	db := newTestDB(t)
+	bm, err := NewWithLevelDB(db)
+	require.NoError(t, err)
+	require.NoError(t, createTestBlocks(bm, 3))
+	corruptInfo(t, db, func(info *BlockMatrixInfo) {
+		info.ID = ""
+	})
+
+	id, err := bm.MatrixID()
+	require.NoError(t, err)
+	require.NotEmpty(t, id)
+
+	again, err := bm.MatrixID()
+	require.NoError(t, err)
+	require.Equal(t, id, again)
+}
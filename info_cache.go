@@ -0,0 +1,71 @@
+package blockmatrix
+
+import (
+	"bytes"
+	"sync"
+)
+
+// infoCache holds the decoded info of a block matrix created WithInfoCache.  It has its own lock since readers that only
+// hold the block matrix's read lock, or no lock, fill it concurrently.  The generation is advanced whenever the info
+// entry is written so a reader that read the entry before a write cannot cache what it read afterward.
+type infoCache struct {
+	mu         sync.Mutex
+	info       *BlockMatrixInfo
+	generation uint64
+}
+
+// cachedInfo returns a copy of the cached info, or nil and the current generation if the info is not cached.
+func (b *BlockMatrix) cachedInfo() (*BlockMatrixInfo, uint64) {
+	if b.infoCache == nil {
+		return nil, 0
+	}
+
+	b.infoCache.mu.Lock()
+	defer b.infoCache.mu.Unlock()
+
+	if b.infoCache.info == nil {
+		return nil, b.infoCache.generation
+	}
+
+	return copyInfo(b.infoCache.info), b.infoCache.generation
+}
+
+// fillInfoCache caches a copy of info, read from the database, unless the info entry was written since generation.
+func (b *BlockMatrix) fillInfoCache(info *BlockMatrixInfo, generation uint64) {
+	if b.infoCache == nil {
+		return
+	}
+
+	b.infoCache.mu.Lock()
+	defer b.infoCache.mu.Unlock()
+
+	if b.infoCache.generation == generation {
+		b.infoCache.info = copyInfo(info)
+	}
+}
+
+// cacheInfo caches a copy of info, which has just been written to the database.
+func (b *BlockMatrix) cacheInfo(info *BlockMatrixInfo) {
+	if b.infoCache == nil {
+		return
+	}
+
+	b.infoCache.mu.Lock()
+	defer b.infoCache.mu.Unlock()
+
+	b.infoCache.info = copyInfo(info)
+}
+
+// invalidateInfo drops the cached info if key is the info entry.  It is called both before and after the entry is
+// written.
+func (b *BlockMatrix) invalidateInfo(key []byte) {
+	if b.infoCache == nil || !bytes.Equal(key, InfoKey) {
+		return
+	}
+
+	b.infoCache.mu.Lock()
+	defer b.infoCache.mu.Unlock()
+
+	b.infoCache.info = nil
+	b.infoCache.generation++
+}
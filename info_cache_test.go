@@ -0,0 +1,80 @@
+package blockmatrix
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestWithInfoCache(t *testing.T) {
+	store := NewMemStore()
+	bm, err := New(store, WithInfoCache())
+	require.NoError(t, err)
+	require.NoError(t, createTestBlocks(bm, 9))
+
+	// requireConsistent checks the cached info against the info stored in the database
+	requireConsistent := func() {
+		cached, err := bm.GetBlockMatrixInfo()
+		require.NoError(t, err)
+
+		uncached, err := New(store)
+		require.NoError(t, err)
+		stored, err := uncached.GetBlockMatrixInfo()
+		require.NoError(t, err)
+		require.Equal(t, stored, cached)
+
+		ok, err := bm.IsValid()
+		require.NoError(t, err)
+		require.True(t, ok)
+	}
+
+	requireConsistent()
+
+	require.NoError(t, bm.UpdateBlock("key2", []byte("updated")))
+	requireConsistent()
+
+	require.NoError(t, bm.EraseBlock("key5"))
+	requireConsistent()
+
+	require.NoError(t, bm.DeleteBlock("key6"))
+	requireConsistent()
+
+	tx := bm.Begin()
+	require.NoError(t, tx.AddBlock("tx", []byte("tx")))
+	require.NoError(t, tx.EraseBlock("key7"))
+	require.NoError(t, tx.Commit())
+	requireConsistent()
+
+	// growing the block matrix
+	require.NoError(t, bm.AddBlocks(testData(20)))
+	requireConsistent()
+
+	// changing the returned info does not change the cache
+	info, err := bm.GetBlockMatrixInfo()
+	require.NoError(t, err)
+	info.BlockCount = 0
+	info.Rows[0] = nil
+	requireConsistent()
+}
+
+// BenchmarkInfoCache adds blocks to a block matrix of 1000 blocks with and without the info cache.
+func BenchmarkInfoCache(b *testing.B) {
+	for _, cached := range []bool{false, true} {
+		b.Run(fmt.Sprintf("cached=%t", cached), func(b *testing.B) {
+			opts := make([]Option, 0)
+			if cached {
+				opts = append(opts, WithInfoCache())
+			}
+
+			bm, err := New(NewMemStore(), opts...)
+			require.NoError(b, err)
+			require.NoError(b, createTestBlocks(bm, 1000))
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				require.NoError(b, bm.AddBlock(fmt.Sprintf("bench%d", i), []byte{byte(i)}))
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package blockmatrix
+
+import (
+	"encoding/binary"
+	"fmt"
+	"github.com/syndtr/goleveldb/leveldb"
+	"io"
+)
+
+// journal record operations
+const (
+	journalPut    byte = 1
+	journalDelete byte = 2
+)
+
+// writeJournal appends a record of a database write to the journal.  Each record is a 4 byte big endian length followed
+// by that many bytes: the operation, the uvarint length of the key, the key, and for puts the value.
+func (b *BlockMatrix) writeJournal(op byte, key []byte, value []byte) error {
+	if b.journal == nil {
+		return nil
+	}
+
+	record := make([]byte, 4, 4+1+binary.MaxVarintLen64+len(key)+len(value))
+	record = append(record, op)
+	record = appendUvarint(record, uint64(len(key)))
+	record = append(record, key...)
+	record = append(record, value...)
+	binary.BigEndian.PutUint32(record, uint32(len(record)-4))
+
+	if _, err := b.journal.Write(record); err != nil {
+		return fmt.Errorf("error writing journal record: %w", err)
+	}
+
+	return nil
+}
+
+func appendUvarint(buf []byte, v uint64) []byte {
+	tmp := make([]byte, binary.MaxVarintLen64)
+	n := binary.PutUvarint(tmp, v)
+	return append(buf, tmp[:n]...)
+}
+
+// RecoverFromJournal replays every record in a journal written with WithJournal into the given database and returns the
+// block matrix stored in it.  Replaying a complete journal into an empty database recreates the journaled block matrix.
+// An incomplete record at the end of the journal is a write that was cut off by a crash, replay stops before it.
+func RecoverFromJournal(db *leveldb.DB, r io.Reader, opts ...Option) (*BlockMatrix, error) {
+	header := make([]byte, 4)
+	for {
+		if _, err := io.ReadFull(r, header); err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("error reading journal record length: %w", err)
+		}
+
+		// the length is read from the journal so the record is only allocated as far as the journal goes
+		n := int64(binary.BigEndian.Uint32(header))
+		record, err := io.ReadAll(io.LimitReader(r, n))
+		if err != nil {
+			return nil, fmt.Errorf("error reading journal record: %w", err)
+		} else if int64(len(record)) < n {
+			break
+		}
+
+		if err := replayJournalRecord(db, record); err != nil {
+			return nil, err
+		}
+	}
+
+	return NewWithLevelDB(db, opts...)
+}
+
+func replayJournalRecord(db *leveldb.DB, record []byte) error {
+	if len(record) == 0 {
+		return fmt.Errorf("%w: empty record", ErrMalformedJournal)
+	}
+
+	op := record[0]
+	keyLen, n := binary.Uvarint(record[1:])
+	if n <= 0 || uint64(len(record)-1-n) < keyLen {
+		return fmt.Errorf("%w: record key", ErrMalformedJournal)
+	}
+
+	key := record[1+n : 1+n+int(keyLen)]
+	value := record[1+n+int(keyLen):]
+
+	switch op {
+	case journalPut:
+		return storageErr(db.Put(key, value, nil))
+	case journalDelete:
+		return storageErr(db.Delete(key, nil))
+	default:
+		return fmt.Errorf("%w: unknown operation %d", ErrMalformedJournal, op)
+	}
+}
@@ -0,0 +1,85 @@
+package blockmatrix
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestRecoverFromJournal(t *testing.T) {
+	journal := &bytes.Buffer{}
+	bm, err := NewWithLevelDB(newTestDB(t), WithJournal(journal))
+	require.NoError(t, err)
+	require.NoError(t, createTestBlocks(bm, 7))
+	require.NoError(t, bm.EraseBlock("key3"))
+
+	recovered, err := RecoverFromJournal(newTestDB(t), bytes.NewReader(journal.Bytes()))
+	require.NoError(t, err)
+
+	expectedInfo, err := bm.GetBlockMatrixInfo()
+	require.NoError(t, err)
+	actualInfo, err := recovered.GetBlockMatrixInfo()
+	require.NoError(t, err)
+	require.Equal(t, expectedInfo, actualInfo)
+
+	expected, err := bm.Matrix()
+	require.NoError(t, err)
+	actual, err := recovered.Matrix()
+	require.NoError(t, err)
+	require.Equal(t, expected, actual)
+
+	_, err = recovered.GetBlock("key3")
+	require.Error(t, err)
+	block, err := recovered.GetBlock("key7")
+	require.NoError(t, err)
+	require.Equal(t, []byte{7}, block.Data)
+}
+
+func TestRecoverFromTruncatedJournal(t *testing.T) {
+	journal := &bytes.Buffer{}
+	bm, err := NewWithLevelDB(newTestDB(t), WithJournal(journal))
+	require.NoError(t, err)
+	require.NoError(t, createTestBlocks(bm, 1))
+	expected, err := bm.Matrix()
+	require.NoError(t, err)
+
+	complete := journal.Len()
+	require.NoError(t, bm.AddBlock("key2", []byte{2}))
+
+	tests := []struct {
+		name string
+		cut  int
+	}{
+		{"partial length", complete + 2},
+		{"partial record", complete + 6},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			truncated := journal.Bytes()[:tt.cut]
+			recovered, err := RecoverFromJournal(newTestDB(t), bytes.NewReader(truncated))
+			require.NoError(t, err)
+
+			actual, err := recovered.Matrix()
+			require.NoError(t, err)
+			require.Equal(t, expected, actual)
+			_, err = recovered.GetBlock("key2")
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestRecoverFromJournalOversizedRecord(t *testing.T) {
+	journal := &bytes.Buffer{}
+	bm, err := NewWithLevelDB(newTestDB(t), WithJournal(journal))
+	require.NoError(t, err)
+	require.NoError(t, createTestBlocks(bm, 1))
+
+	// a record claiming 4GiB followed by a few bytes
+	journal.Write([]byte{0xff, 0xff, 0xff, 0xff, 1, 2, 3})
+
+	recovered, err := RecoverFromJournal(newTestDB(t), bytes.NewReader(journal.Bytes()))
+	require.NoError(t, err)
+	block, err := recovered.GetBlock("key1")
+	require.NoError(t, err)
+	require.Equal(t, []byte{1}, block.Data)
+}
@@ -0,0 +1,154 @@
+package blockmatrix
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// keyIndexPrefix prefixes the key index entries.  Each key that has a block has an entry under its database key holding
+// the application key and the sequence number it was indexed with, so Keys can list the keys in insertion order without
+// scanning the blocks.
+const keyIndexPrefix = "key_index:"
+
+// errStopIteration stops an iteration early without it failing.
+var errStopIteration = errors.New("stop iteration")
+
+type keyIndexValue struct {
+	Seq uint64 `json:"seq"`
+	Key string `json:"key"`
+}
+
+func keyIndexEntry(dbKey []byte) []byte {
+	return append([]byte(keyIndexPrefix), dbKey...)
+}
+
+// Keys returns every key that has a block, in the order the keys were added.  Erased and deleted keys are not listed,
+// a key added again after it was erased is listed at the position it was added again.  Adding a block with a key that
+// already has one does not move the key.
+func (b *BlockMatrix) Keys() ([]string, error) {
+	defer b.startSpan("Keys")()
+
+	values := make([]keyIndexValue, 0)
+	err := b.iterate([]byte(keyIndexPrefix), func(key []byte, value []byte) error {
+		indexValue := keyIndexValue{}
+		if err := json.Unmarshal(value, &indexValue); err != nil {
+			return fmt.Errorf("error decoding key index entry %q: %w", key, err)
+		}
+
+		values = append(values, indexValue)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(values, func(i, j int) bool {
+		return values[i].Seq < values[j].Seq
+	})
+
+	keys := make([]string, len(values))
+	for i, value := range values {
+		keys[i] = value.Key
+	}
+
+	return keys, nil
+}
+
+// keyIndexWrite returns the value of the key index entry for key, whose database key is dbKey, reading entries with
+// get.  If the key is already indexed, or dbKey is the chunk entry of a large value, nil is returned and the key keeps
+// its position.
+func (b *BlockMatrix) keyIndexWrite(get func(key []byte) ([]byte, error), key string, dbKey []byte) ([]byte, error) {
+	if isLargeChunkEntry(dbKey) {
+		return nil, nil
+	}
+
+	if _, err := get(keyIndexEntry(dbKey)); err == nil {
+		return nil, nil
+	} else if !errors.Is(err, ErrNotFound) {
+		return nil, err
+	}
+
+	seq, err := b.nextKeyIndexSeq()
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(keyIndexValue{Seq: seq, Key: key})
+}
+
+// indexKey adds key, whose database key is dbKey, to the key index unless it is already indexed.
+func (b *BlockMatrix) indexKey(key string, dbKey []byte) error {
+	value, err := b.keyIndexWrite(b.get, key, dbKey)
+	if err != nil || value == nil {
+		return err
+	}
+
+	return b.put(keyIndexEntry(dbKey), value)
+}
+
+// nextKeyIndexSeq returns the sequence number of the next key indexed.  Like nextChangeLogSeq the index is scanned for
+// the last sequence number the first time and the sequence number is counted in memory afterwards.
+func (b *BlockMatrix) nextKeyIndexSeq() (uint64, error) {
+	if b.keyIndexSeq == 0 {
+		err := b.iterate([]byte(keyIndexPrefix), func(key []byte, value []byte) error {
+			indexValue := keyIndexValue{}
+			if err := json.Unmarshal(value, &indexValue); err != nil {
+				return fmt.Errorf("error decoding key index entry %q: %w", key, err)
+			}
+
+			if indexValue.Seq >= b.keyIndexSeq {
+				b.keyIndexSeq = indexValue.Seq + 1
+			}
+
+			return nil
+		})
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	seq := b.keyIndexSeq
+	b.keyIndexSeq++
+	return seq, nil
+}
+
+// buildKeyIndex indexes the keys of a block matrix created before keys were indexed, in block number order, if it has
+// blocks but no key index.
+func (b *BlockMatrix) buildKeyIndex(info *BlockMatrixInfo) error {
+	if info.BlockCount == 0 {
+		return nil
+	}
+
+	indexed := false
+	err := b.iterate([]byte(keyIndexPrefix), func(key []byte, value []byte) error {
+		indexed = true
+		return errStopIteration
+	})
+	if err != nil && !errors.Is(err, errStopIteration) {
+		return err
+	} else if indexed {
+		return nil
+	}
+
+	keys, err := b.blockKeys()
+	if err != nil {
+		return err
+	}
+
+	blockNums := make([]int, 0, len(keys))
+	for blockNum := range keys {
+		blockNums = append(blockNums, blockNum)
+	}
+	sort.Ints(blockNums)
+
+	for _, blockNum := range blockNums {
+		key := keys[blockNum]
+		if err = b.indexKey(key, b.dbKey(key)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
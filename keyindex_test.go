@@ -0,0 +1,52 @@
+package blockmatrix
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestKeys(t *testing.T) {
+	db := newTestDB(t)
+	bm, err := NewWithLevelDB(db)
+	require.NoError(t, err)
+
+	keys, err := bm.Keys()
+	require.NoError(t, err)
+	require.Empty(t, keys)
+
+	for _, key := range []string{"zebra", "apple", "mango", "kiwi"} {
+		require.NoError(t, bm.AddBlock(key, []byte(key)))
+	}
+	require.NoError(t, bm.EraseBlock("apple"))
+	require.NoError(t, bm.DeleteBlock("kiwi"))
+
+	keys, err = bm.Keys()
+	require.NoError(t, err)
+	require.Equal(t, []string{"zebra", "mango"}, keys)
+
+	// a key added again after it was erased moves to the end, adding a block with a live key does not move it
+	tx := bm.Begin()
+	require.NoError(t, tx.AddBlock("apple", []byte("apple")))
+	require.NoError(t, tx.AddBlock("pear", []byte("pear")))
+	require.NoError(t, tx.Commit())
+	require.NoError(t, bm.AddBlock("zebra", []byte("zebra2")))
+
+	keys, err = bm.Keys()
+	require.NoError(t, err)
+	require.Equal(t, []string{"zebra", "mango", "apple", "pear"}, keys)
+
+	// a block matrix created before keys were indexed has its keys indexed in block number order when it is opened
+	require.NoError(t, bm.iterate([]byte(keyIndexPrefix), func(key []byte, value []byte) error {
+		return bm.store.Delete(copyBytes(key))
+	}))
+	bm, err = NewWithLevelDB(db)
+	require.NoError(t, err)
+
+	keys, err = bm.Keys()
+	require.NoError(t, err)
+	require.Equal(t, []string{"mango", "apple", "pear", "zebra"}, keys)
+	require.NoError(t, bm.AddBlock("plum", []byte("plum")))
+	keys, err = bm.Keys()
+	require.NoError(t, err)
+	require.Equal(t, []string{"mango", "apple", "pear", "zebra", "plum"}, keys)
+}
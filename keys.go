@@ -0,0 +1,151 @@
+package blockmatrix
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+)
+
+// originalKeyPrefix prefixes the entries mapping a transformed database key back to the application key it came from.
+const originalKeyPrefix = "original_key:"
+
+// DefaultMaxKeyLength is the default length in bytes of the longest database key a block may be added with, see
+// WithMaxKeyLength.
+const DefaultMaxKeyLength = 4096
+
+// internalPrefixes are the prefixes of database entries the block matrix stores for its own bookkeeping, these entries
+// are neither keys nor blocks.
+var internalPrefixes = []string{originalKeyPrefix, quarantinePrefix, reservedPrefix, changeLogPrefix, freePrefix,
+	hashIndexPrefix, cleanShutdownKey, eraseJournalPrefix, keyIndexPrefix, aliasPrefix, largeChunkPrefix}
+
+// dbKey returns the database key for the given application key.  The chunk entries of large values are database keys
+// already.
+func (b *BlockMatrix) dbKey(key string) []byte {
+	key = b.normalizeKey(key)
+	if b.keyTransform == nil || isLargeChunkEntry([]byte(key)) {
+		return []byte(key)
+	}
+
+	return []byte(b.keyTransform(key))
+}
+
+// normalizeKey returns the key as normalized by the configured key normalizer.
+func (b *BlockMatrix) normalizeKey(key string) string {
+	if b.keyNormalizer == nil || isLargeChunkEntry([]byte(key)) {
+		return key
+	}
+
+	return b.keyNormalizer(key)
+}
+
+func originalKeyEntry(dbKey []byte) []byte {
+	return append([]byte(originalKeyPrefix), dbKey...)
+}
+
+// checkKeyLength returns ErrKeyTooLong if dbKey, the database key for key, is longer than the maximum key length.
+func (b *BlockMatrix) checkKeyLength(key string, dbKey []byte) error {
+	if b.maxKeyLength > 0 && len(dbKey) > b.maxKeyLength {
+		return fmt.Errorf("%w: key %.32q... is %d bytes in the database, the maximum is %d", ErrKeyTooLong, key,
+			len(dbKey), b.maxKeyLength)
+	}
+
+	return nil
+}
+
+// checkReservedKey returns ErrReservedKey if dbKey, the database key for key, could be mistaken for one of the block
+// matrix's own entries: a block entry, which is keyed by its number, or an entry with one of the internal prefixes.
+func checkReservedKey(key string, dbKey []byte) error {
+	if _, err := strconv.Atoi(string(dbKey)); err == nil || isInternalEntry(dbKey) {
+		return fmt.Errorf("%w: key %.32q is stored as %.32q", ErrReservedKey, key, dbKey)
+	}
+
+	return nil
+}
+
+// checkKeyCollision returns ErrKeyCollision if dbKey is already used by an application key other than key.
+func (b *BlockMatrix) checkKeyCollision(key string, dbKey []byte) error {
+	if b.keyTransform == nil {
+		return nil
+	}
+
+	if ok, err := b.has(originalKeyEntry(dbKey)); err != nil {
+		return err
+	} else if !ok {
+		return nil
+	}
+
+	original, err := b.get(originalKeyEntry(dbKey))
+	if err != nil {
+		return err
+	}
+
+	if string(original) != key {
+		return fmt.Errorf("%w: %q and %q both map to %q", ErrKeyCollision, original, key, dbKey)
+	}
+
+	return nil
+}
+
+// isInternalEntry returns true if the database key is the info entry or has one of the internal prefixes.
+func isInternalEntry(dbKey []byte) bool {
+	if bytes.Equal(dbKey, InfoKey) {
+		return true
+	}
+
+	for _, prefix := range internalPrefixes {
+		if bytes.HasPrefix(dbKey, []byte(prefix)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// keyEntryBlock returns the block number held by a key entry and true, or false if the entry is not a key entry.  The
+// chunk entries of large values are key entries.
+func keyEntryBlock(dbKey []byte, value []byte) (int, bool) {
+	if isInternalEntry(dbKey) && !isLargeChunkEntry(dbKey) {
+		return 0, false
+	}
+
+	if _, err := strconv.Atoi(string(dbKey)); err == nil {
+		return 0, false
+	}
+
+	blockNum, err := strconv.Atoi(string(value))
+	if err != nil {
+		return 0, false
+	}
+
+	return blockNum, true
+}
+
+// blockKeys scans the database for key entries and returns the application key mapped to each block number.  Block
+// entries are keyed by their number so any other entry that isn't internal and holds a number is a key entry.
+func (b *BlockMatrix) blockKeys() (map[int]string, error) {
+	keys := make(map[int]string)
+	err := b.iterate(nil, func(dbKey []byte, value []byte) error {
+		blockNum, ok := keyEntryBlock(dbKey, value)
+		if !ok {
+			return nil
+		}
+
+		key := string(dbKey)
+		if b.keyTransform != nil && !isLargeChunkEntry(dbKey) {
+			original, err := b.get(originalKeyEntry(dbKey))
+			if err != nil {
+				return err
+			}
+
+			key = string(original)
+		}
+
+		keys[blockNum] = key
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
@@ -0,0 +1,142 @@
+package blockmatrix
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+func TestWithKeyTransform(t *testing.T) {
+	hashKey := func(key string) string {
+		sum := sha256.Sum256([]byte(key))
+		return hex.EncodeToString(sum[:])
+	}
+
+	bm, err := NewWithLevelDB(newTestDB(t), WithKeyTransform(hashKey))
+	require.NoError(t, err)
+
+	longKey := strings.Repeat("a", 1024)
+	require.NoError(t, bm.AddBlock(longKey, []byte{1}))
+	require.NoError(t, bm.AddBlock("key2", []byte{2}))
+
+	block, err := bm.GetBlock(longKey)
+	require.NoError(t, err)
+	require.Equal(t, []byte{1}, block.Data)
+	block, err = bm.GetBlock("key2")
+	require.NoError(t, err)
+	require.Equal(t, []byte{2}, block.Data)
+
+	require.NoError(t, bm.EraseBlock("key2"))
+	_, err = bm.GetBlock("key2")
+	require.Error(t, err)
+}
+
+func TestWithKeyTransformCollision(t *testing.T) {
+	truncate := func(key string) string {
+		return key[:3]
+	}
+
+	bm, err := NewWithLevelDB(newTestDB(t), WithKeyTransform(truncate))
+	require.NoError(t, err)
+
+	require.NoError(t, bm.AddBlock("key1", []byte{1}))
+	err = bm.AddBlock("key2", []byte{2})
+	require.True(t, errors.Is(err, ErrKeyCollision))
+
+	block, err := bm.GetBlock("key1")
+	require.NoError(t, err)
+	require.Equal(t, []byte{1}, block.Data)
+}
+
+func TestWithKeyNormalizer(t *testing.T) {
+	bm, err := NewWithLevelDB(newTestDB(t), WithKeyNormalizer(strings.ToLower), WithKeyTransform(strings.ToUpper))
+	require.NoError(t, err)
+
+	require.NoError(t, bm.AddBlock("Key", []byte{1}))
+
+	for _, key := range []string{"Key", "key", "KEY"} {
+		block, err := bm.GetBlock(key)
+		require.NoError(t, err)
+		require.Equal(t, []byte{1}, block.Data)
+
+		blockNum, err := bm.BlockNumber(key)
+		require.NoError(t, err)
+		require.Equal(t, 1, blockNum)
+	}
+
+	keys, err := bm.blockKeys()
+	require.NoError(t, err)
+	require.Equal(t, map[int]string{1: "key"}, keys)
+
+	require.NoError(t, bm.EraseBlock("KEY"))
+	_, err = bm.GetBlock("key")
+	require.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestWithMaxKeyLength(t *testing.T) {
+	bm, err := NewWithLevelDB(newTestDB(t))
+	require.NoError(t, err)
+	require.NoError(t, createTestBlocks(bm, 2))
+	before, err := bm.GetBlockMatrixInfo()
+	require.NoError(t, err)
+
+	longKey := strings.Repeat("a", DefaultMaxKeyLength+1)
+	require.ErrorIs(t, bm.AddBlock(longKey, []byte{1}), ErrKeyTooLong)
+	tx := bm.Begin()
+	require.NoError(t, tx.AddBlock(longKey, []byte{1}))
+	require.ErrorIs(t, tx.Commit(), ErrKeyTooLong)
+
+	// nothing was written
+	after, err := bm.GetBlockMatrixInfo()
+	require.NoError(t, err)
+	require.Equal(t, before, after)
+	ok, err := bm.has([]byte(longKey))
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	bm, err = NewWithLevelDB(newTestDB(t), WithMaxKeyLength(8))
+	require.NoError(t, err)
+	require.NoError(t, bm.AddBlock("abcdefgh", []byte{1}))
+	require.ErrorIs(t, bm.AddBlock("abcdefghi", []byte{1}), ErrKeyTooLong)
+
+	bm, err = NewWithLevelDB(newTestDB(t), WithMaxKeyLength(0))
+	require.NoError(t, err)
+	require.NoError(t, bm.AddBlock(longKey, []byte{1}))
+}
+
+func TestReservedKeys(t *testing.T) {
+	bm, err := NewWithLevelDB(newTestDB(t))
+	require.NoError(t, err)
+	require.NoError(t, createTestBlocks(bm, 2))
+	before, err := bm.GetBlockMatrixInfo()
+	require.NoError(t, err)
+
+	for _, key := range []string{"free:2", "1", "3", "info", "hash:abc", "alias:key1", "key_index:key1"} {
+		require.ErrorIs(t, bm.AddBlock(key, []byte{9}), ErrReservedKey, key)
+
+		tx := bm.Begin()
+		require.NoError(t, tx.AddBlock(key, []byte{9}))
+		require.ErrorIs(t, tx.Commit(), ErrReservedKey, key)
+
+		require.ErrorIs(t, bm.AddAlias("key1", key), ErrReservedKey, key)
+	}
+
+	// nothing was written
+	after, err := bm.GetBlockMatrixInfo()
+	require.NoError(t, err)
+	require.Equal(t, before, after)
+
+	// the next block gets a new slot instead of one named by a rejected key
+	require.NoError(t, bm.AddBlock("zz", []byte{3}))
+	blockNum, err := bm.BlockNumber("zz")
+	require.NoError(t, err)
+	require.Equal(t, 3, blockNum)
+	for i, key := range []string{"key1", "key2", "zz"} {
+		block, err := bm.GetBlock(key)
+		require.NoError(t, err)
+		require.Equal(t, []byte{byte(i + 1)}, block.Data)
+	}
+}
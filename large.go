@@ -0,0 +1,108 @@
+package blockmatrix
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+)
+
+// largeBlockManifest is stored as the data of the block at a large value's key and links the chunks of the value.
+type largeBlockManifest struct {
+	// Chunks stores the keys of the chunk blocks in order
+	Chunks []string `json:"chunks"`
+	// Length is the length of the whole value
+	Length int `json:"length"`
+	// Hash is the hash of the whole value
+	Hash []byte `json:"hash"`
+}
+
+// largeChunkPrefix prefixes the entries mapping the chunks of a large value to their block numbers.  Chunk entries are
+// key entries but are never normalized, transformed, or indexed.  Adding a block whose database key has this prefix
+// fails with ErrReservedKey, so chunk entries can't collide with an application key.
+const largeChunkPrefix = "large_chunk:"
+
+// chunkEntry returns the database key of the chunk block at the given index of a large value.
+func chunkEntry(key string, index int) string {
+	return fmt.Sprintf("%s%s#%d", largeChunkPrefix, key, index)
+}
+
+func isLargeChunkEntry(dbKey []byte) bool {
+	return bytes.HasPrefix(dbKey, []byte(largeChunkPrefix))
+}
+
+// AddLargeBlock splits data into chunks of at most chunkSize bytes and adds each chunk as a block, followed by a
+// manifest block at the given key linking the chunks together.  The chunks and the manifest are added in a single
+// transaction, see Tx.  Chunks are regular blocks and participate in the row and column hashes like any other block.
+// Use GetLargeBlock to reassemble the value.
+func (b *BlockMatrix) AddLargeBlock(key string, data []byte, chunkSize int) error {
+	if chunkSize <= 0 {
+		return fmt.Errorf("chunk size must be positive, got %d", chunkSize)
+	}
+
+	manifest := largeBlockManifest{
+		Chunks: make([]string, 0, len(data)/chunkSize+1),
+		Length: len(data),
+		Hash:   b.hash(data),
+	}
+
+	tx := b.Begin()
+	for i := 0; i*chunkSize < len(data); i++ {
+		end := (i + 1) * chunkSize
+		if end > len(data) {
+			end = len(data)
+		}
+
+		entry := chunkEntry(b.normalizeKey(key), i)
+		if err := tx.addChunk(entry, data[i*chunkSize:end]); err != nil {
+			return fmt.Errorf("error adding chunk %d: %w", i, err)
+		}
+
+		manifest.Chunks = append(manifest.Chunks, entry)
+	}
+
+	bytes, err := json.Marshal(manifest)
+	if err != nil {
+		return err
+	}
+
+	if err = tx.AddBlock(key, bytes); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// GetLargeBlock reassembles a value added with AddLargeBlock.  Each chunk's hash is checked against its data and the
+// reassembled value is checked against the hash in the manifest.
+func (b *BlockMatrix) GetLargeBlock(key string) ([]byte, error) {
+	block, err := b.GetBlock(key)
+	if err != nil {
+		return nil, err
+	}
+
+	manifest := largeBlockManifest{}
+	if err = json.Unmarshal(block.Data, &manifest); err != nil {
+		return nil, fmt.Errorf("error reading manifest of %q: %w", key, err)
+	}
+
+	data := bytes.NewBuffer(make([]byte, 0, manifest.Length))
+	for i, k := range manifest.Chunks {
+		chunk, err := b.GetBlock(k)
+		if err != nil {
+			return nil, fmt.Errorf("error getting chunk %d: %w", i, err)
+		}
+
+		if !reflect.DeepEqual(chunk.Hash, b.blockHash(chunk)) {
+			return nil, fmt.Errorf("%w: chunk %d", ErrHashMismatch, i)
+		}
+
+		data.Write(chunk.Data)
+	}
+
+	if data.Len() != manifest.Length || !reflect.DeepEqual(b.hash(data.Bytes()), manifest.Hash) {
+		return nil, fmt.Errorf("%w: reassembled value of %q does not match its manifest", ErrHashMismatch, key)
+	}
+
+	return data.Bytes(), nil
+}
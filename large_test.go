@@ -0,0 +1,83 @@
+package blockmatrix
+
+import (
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+func TestLargeBlock(t *testing.T) {
+	db := newTestDB(t)
+	bm, err := NewWithLevelDB(db)
+	require.NoError(t, err)
+	require.NoError(t, bm.AddBlock("small", []byte{1}))
+
+	data := make([]byte, 1000)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	require.NoError(t, bm.AddLargeBlock("large", data, 300))
+
+	info, err := bm.GetBlockMatrixInfo()
+	require.NoError(t, err)
+	require.Equal(t, 6, info.BlockCount)
+
+	actual, err := bm.GetLargeBlock("large")
+	require.NoError(t, err)
+	require.Equal(t, data, actual)
+
+	// the chunks are not keys of the block matrix and don't collide with the keys of the application
+	keys, err := bm.Keys()
+	require.NoError(t, err)
+	require.Equal(t, []string{"small", "large"}, keys)
+	require.NoError(t, bm.AddBlock("large#chunk0", []byte{2}))
+	require.ErrorIs(t, bm.AddBlock(chunkEntry("large", 0), []byte{2}), ErrReservedKey)
+	require.ErrorIs(t, bm.AddBlock("large_chunk:x#0", []byte{2}), ErrReservedKey)
+	actual, err = bm.GetLargeBlock("large")
+	require.NoError(t, err)
+	require.Equal(t, data, actual)
+
+	result, err := bm.Validate()
+	require.NoError(t, err)
+	require.True(t, result.OK)
+
+	// block 3 is the second chunk
+	corruptBlockData(t, db, 3, []byte("tampered"))
+	_, err = bm.GetLargeBlock("large")
+	require.Error(t, err)
+
+	require.Error(t, bm.AddLargeBlock("invalid", data, 0))
+}
+
+func TestLargeBlockAtomic(t *testing.T) {
+	bm, err := NewWithLevelDB(newTestDB(t), WithMaxSize(3))
+	require.NoError(t, err)
+	require.NoError(t, bm.AddBlock("small", []byte{1}))
+
+	// five chunks and the manifest don't fit next to the small block
+	require.ErrorIs(t, bm.AddLargeBlock("large", make([]byte, 1000), 200), ErrMaxSizeExceeded)
+
+	info, err := bm.GetBlockMatrixInfo()
+	require.NoError(t, err)
+	require.Equal(t, 1, info.BlockCount)
+
+	keys, err := bm.Keys()
+	require.NoError(t, err)
+	require.Equal(t, []string{"small"}, keys)
+}
+
+func TestLargeBlockKeyTransform(t *testing.T) {
+	bm, err := NewWithLevelDB(newTestDB(t), WithKeyNormalizer(strings.ToLower), WithKeyTransform(strings.ToUpper))
+	require.NoError(t, err)
+
+	data := []byte("a value split in several chunks")
+	require.NoError(t, bm.AddLargeBlock("Large", data, 8))
+
+	actual, err := bm.GetLargeBlock("LARGE")
+	require.NoError(t, err)
+	require.Equal(t, data, actual)
+
+	keys, err := bm.Keys()
+	require.NoError(t, err)
+	require.Equal(t, []string{"large"}, keys)
+}
@@ -0,0 +1,101 @@
+package blockmatrix
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/require"
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/storage"
+	"testing"
+)
+
+func TestWithLazyEmptyBlocks(t *testing.T) {
+	eager, err := NewWithLevelDB(newTestDB(t), WithClock(testClock))
+	require.NoError(t, err)
+	lazy, err := NewWithLevelDB(newTestDB(t), WithClock(testClock), WithLazyEmptyBlocks())
+	require.NoError(t, err)
+
+	for _, bm := range []*BlockMatrix{eager, lazy} {
+		require.NoError(t, createTestBlocks(bm, 10))
+		require.NoError(t, bm.EraseBlock("key7"))
+
+		tx := bm.Begin()
+		require.NoError(t, tx.AddBlock("key11", []byte{11}))
+		require.NoError(t, tx.AddBlock("key12", []byte{12}))
+		require.NoError(t, tx.AddBlock("key13", []byte{13}))
+		require.NoError(t, tx.Commit())
+	}
+
+	stats, err := lazy.KeyspaceStats()
+	require.NoError(t, err)
+	require.Equal(t, 13, stats.Blocks)
+	stats, err = eager.KeyspaceStats()
+	require.NoError(t, err)
+	require.Equal(t, 20, stats.Blocks)
+
+	eagerRoot, err := eager.RootHash()
+	require.NoError(t, err)
+	lazyRoot, err := lazy.RootHash()
+	require.NoError(t, err)
+	require.Equal(t, eagerRoot, lazyRoot)
+
+	eagerMatrix, err := eager.Matrix()
+	require.NoError(t, err)
+	lazyMatrix, err := lazy.Matrix()
+	require.NoError(t, err)
+	require.Equal(t, eagerMatrix, lazyMatrix)
+
+	block, err := lazy.GetBlockByNumber(20)
+	require.NoError(t, err)
+	require.True(t, block.IsEmpty())
+	_, err = lazy.GetBlockByNumber(21)
+	require.ErrorIs(t, err, ErrNotFound)
+
+	blocks, err := lazy.GetBlocksByNumbers([]int{13, 14})
+	require.NoError(t, err)
+	require.Equal(t, []byte{13}, blocks[13].Data)
+	require.True(t, blocks[14].IsEmpty())
+
+	ok, err := lazy.IsValid()
+	require.NoError(t, err)
+	require.True(t, ok)
+}
+
+// BenchmarkLazyEmptyBlocks adds 100 blocks to a block matrix with and without lazy empty blocks and reports the number
+// of database writes.
+func BenchmarkLazyEmptyBlocks(b *testing.B) {
+	for _, lazy := range []bool{false, true} {
+		b.Run(fmt.Sprintf("lazy=%t", lazy), func(b *testing.B) {
+			journal := &countingWriter{}
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				db, err := leveldb.Open(storage.NewMemStorage(), nil)
+				require.NoError(b, err)
+				opts := []Option{WithJournal(journal)}
+				if lazy {
+					opts = append(opts, WithLazyEmptyBlocks())
+				}
+				bm, err := NewWithLevelDB(db, opts...)
+				require.NoError(b, err)
+				b.StartTimer()
+
+				require.NoError(b, createTestBlocks(bm, 100))
+
+				b.StopTimer()
+				require.NoError(b, db.Close())
+				b.StartTimer()
+			}
+
+			b.ReportMetric(float64(journal.writes)/float64(b.N), "writes/op")
+		})
+	}
+}
+
+// countingWriter counts the writes made to it, one per journal record.
+type countingWriter struct {
+	writes int
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.writes++
+	return len(p), nil
+}
@@ -0,0 +1,50 @@
+package blockmatrix
+
+import "bytes"
+
+// Logger receives structured log lines from the block matrix, each a message followed by alternating keys and values,
+// so that it can be adapted to a logging library such as log/slog, zap, or logr.  Logging never changes what the block
+// matrix does, a Logger cannot fail an operation.  The methods are called while the block matrix is locked, so they
+// must not call the block matrix.
+//
+// A log/slog adapter only needs to wrap a slog.Logger:
+//
+//	type slogLogger struct {
+//		logger *slog.Logger
+//	}
+//
+//	func (l slogLogger) Debug(msg string, keysAndValues ...interface{}) { l.logger.Debug(msg, keysAndValues...) }
+type Logger interface {
+	Debug(msg string, keysAndValues ...interface{})
+	Info(msg string, keysAndValues ...interface{})
+	Warn(msg string, keysAndValues ...interface{})
+	Error(msg string, keysAndValues ...interface{})
+}
+
+// noopLogger is the default Logger, it discards every line.
+type noopLogger struct{}
+
+func (noopLogger) Debug(string, ...interface{}) {}
+
+func (noopLogger) Info(string, ...interface{}) {}
+
+func (noopLogger) Warn(string, ...interface{}) {}
+
+func (noopLogger) Error(string, ...interface{}) {}
+
+// logGrowth logs that the block matrix is growing from one size to another.
+func (b *BlockMatrix) logGrowth(from int, to int) {
+	b.logger.Info("growing block matrix", "from_size", from, "to_size", to)
+}
+
+// changedIndices returns the indices at which the hashes in before and after differ.
+func changedIndices(before [][]byte, after [][]byte) []int {
+	changed := make([]int, 0)
+	for i := range after {
+		if i >= len(before) || !bytes.Equal(before[i], after[i]) {
+			changed = append(changed, i)
+		}
+	}
+
+	return changed
+}
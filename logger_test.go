@@ -0,0 +1,59 @@
+package blockmatrix
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/require"
+	"sync"
+	"testing"
+)
+
+// capturingLogger records every line logged at or above info level as the level, message, and key-value pairs.
+type capturingLogger struct {
+	mu    sync.Mutex
+	lines []string
+}
+
+func (l *capturingLogger) log(level string, msg string, keysAndValues []interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.lines = append(l.lines, fmt.Sprint(level, " ", msg, " ", keysAndValues))
+}
+
+func (l *capturingLogger) Debug(string, ...interface{}) {}
+
+func (l *capturingLogger) Info(msg string, keysAndValues ...interface{}) {
+	l.log("INFO", msg, keysAndValues)
+}
+
+func (l *capturingLogger) Warn(msg string, keysAndValues ...interface{}) {
+	l.log("WARN", msg, keysAndValues)
+}
+
+func (l *capturingLogger) Error(msg string, keysAndValues ...interface{}) {
+	l.log("ERROR", msg, keysAndValues)
+}
+
+func TestWithLogger(t *testing.T) {
+	logger := &capturingLogger{}
+	db := newTestDB(t)
+	bm, err := NewWithLevelDB(db, WithLogger(logger))
+	require.NoError(t, err)
+
+	// the first block grows the block matrix from size 1 to 2
+	require.NoError(t, bm.AddBlock("key1", []byte{1}))
+	require.Equal(t, []string{"INFO growing block matrix [from_size 1 to_size 2]"}, logger.lines)
+
+	require.NoError(t, bm.AddBlock("key2", []byte{2}))
+	require.Len(t, logger.lines, 1)
+
+	corruptBlockData(t, db, 2, []byte("corrupted"))
+	ok, err := bm.IsValid()
+	require.NoError(t, err)
+	require.False(t, ok)
+	require.Equal(t, "WARN block hash mismatch [block 2 row 1 col 0]", logger.lines[1])
+
+	// logging does not change the outcome of a failed add
+	err = bm.AddBlock(string(make([]byte, DefaultMaxKeyLength+1)), []byte{3})
+	require.ErrorIs(t, err, ErrKeyTooLong)
+	require.Contains(t, logger.lines[2], "ERROR error adding block")
+}
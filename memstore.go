@@ -0,0 +1,102 @@
+package blockmatrix
+
+import (
+	"bytes"
+	"sort"
+	"sync"
+)
+
+// MemStore is a Store that keeps entries in memory, for tests and block matrices that don't outlive the process.  Values
+// are copied on the way in and out so callers can't change stored bytes.  A MemStore is safe for concurrent use.
+type MemStore struct {
+	mu      sync.Mutex
+	entries map[string][]byte
+}
+
+// NewMemStore creates an empty in-memory store.
+func NewMemStore() *MemStore {
+	return &MemStore{entries: make(map[string][]byte)}
+}
+
+func (s *MemStore) Get(key []byte) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	value, ok := s.entries[string(key)]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	return copyBytes(value), nil
+}
+
+func (s *MemStore) Has(key []byte) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, ok := s.entries[string(key)]
+	return ok, nil
+}
+
+func (s *MemStore) Put(key []byte, value []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.entries[string(key)] = copyBytes(value)
+	return nil
+}
+
+func (s *MemStore) Delete(key []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.entries, string(key))
+	return nil
+}
+
+func (s *MemStore) Write(writes []StoreWrite) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, write := range writes {
+		if write.Delete {
+			delete(s.entries, string(write.Key))
+		} else {
+			s.entries[string(write.Key)] = copyBytes(write.Value)
+		}
+	}
+
+	return nil
+}
+
+// Iterate copies the matching entries before calling fn, so fn sees the entries as they were when Iterate was called and
+// may write to the store.
+func (s *MemStore) Iterate(prefix []byte, fn func(key []byte, value []byte) error) error {
+	s.mu.Lock()
+	keys := make([]string, 0, len(s.entries))
+	for key := range s.entries {
+		if bytes.HasPrefix([]byte(key), prefix) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	values := make([][]byte, len(keys))
+	for i, key := range keys {
+		values[i] = copyBytes(s.entries[key])
+	}
+	s.mu.Unlock()
+
+	for i, key := range keys {
+		if err := fn([]byte(key), values[i]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// copyBytes returns a copy of b that is non-nil even if b is empty.
+func copyBytes(b []byte) []byte {
+	return append([]byte{}, b...)
+}
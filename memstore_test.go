@@ -0,0 +1,59 @@
+package blockmatrix
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestMemStoreCopies(t *testing.T) {
+	store := NewMemStore()
+
+	value := []byte("value")
+	require.NoError(t, store.Put([]byte("key"), value))
+	value[0] = 'X'
+
+	got, err := store.Get([]byte("key"))
+	require.NoError(t, err)
+	require.Equal(t, []byte("value"), got)
+
+	got[0] = 'X'
+	got, err = store.Get([]byte("key"))
+	require.NoError(t, err)
+	require.Equal(t, []byte("value"), got)
+
+	require.NoError(t, store.Iterate(nil, func(key []byte, value []byte) error {
+		value[0] = 'X'
+		return nil
+	}))
+	got, err = store.Get([]byte("key"))
+	require.NoError(t, err)
+	require.Equal(t, []byte("value"), got)
+
+	_, err = store.Get([]byte("missing"))
+	require.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestMemStoreIterate(t *testing.T) {
+	store := NewMemStore()
+	require.NoError(t, store.Write([]StoreWrite{
+		{Key: []byte("b:2"), Value: []byte{2}},
+		{Key: []byte("a"), Value: []byte{0}},
+		{Key: []byte("b:1"), Value: []byte{1}},
+		{Key: []byte("a"), Delete: true},
+	}))
+
+	keys := make([]string, 0)
+	require.NoError(t, store.Iterate([]byte("b:"), func(key []byte, value []byte) error {
+		keys = append(keys, string(key))
+		// writing from the callback must not deadlock
+		return store.Put([]byte("c"), value)
+	}))
+	require.Equal(t, []string{"b:1", "b:2"}, keys)
+
+	ok, err := store.Has([]byte("a"))
+	require.NoError(t, err)
+	require.False(t, ok)
+	ok, err = store.Has([]byte("c"))
+	require.NoError(t, err)
+	require.True(t, ok)
+}
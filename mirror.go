@@ -0,0 +1,79 @@
+package blockmatrix
+
+import (
+	"reflect"
+	"sort"
+)
+
+// ExportHashes returns the stored hash of every block, keyed by block number.  The hashes are enough to build a cheap
+// integrity mirror of the block matrix that can later detect corruption with VerifyAgainstHashes.
+func (b *BlockMatrix) ExportHashes() (map[int][]byte, error) {
+	info, err := b.GetBlockMatrixInfo()
+	if err != nil {
+		return nil, err
+	}
+
+	last, err := b.lastBlock(info)
+	if err != nil {
+		return nil, err
+	}
+
+	blocks, err := b.GetBlocksByNumbers(blockRange(last))
+	if err != nil {
+		return nil, err
+	}
+
+	hashes := make(map[int][]byte, len(blocks))
+	for blockNum, block := range blocks {
+		hashes[blockNum] = block.Hash
+	}
+
+	return hashes, nil
+}
+
+// VerifyAgainstHashes compares the hash of the data of every block to the hashes exported by ExportHashes and returns
+// the numbers of the blocks that diverge, in ascending order.  Blocks missing from either the block matrix or the
+// mirror are reported as diverged.
+func (b *BlockMatrix) VerifyAgainstHashes(mirror map[int][]byte) ([]int, error) {
+	info, err := b.GetBlockMatrixInfo()
+	if err != nil {
+		return nil, err
+	}
+
+	last, err := b.lastBlock(info)
+	if err != nil {
+		return nil, err
+	}
+
+	blocks, err := b.GetBlocksByNumbers(blockRange(last))
+	if err != nil {
+		return nil, err
+	}
+
+	diverged := make([]int, 0)
+	for blockNum, block := range blocks {
+		if hash, ok := mirror[blockNum]; !ok || !reflect.DeepEqual(hash, b.blockHash(block)) {
+			diverged = append(diverged, blockNum)
+		}
+	}
+
+	for blockNum := range mirror {
+		if _, ok := blocks[blockNum]; !ok {
+			diverged = append(diverged, blockNum)
+		}
+	}
+
+	sort.Ints(diverged)
+
+	return diverged, nil
+}
+
+// blockRange returns the block numbers 1 through n.
+func blockRange(n int) []int {
+	nums := make([]int, n)
+	for i := range nums {
+		nums[i] = i + 1
+	}
+
+	return nums
+}
@@ -0,0 +1,38 @@
+package blockmatrix
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestVerifyAgainstHashes(t *testing.T) {
+	db := newTestDB(t)
+	bm, err := NewWithLevelDB(db)
+	require.NoError(t, err)
+	require.NoError(t, createTestBlocks(bm, 7))
+	require.NoError(t, bm.EraseBlock("key2"))
+
+	mirror, err := bm.ExportHashes()
+	require.NoError(t, err)
+	require.Len(t, mirror, 7)
+	require.Equal(t, EmptyBlock().Hash, mirror[2])
+
+	diverged, err := bm.VerifyAgainstHashes(mirror)
+	require.NoError(t, err)
+	require.Empty(t, diverged)
+
+	corruptBlockData(t, db, 4, []byte("tampered"))
+	corruptBlockData(t, db, 6, []byte("tampered"))
+
+	diverged, err = bm.VerifyAgainstHashes(mirror)
+	require.NoError(t, err)
+	require.Equal(t, []int{4, 6}, diverged)
+
+	// blocks only in the block matrix or only in the mirror diverge
+	require.NoError(t, bm.AddBlock("key8", []byte{8}))
+	mirror[20] = EmptyBlock().Hash
+
+	diverged, err = bm.VerifyAgainstHashes(mirror)
+	require.NoError(t, err)
+	require.Equal(t, []int{4, 6, 8, 20}, diverged)
+}
@@ -0,0 +1,61 @@
+package blockmatrix
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Observer is notified of block matrix events so they can be exported as metrics without this package depending on a
+// metrics library, see the prometheus subpackage for a Prometheus adapter.  The callbacks are made while the block
+// matrix is locked, so they must return quickly and must not call the block matrix.
+type Observer interface {
+	// BlockAdded is called after a block is added with AddBlock, AddBlockWithMetadata, AddBlockContext, or a Tx
+	BlockAdded(blockNum int)
+	// BlockErased is called after a block is erased or deleted, even if the validity policy then rejects the erase
+	// since the block remains erased
+	BlockErased(blockNum int)
+	// ValidationFailed is called when validation finds hashes that do not match or the validity policy rejects an
+	// erase, with a description of the failure
+	ValidationFailed(reason string)
+	// OperationDone is called when AddBlock or IsValid returns, whether it succeeded or not, with the name of the
+	// operation, how long it took, and the error it returned
+	OperationDone(operation string, duration time.Duration, err error)
+}
+
+// noopObserver is the default Observer, it ignores every event.
+type noopObserver struct{}
+
+func (noopObserver) BlockAdded(int) {}
+
+func (noopObserver) BlockErased(int) {}
+
+func (noopObserver) ValidationFailed(string) {}
+
+func (noopObserver) OperationDone(string, time.Duration, error) {}
+
+// observeOperation returns a function reporting the named operation to the observer, intended to be deferred with a
+// pointer to the operation's named error result:
+//
+//	defer b.observeOperation("AddBlock")(&err)
+func (b *BlockMatrix) observeOperation(operation string) func(err *error) {
+	start := time.Now()
+	return func(err *error) {
+		b.observer.OperationDone(operation, time.Since(start), *err)
+	}
+}
+
+// failureReason describes the hashes that do not match in a failed validation result.
+func (r *ValidationResult) failureReason() string {
+	failures := make([]string, 0, 3)
+	for _, failure := range []struct {
+		kind    string
+		indices []int
+	}{{"blocks", r.BlockErrors}, {"rows", r.RowErrors}, {"columns", r.ColumnErrors}} {
+		if len(failure.indices) > 0 {
+			failures = append(failures, fmt.Sprintf("%s %v", failure.kind, failure.indices))
+		}
+	}
+
+	return "hash mismatch in " + strings.Join(failures, ", ")
+}
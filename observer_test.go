@@ -0,0 +1,89 @@
+package blockmatrix
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/require"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordingObserver records every event it is notified of.
+type recordingObserver struct {
+	mu         sync.Mutex
+	added      []int
+	erased     []int
+	failures   []string
+	operations []string
+}
+
+func (r *recordingObserver) BlockAdded(blockNum int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.added = append(r.added, blockNum)
+}
+
+func (r *recordingObserver) BlockErased(blockNum int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.erased = append(r.erased, blockNum)
+}
+
+func (r *recordingObserver) ValidationFailed(reason string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.failures = append(r.failures, reason)
+}
+
+func (r *recordingObserver) OperationDone(operation string, duration time.Duration, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.operations = append(r.operations, fmt.Sprintf("%s %v", operation, err))
+}
+
+func TestWithObserver(t *testing.T) {
+	observer := &recordingObserver{}
+	db := newTestDB(t)
+	bm, err := NewWithLevelDB(db, WithObserver(observer))
+	require.NoError(t, err)
+
+	require.NoError(t, bm.AddBlock("key1", []byte{1}))
+	require.NoError(t, bm.AddBlock("key2", []byte{2}))
+	require.Equal(t, []int{1, 2}, observer.added)
+	require.Equal(t, []string{"AddBlock <nil>", "AddBlock <nil>"}, observer.operations)
+
+	// failed adds are timed too
+	tooLong := make([]byte, DefaultMaxKeyLength+1)
+	err = bm.AddBlock(string(tooLong), []byte{3})
+	require.ErrorIs(t, err, ErrKeyTooLong)
+	require.Equal(t, []int{1, 2}, observer.added)
+	require.Equal(t, fmt.Sprintf("AddBlock %v", err), observer.operations[2])
+
+	require.NoError(t, bm.EraseBlock("key2"))
+	require.Equal(t, []int{2}, observer.erased)
+
+	// a second erase in the same row and column is rejected by the validity policy but the block remains erased
+	require.NoError(t, bm.AddBlock("key3", []byte{3}))
+	require.NoError(t, bm.AddBlock("key4", []byte{4}))
+	require.ErrorIs(t, bm.EraseBlocks("key3", "key4"), ErrInvalidErase)
+	require.Equal(t, []int{2, 3, 4}, observer.erased)
+	require.Equal(t, []string{"erase rejected by the validity policy"}, observer.failures)
+
+	observer.operations = nil
+	ok, err := bm.IsValid()
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Len(t, observer.failures, 1)
+	require.Equal(t, []string{"IsValid <nil>"}, observer.operations)
+
+	corruptBlockData(t, db, 1, []byte("corrupted"))
+	ok, err = bm.IsValid()
+	require.NoError(t, err)
+	require.False(t, ok)
+	require.Equal(t, "hash mismatch in blocks [1]", observer.failures[1])
+
+	tx := bm.Begin()
+	require.NoError(t, tx.AddBlock("tx", []byte("tx")))
+	require.NoError(t, tx.Commit())
+	require.Equal(t, []int{1, 2, 3, 4, 5}, observer.added)
+}
@@ -0,0 +1,263 @@
+package blockmatrix
+
+import (
+	"hash"
+	"io"
+	"time"
+)
+
+// Option configures optional behavior of a BlockMatrix when passed to New.
+type Option func(b *BlockMatrix)
+
+// WithJSONIndent pretty-prints JSON exports, indenting each nested level with the given string (e.g. "  " or "\t").  By
+// default exports are compact.
+func WithJSONIndent(indent string) Option {
+	return func(b *BlockMatrix) {
+		b.jsonIndent = indent
+	}
+}
+
+// WithKeyTransform maps every application key through fn before it is written to or looked up in the database, for
+// example to hash long keys down to a fixed length.  The original key is stored alongside the transformed key so that
+// AddBlock returns ErrKeyCollision if two different keys are transformed to the same database key.
+func WithKeyTransform(fn func(string) string) Option {
+	return func(b *BlockMatrix) {
+		b.keyTransform = fn
+	}
+}
+
+// WithKeyNormalizer maps every key through fn on every write and lookup, so that keys fn maps to the same string, for
+// example "Key" and "key" with strings.ToLower, refer to the same block.  Unlike WithKeyTransform the normalized key is
+// the key: two inputs that normalize to the same key do not collide, adding a block with the second input is the same
+// as adding a block with the first one again, which points the key at the new block.  Keys are reported in their
+// normalized form.  fn must return its input unchanged for a normalized key.  By default keys are not normalized.
+func WithKeyNormalizer(fn func(string) string) Option {
+	return func(b *BlockMatrix) {
+		b.keyNormalizer = fn
+	}
+}
+
+// WithJournal writes a length-prefixed record of every database write to w before the write is applied, independent of
+// leveldb's own write-ahead log.  The journal can be shipped to cold storage and replayed with RecoverFromJournal.
+func WithJournal(w io.Writer) Option {
+	return func(b *BlockMatrix) {
+		b.journal = w
+	}
+}
+
+// WithSelfTest makes New check that every block number up to the capacity of the block matrix is placed in a unique,
+// in bounds, non-diagonal cell that is listed in its row and column, see CrossConsistencyCheck, returning an error if
+// the placement arithmetic contradicts itself.
+func WithSelfTest() Option {
+	return func(b *BlockMatrix) {
+		b.selfTest = true
+	}
+}
+
+// WithWriteRateLimit paces AddBlock, AddBlockWithMetadata, AddBlockContext, AddBlocks, and AddBlocksContext so that at
+// most opsPerSec blocks are added per second, evenly spaced, giving leveldb's compaction time to keep up with bulk
+// ingest instead of stalling writes or growing its memory without bound.  AddBlocks waits for one slot per block.  The
+// wait happens before the block matrix is locked and is abandoned when the context of AddBlockContext or
+// AddBlocksContext is done.  A limit of 0 or less disables pacing, which is the default.
+func WithWriteRateLimit(opsPerSec int) Option {
+	return func(b *BlockMatrix) {
+		b.writeLimiter = nil
+		if opsPerSec > 0 {
+			b.writeLimiter = newRateLimiter(opsPerSec)
+		}
+	}
+}
+
+// WithLogger sends structured log lines to logger: added blocks with their coordinates at debug level, growth of the
+// block matrix at info level, erases rejected by the validity policy and hash mismatches found by validation with the
+// blocks, rows, and columns involved at warn level, and failed adds with their key at error level.  By default nothing
+// is logged.
+func WithLogger(logger Logger) Option {
+	return func(b *BlockMatrix) {
+		b.logger = logger
+	}
+}
+
+// WithObserver notifies observer of added and erased blocks, failed validations, and the duration of every AddBlock
+// and IsValid call, for example to export them as metrics.  By default nothing is observed.
+func WithObserver(observer Observer) Option {
+	return func(b *BlockMatrix) {
+		b.observer = observer
+	}
+}
+
+// WithTracer records a span for each block matrix operation with the given Tracer.  By default no spans are recorded.
+func WithTracer(tracer Tracer) Option {
+	return func(b *BlockMatrix) {
+		b.tracer = tracer
+	}
+}
+
+// WithMaxSize caps the dimension of the block matrix at n.  The block matrix grows as usual until it is n x n, after
+// which adding a block that would grow it further returns ErrMaxSizeExceeded without writing anything.  By default the
+// size is unbounded.
+func WithMaxSize(n int) Option {
+	return func(b *BlockMatrix) {
+		b.maxSize = n
+	}
+}
+
+// WithMaxKeyLength limits the database keys blocks are added with to n bytes, after the key is normalized and
+// transformed.  leveldb has no hard limit on key length but every key is kept in its index blocks and compared on every
+// lookup, so very long keys degrade the whole database.  Adding a block with a longer key returns ErrKeyTooLong without
+// writing anything.  The default is DefaultMaxKeyLength, 0 removes the limit.  Keys that are legitimately long can be
+// shortened with WithKeyTransform.
+func WithMaxKeyLength(n int) Option {
+	return func(b *BlockMatrix) {
+		b.maxKeyLength = n
+	}
+}
+
+// WithValidationConcurrency limits the goroutines that read and check blocks during validation, by IsValid, Validate,
+// CorruptBlocks, and the validation run by WithBackgroundValidation, to n, so that a scan of a large block matrix does
+// not starve other traffic to the database.  With n of 1 blocks are checked one at a time.  The default, or n of 0,
+// is GOMAXPROCS goroutines.
+func WithValidationConcurrency(n int) Option {
+	return func(b *BlockMatrix) {
+		b.validationConcurrency = n
+	}
+}
+
+// WithRefuseAliasedErase makes erasing or deleting a block that has aliases, see AddAlias, fail with an error wrapping
+// ErrHasAliases without changing anything.  By default the aliases are removed along with the key.
+func WithRefuseAliasedErase() Option {
+	return func(b *BlockMatrix) {
+		b.refuseAliasedErase = true
+	}
+}
+
+// WithInfoCache keeps the decoded block matrix info in memory so that operations do not read and decode it from the
+// database every time.  The cache is updated whenever the block matrix writes the info, so it is only safe if nothing
+// else writes to the database while the block matrix is open, including another block matrix over the same database.
+// By default the info is read from the database on every use.
+func WithInfoCache() Option {
+	return func(b *BlockMatrix) {
+		b.infoCache = &infoCache{}
+	}
+}
+
+// WithHasher hashes blocks, rows, columns, and the root hash with the hash function created by newHash instead of
+// SHA-256, for example sha512.New.  The hash function is recorded in the block matrix info when the block matrix is
+// created and New returns ErrHasherMismatch if the block matrix is opened with a different one.  HMACs always use
+// SHA-256.  Bundles and frozen block matrices are verified without a block matrix and only with SHA-256, so Freeze and
+// Bundle.Verify return an error for a block matrix with another hash function.
+func WithHasher(newHash func() hash.Hash) Option {
+	return func(b *BlockMatrix) {
+		b.hasher = newHash
+	}
+}
+
+// WithCommitmentHashLength truncates the row, column, and root hashes of the commitments returned by Commitment to
+// their first n bytes to save bandwidth when anchoring them, for example in a blockchain transaction.  The hashes kept
+// by the block matrix are not truncated, and Bundle.Verify truncates the row and column hashes it recomputes to the
+// length recorded in the commitment.  Verification bundles still carry full block hashes since the row and column
+// hashes are computed over them.  Truncation weakens the commitment: finding a different row or column with the same
+// n byte hash takes about 2^(8n) attempts and finding any two rows or columns with the same hash about 2^(4n), so 16
+// bytes give 64 bit collision resistance instead of 128.  The root of a truncated commitment is computed over its
+// truncated row and column hashes, so Bundle.Verify checks it like the root of a full commitment.  n of 0, or at least
+// the length of the hashes, leaves commitments untruncated, which is the default.  New returns an error if n is
+// negative.
+func WithCommitmentHashLength(n int) Option {
+	return func(b *BlockMatrix) {
+		b.commitmentHashLength = n
+	}
+}
+
+// WithValidityPolicy replaces the default rule that an erase must change exactly one row hash and one column hash with
+// the given policy.
+func WithValidityPolicy(policy ValidityPolicy) Option {
+	return func(b *BlockMatrix) {
+		b.validityPolicy = policy
+	}
+}
+
+// WithBackgroundValidation validates the block matrix every interval in a background goroutine and passes the result to
+// fn.  Background validation can be paused with PauseBackground and is stopped by Close.
+func WithBackgroundValidation(interval time.Duration, fn func(result *ValidationResult, err error)) Option {
+	return func(b *BlockMatrix) {
+		b.backgroundTasks = append(b.backgroundTasks, backgroundTask{
+			interval: interval,
+			run: func() {
+				fn(b.Validate())
+			},
+		})
+	}
+}
+
+// WithHMAC stores an HMAC-SHA256 of every block's data keyed with key and verifies it whenever a block is read,
+// returning ErrAuthenticationFailed if it does not match, so that anyone able to write to the database but without the
+// key cannot forge a block.  Row and column hashes remain over the plain block hashes so commitments and proofs can be
+// verified without the key.  Blocks written without the option have no MAC and fail authentication.
+func WithHMAC(key []byte) Option {
+	return func(b *BlockMatrix) {
+		b.hmacKey = key
+	}
+}
+
+// WithHashCollisionCheck makes every write of a block with data check that no other block has the same hash but
+// different data, which can only happen when the hash function is broken or truncated.  An index from block hashes to
+// block numbers is kept in the database for the check.  On a collision handler is called with the hash and the number
+// of the block that already has it and the error it returns, if any, aborts the write before anything is written.  A
+// nil handler aborts the write with an error wrapping ErrHashCollision.  Only blocks written while the option is set are
+// indexed.  By default collisions are not checked.
+func WithHashCollisionCheck(handler func(hash []byte, existing int) error) Option {
+	return func(b *BlockMatrix) {
+		b.hashCollisionCheck = true
+		b.hashCollisionHandler = handler
+	}
+}
+
+// WithCodecs encodes the data of every block written with each codec in order, for example to compress and then
+// encrypt it, and decodes it in reverse order when the block is read.  Stored blocks record the names of the codecs
+// they were written with and are decoded with that chain, looking the codecs up by name, so blocks written before the
+// option was set or with the codecs in another order remain readable as long as every codec they name is configured.
+// Block hashes and MACs are over the plain data.  By default block data is stored as is.
+func WithCodecs(codecs ...Codec) Option {
+	return func(b *BlockMatrix) {
+		b.codecs = codecs
+	}
+}
+
+// WithBlockEncoding stores the blocks of a new block matrix with the given encoding, for example JSONBlockEncoding for
+// block matrices read by tools that expect JSON, instead of BinaryBlockEncoding.  The encoding is recorded in the info
+// and an existing block matrix is always opened with the encoding it was created with, so the option only has to be
+// passed to open a block matrix created with an encoding that is not built in.
+func WithBlockEncoding(encoding BlockEncoding) Option {
+	return func(b *BlockMatrix) {
+		b.blockEncoding = encoding
+	}
+}
+
+// WithValidateOnImport makes imports check each block's hash against its data as it is imported and, once every block
+// is imported, check the imported row and column hashes against the blocks.  The import is rejected with an ImportError
+// identifying the first block, row, or column that does not match.  By default imports trust the input.
+func WithValidateOnImport() Option {
+	return func(b *BlockMatrix) {
+		b.validateOnImport = true
+	}
+}
+
+// WithLazyEmptyBlocks stops growth from writing an empty block to every new slot of the block matrix.  Slots are only
+// written when a block is added to them and slots that were never written are read and hashed as empty blocks, so the
+// block matrix and its hashes are the same as without the option.  RepairSlots materializes the missing slots and
+// VerifyReconstructable, which derives the size from the slots, only succeeds once every slot is materialized.
+func WithLazyEmptyBlocks() Option {
+	return func(b *BlockMatrix) {
+		b.lazyEmptyBlocks = true
+	}
+}
+
+// WithClock stamps the blocks written to the block matrix with the time returned by clock instead of the current time.
+// Block timestamps are part of the block hashes, so block matrices built from the same data, for example with
+// NewWithData or AddBlocks, only have the same hashes and root hash if their blocks are stamped with the same times.  A
+// clock returning a fixed time makes them identical.
+func WithClock(clock func() time.Time) Option {
+	return func(b *BlockMatrix) {
+		b.clock = clock
+	}
+}
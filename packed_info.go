@@ -0,0 +1,154 @@
+package blockmatrix
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+)
+
+// packedInfoMagic prefixes block matrix info stored in the packed encoding.  Info without the prefix is JSON.
+var packedInfoMagic = []byte("BMPI")
+
+// packedInfo is the header of the packed info encoding.  The packed encoding is the magic, the uvarint length of the
+// JSON header, the header, and then every row hash followed by every column hash, each HashLength bytes wide.  Storing
+// the hashes as one fixed width blob instead of base64 in JSON makes rewriting the info after every write cheaper, and a
+// store that supports partial writes could rewrite only the segments of the changed row and column.
+type packedInfo struct {
+	Size          int    `json:"size"`
+	BlockCount    int    `json:"block_count"`
+	ID            string `json:"id,omitempty"`
+	Hasher        string `json:"hasher,omitempty"`
+	HashLength    int    `json:"hash_length"`
+	BlockEncoding string `json:"block_encoding,omitempty"`
+}
+
+// encodeInfo serializes the info for storage.  The packed encoding is used unless the row and column hashes do not all
+// have the same length, in which case the info is stored as JSON.
+func encodeInfo(info *BlockMatrixInfo) ([]byte, error) {
+	hashLength, ok := uniformHashLength(info)
+	if !ok {
+		return json.Marshal(info)
+	}
+
+	header, err := json.Marshal(packedInfo{
+		Size:          info.Size,
+		BlockCount:    info.BlockCount,
+		ID:            info.ID,
+		Hasher:        info.Hasher,
+		HashLength:    hashLength,
+		BlockEncoding: info.BlockEncoding,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	buf := make([]byte, 0, len(packedInfoMagic)+binary.MaxVarintLen64+len(header)+2*info.Size*hashLength)
+	buf = append(buf, packedInfoMagic...)
+	buf = appendUvarint(buf, uint64(len(header)))
+	buf = append(buf, header...)
+	for _, hash := range info.Rows {
+		buf = append(buf, hash...)
+	}
+	for _, hash := range info.Cols {
+		buf = append(buf, hash...)
+	}
+
+	return buf, nil
+}
+
+// uniformHashLength returns the length shared by every row and column hash, and false if the lengths differ or the
+// number of hashes does not match the size.
+func uniformHashLength(info *BlockMatrixInfo) (int, bool) {
+	if len(info.Rows) != info.Size || len(info.Cols) != info.Size || info.Size == 0 {
+		return 0, false
+	}
+
+	hashLength := len(info.Rows[0])
+	for i := 0; i < info.Size; i++ {
+		if len(info.Rows[i]) != hashLength || len(info.Cols[i]) != hashLength {
+			return 0, false
+		}
+	}
+
+	return hashLength, true
+}
+
+// decodeInfo deserializes stored info in either the packed or the JSON encoding.
+func decodeInfo(data []byte) (*BlockMatrixInfo, error) {
+	info := &BlockMatrixInfo{}
+	if !bytes.HasPrefix(data, packedInfoMagic) {
+		if err := json.Unmarshal(data, info); err != nil {
+			return nil, err
+		}
+
+		return info, nil
+	}
+
+	header, hashes, err := decodePackedHeader(data)
+	if err != nil {
+		return nil, err
+	}
+
+	if header.Size < 0 || header.HashLength < 0 || len(hashes) != 2*header.Size*header.HashLength {
+		return nil, fmt.Errorf("packed block matrix info has %d bytes of hashes for size %d", len(hashes), header.Size)
+	}
+
+	info.Size = header.Size
+	info.BlockCount = header.BlockCount
+	info.ID = header.ID
+	info.Hasher = header.Hasher
+	info.BlockEncoding = header.BlockEncoding
+	info.Rows = make([][]byte, header.Size)
+	info.Cols = make([][]byte, header.Size)
+	for i := 0; i < header.Size; i++ {
+		info.Rows[i] = append([]byte{}, hashes[i*header.HashLength:(i+1)*header.HashLength]...)
+		offset := (header.Size + i) * header.HashLength
+		info.Cols[i] = append([]byte{}, hashes[offset:offset+header.HashLength]...)
+	}
+
+	return info, nil
+}
+
+// decodeInfoHeader deserializes only the scalar fields of stored info, leaving the row and column hashes undecoded if
+// the info is packed.  The returned header's HashLength is not set for JSON info.
+func decodeInfoHeader(data []byte) (*packedInfo, error) {
+	if !bytes.HasPrefix(data, packedInfoMagic) {
+		info, err := decodeInfo(data)
+		if err != nil {
+			return nil, err
+		}
+
+		return infoHeaderOf(info), nil
+	}
+
+	header, _, err := decodePackedHeader(data)
+	return header, err
+}
+
+// infoHeaderOf returns the scalar fields of info.  The HashLength is not set.
+func infoHeaderOf(info *BlockMatrixInfo) *packedInfo {
+	return &packedInfo{
+		Size:          info.Size,
+		BlockCount:    info.BlockCount,
+		ID:            info.ID,
+		Hasher:        info.Hasher,
+		BlockEncoding: info.BlockEncoding,
+	}
+}
+
+// decodePackedHeader deserializes the header of packed info and returns it with the bytes of the hashes that follow.
+func decodePackedHeader(data []byte) (*packedInfo, []byte, error) {
+	data = data[len(packedInfoMagic):]
+	headerLength, n := binary.Uvarint(data)
+	if n <= 0 || uint64(len(data)-n) < headerLength {
+		return nil, nil, fmt.Errorf("packed block matrix info header is malformed")
+	}
+
+	header := &packedInfo{}
+	if err := json.Unmarshal(data[n:n+int(headerLength)], header); err != nil {
+		return nil, nil, fmt.Errorf("packed block matrix info header is malformed: %w", err)
+	}
+
+	return header, data[n+int(headerLength):], nil
+}
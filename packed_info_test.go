@@ -0,0 +1,92 @@
+package blockmatrix
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestPackedInfo(t *testing.T) {
+	db := newTestDB(t)
+	bm, err := NewWithLevelDB(db)
+	require.NoError(t, err)
+	require.NoError(t, createTestBlocks(bm, 7))
+
+	stored, err := db.Get(InfoKey, nil)
+	require.NoError(t, err)
+	require.Equal(t, packedInfoMagic, stored[:len(packedInfoMagic)])
+
+	info, err := bm.GetBlockMatrixInfo()
+	require.NoError(t, err)
+	decoded, err := decodeInfo(stored)
+	require.NoError(t, err)
+	require.Equal(t, info, decoded)
+
+	// info stored as JSON before the packed encoding is still read
+	legacy, err := json.Marshal(info)
+	require.NoError(t, err)
+	require.NoError(t, db.Put(InfoKey, legacy, nil))
+	decoded, err = bm.GetBlockMatrixInfo()
+	require.NoError(t, err)
+	require.Equal(t, info, decoded)
+
+	// hashes of different lengths can't be packed so they are stored as JSON
+	info.Rows[1] = []byte("short")
+	encoded, err := encodeInfo(info)
+	require.NoError(t, err)
+	require.Equal(t, byte('{'), encoded[0])
+	decoded, err = decodeInfo(encoded)
+	require.NoError(t, err)
+	require.Equal(t, info, decoded)
+
+	_, err = decodeInfo(stored[:len(stored)-1])
+	require.Error(t, err)
+}
+
+// BenchmarkInfoEncoding reports the number of bytes written to store the info of a block matrix of size 300, which
+// every AddBlock rewrites after updating one row and one column hash.
+func BenchmarkInfoEncoding(b *testing.B) {
+	const size = 300
+
+	info := &BlockMatrixInfo{
+		Size:       size,
+		BlockCount: capacity(size - 1),
+		Rows:       make([][]byte, size),
+		Cols:       make([][]byte, size),
+	}
+	for i := 0; i < size; i++ {
+		row := sha256.Sum256([]byte(fmt.Sprint("row", i)))
+		col := sha256.Sum256([]byte(fmt.Sprint("col", i)))
+		info.Rows[i] = row[:]
+		info.Cols[i] = col[:]
+	}
+
+	encodings := map[string]func(*BlockMatrixInfo) ([]byte, error){
+		"json": func(info *BlockMatrixInfo) ([]byte, error) {
+			return json.Marshal(info)
+		},
+		"packed": encodeInfo,
+	}
+
+	for name, encode := range encodings {
+		b.Run(name, func(b *testing.B) {
+			written := 0
+			for i := 0; i < b.N; i++ {
+				row, col := locateBlock(info.BlockCount + 1 + i%size)
+				hash := sha256.Sum256([]byte(fmt.Sprint(i)))
+				info.Rows[row] = hash[:]
+				info.Cols[col] = hash[:]
+
+				bytes, err := encode(info)
+				if err != nil {
+					b.Fatal(err)
+				}
+				written += len(bytes)
+			}
+
+			b.ReportMetric(float64(written)/float64(b.N), "bytes/op")
+		})
+	}
+}
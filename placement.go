@@ -0,0 +1,87 @@
+package blockmatrix
+
+import "fmt"
+
+// checkPlacement verifies that every block number that fits in a block matrix of the given size is located in a unique
+// cell that is inside the matrix and not on the diagonal.
+func checkPlacement(size int) error {
+	placed := make(map[[2]int]int)
+	for blockNum := 1; blockNum <= capacity(size); blockNum++ {
+		i, j := locateBlock(blockNum)
+		if i < 0 || i >= size || j < 0 || j >= size {
+			return fmt.Errorf("block %d located at (%d, %d) outside of block matrix of size %d", blockNum, i, j, size)
+		}
+
+		if i == j {
+			return fmt.Errorf("block %d located on the diagonal at (%d, %d)", blockNum, i, j)
+		}
+
+		if other, ok := placed[[2]int{i, j}]; ok {
+			return fmt.Errorf("blocks %d and %d both located at (%d, %d)", other, blockNum, i, j)
+		}
+
+		placed[[2]int{i, j}] = blockNum
+	}
+
+	return nil
+}
+
+// CrossConsistencyCheck verifies that the row and column numbering of the block matrix agree with the placement of its
+// blocks: every block up to the capacity of the block matrix must be listed in the block numbers of both the row and
+// the column of the cell it is located in, and nowhere else.  An error describing the first disagreement is returned.
+func (b *BlockMatrix) CrossConsistencyCheck() error {
+	info, err := b.GetBlockMatrixInfo()
+	if err != nil {
+		return err
+	}
+
+	return checkCrossConsistency(info.Size)
+}
+
+// checkCrossConsistency verifies that rowBlockNumbers and columnBlockNumbers list exactly the blocks locateBlock places
+// in each row and column of a block matrix of the given size.
+func checkCrossConsistency(size int) error {
+	rows := make([]map[int]bool, size)
+	cols := make([]map[int]bool, size)
+	for i := 0; i < size; i++ {
+		rowBlocks, err := rowBlockNumbers(i, size)
+		if err != nil {
+			return err
+		}
+
+		colBlocks, err := columnBlockNumbers(i, size)
+		if err != nil {
+			return err
+		}
+
+		rows[i] = make(map[int]bool, len(rowBlocks))
+		for _, blockNum := range rowBlocks {
+			rows[i][blockNum] = true
+		}
+
+		cols[i] = make(map[int]bool, len(colBlocks))
+		for _, blockNum := range colBlocks {
+			cols[i][blockNum] = true
+		}
+
+		if len(rows[i]) != size-1 || len(cols[i]) != size-1 {
+			return fmt.Errorf("row %d has %d blocks and column %d has %d, expected %d each", i, len(rows[i]), i,
+				len(cols[i]), size-1)
+		}
+	}
+
+	for blockNum := 1; blockNum <= capacity(size); blockNum++ {
+		i, j := locateBlock(blockNum)
+		if !rows[i][blockNum] {
+			return fmt.Errorf("block %d located at (%d, %d) is not in row %d", blockNum, i, j, i)
+		}
+
+		if !cols[j][blockNum] {
+			return fmt.Errorf("block %d located at (%d, %d) is not in column %d", blockNum, i, j, j)
+		}
+	}
+
+	// every row and column lists size-1 blocks and each block was found in its own row and column, so no row or column
+	// can list a block from another one
+	return nil
+}
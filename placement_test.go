@@ -0,0 +1,34 @@
+package blockmatrix
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestWithSelfTest(t *testing.T) {
+	db := newTestDB(t)
+	bm, err := NewWithLevelDB(db, WithSelfTest())
+	require.NoError(t, err)
+	require.NoError(t, createTestBlocks(bm, 25))
+
+	_, err = NewWithLevelDB(db, WithSelfTest())
+	require.NoError(t, err)
+}
+
+func TestCheckPlacement(t *testing.T) {
+	for size := 1; size <= 30; size++ {
+		require.NoError(t, checkPlacement(size))
+	}
+}
+
+func TestCrossConsistencyCheck(t *testing.T) {
+	for size := 1; size <= 30; size++ {
+		require.NoError(t, checkCrossConsistency(size), "size %d", size)
+	}
+
+	bm, err := New(NewMemStore())
+	require.NoError(t, err)
+	require.NoError(t, bm.CrossConsistencyCheck())
+	require.NoError(t, createTestBlocks(bm, 40))
+	require.NoError(t, bm.CrossConsistencyCheck())
+}
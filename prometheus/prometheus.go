@@ -0,0 +1,86 @@
+// Package prometheus exports the events of a block matrix as Prometheus metrics.  Pass an Observer to
+// blockmatrix.WithObserver:
+//
+//	observer, err := prometheus.NewObserver(prom.DefaultRegisterer)
+//	if err != nil {
+//		return err
+//	}
+//
+//	bm, err := blockmatrix.New(store, blockmatrix.WithObserver(observer))
+package prometheus
+
+import (
+	"github.com/PM-Master/blockmatrix-go"
+	"github.com/prometheus/client_golang/prometheus"
+	"time"
+)
+
+// Observer is a blockmatrix.Observer that counts added and erased blocks and failed validations, and records the
+// duration of AddBlock and IsValid calls in a histogram labeled by operation and result.
+type Observer struct {
+	blocksAdded        prometheus.Counter
+	blocksErased       prometheus.Counter
+	validationFailures prometheus.Counter
+	durations          *prometheus.HistogramVec
+}
+
+var _ blockmatrix.Observer = (*Observer)(nil)
+
+// NewObserver creates an Observer and registers its metrics with registerer.  The metrics are named
+// blockmatrix_blocks_added_total, blockmatrix_blocks_erased_total, blockmatrix_validation_failures_total, and
+// blockmatrix_operation_duration_seconds.  The failure reason is not a label since it names block, row, and column
+// indices, which would make the number of series unbounded.
+func NewObserver(registerer prometheus.Registerer) (*Observer, error) {
+	o := &Observer{
+		blocksAdded: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: "blockmatrix",
+			Name:      "blocks_added_total",
+			Help:      "Number of blocks added.",
+		}),
+		blocksErased: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: "blockmatrix",
+			Name:      "blocks_erased_total",
+			Help:      "Number of blocks erased or deleted.",
+		}),
+		validationFailures: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: "blockmatrix",
+			Name:      "validation_failures_total",
+			Help:      "Number of failed validations and erases rejected by the validity policy.",
+		}),
+		durations: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "blockmatrix",
+			Name:      "operation_duration_seconds",
+			Help:      "Duration of block matrix operations.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"operation", "result"}),
+	}
+
+	for _, collector := range []prometheus.Collector{o.blocksAdded, o.blocksErased, o.validationFailures, o.durations} {
+		if err := registerer.Register(collector); err != nil {
+			return nil, err
+		}
+	}
+
+	return o, nil
+}
+
+func (o *Observer) BlockAdded(int) {
+	o.blocksAdded.Inc()
+}
+
+func (o *Observer) BlockErased(int) {
+	o.blocksErased.Inc()
+}
+
+func (o *Observer) ValidationFailed(string) {
+	o.validationFailures.Inc()
+}
+
+func (o *Observer) OperationDone(operation string, duration time.Duration, err error) {
+	result := "ok"
+	if err != nil {
+		result = "error"
+	}
+
+	o.durations.WithLabelValues(operation, result).Observe(duration.Seconds())
+}
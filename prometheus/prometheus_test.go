@@ -0,0 +1,34 @@
+package prometheus
+
+import (
+	"github.com/PM-Master/blockmatrix-go"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestObserver(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	observer, err := NewObserver(registry)
+	require.NoError(t, err)
+
+	bm, err := blockmatrix.New(blockmatrix.NewMemStore(), blockmatrix.WithObserver(observer))
+	require.NoError(t, err)
+	require.NoError(t, bm.AddBlock("key1", []byte{1}))
+	require.NoError(t, bm.AddBlock("key2", []byte{2}))
+	require.NoError(t, bm.EraseBlock("key1"))
+	require.Error(t, bm.EraseBlock("missing"))
+	ok, err := bm.IsValid()
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	require.Equal(t, float64(2), testutil.ToFloat64(observer.blocksAdded))
+	require.Equal(t, float64(1), testutil.ToFloat64(observer.blocksErased))
+	require.Equal(t, float64(0), testutil.ToFloat64(observer.validationFailures))
+	require.Equal(t, 2, testutil.CollectAndCount(observer.durations))
+
+	// the metrics can only be registered once
+	_, err = NewObserver(registry)
+	require.Error(t, err)
+}
@@ -0,0 +1,163 @@
+package blockmatrix
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"hash"
+)
+
+// VerifyExternalProof checks a proof that leaf, the hash of a block, contributes to expectedRoot, the hash of the
+// block's row or column, without a BlockMatrix.  A row or column hash is a one level tree over the hashes of the blocks
+// in the row or column: the hash function is applied to the concatenation of the block hashes in leaf order, see
+// RowLeafOrder and ColumnLeafOrder, without separators, length prefixes, or padding.  siblings are the hashes of the
+// other blocks in leaf order, and index is the position of leaf among all of them, so siblings[:index] precede leaf and
+// siblings[index:] follow it.  A nil hasher is SHA-256, the hash function of block matrices not created WithHasher.
+// It returns false if index is outside [0, len(siblings)] or the recomputed hash is not expectedRoot.
+func VerifyExternalProof(leaf []byte, siblings [][]byte, index int, expectedRoot []byte,
+	hasher func() hash.Hash) bool {
+	if index < 0 || index > len(siblings) {
+		return false
+	}
+
+	if hasher == nil {
+		hasher = sha256.New
+	}
+
+	h := hasher()
+	for _, sibling := range siblings[:index] {
+		h.Write(sibling)
+	}
+	h.Write(leaf)
+	for _, sibling := range siblings[index:] {
+		h.Write(sibling)
+	}
+
+	return bytes.Equal(h.Sum(nil), expectedRoot)
+}
+
+// Proof shows that a block contributes to the hashes of its row and column.  The row and column hashes are recomputed
+// from the block's hash and the hashes of the other blocks in its row and column, see VerifyExternalProof, so a third
+// party holding only the row and column hashes, for example from a Commitment, can check it without the database.
+type Proof struct {
+	// BlockNumber is the number of the block the proof is for
+	BlockNumber int `json:"block_number"`
+	// Row is the index of the row the block is in
+	Row int `json:"row"`
+	// Col is the index of the column the block is in
+	Col int `json:"col"`
+	// BlockHash is the hash of the block
+	BlockHash []byte `json:"block_hash"`
+	// RowIndex is the position of the block among the blocks of its row in leaf order
+	RowIndex int `json:"row_index"`
+	// RowSiblings stores the hashes of the other blocks in the block's row, in leaf order
+	RowSiblings [][]byte `json:"row_siblings"`
+	// ColumnIndex is the position of the block among the blocks of its column in leaf order
+	ColumnIndex int `json:"column_index"`
+	// ColumnSiblings stores the hashes of the other blocks in the block's column, in leaf order
+	ColumnSiblings [][]byte `json:"column_siblings"`
+	// Hasher identifies the hash function of the block matrix, it is empty for SHA-256
+	Hasher string `json:"hasher,omitempty"`
+}
+
+// InclusionProof returns a proof that the block associated with key contributes to the current hashes of its row and
+// column.  Unlike VerificationBundle the proof does not contain the block's data, only its hash.
+func (b *BlockMatrix) InclusionProof(key string) (*Proof, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	blockNum, err := b.BlockNumber(key)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := b.GetBlockMatrixInfo()
+	if err != nil {
+		return nil, err
+	}
+
+	row, col := locateBlock(blockNum)
+	proof := &Proof{
+		BlockNumber: blockNum,
+		Row:         row,
+		Col:         col,
+		Hasher:      info.Hasher,
+	}
+
+	rowBlocks, err := rowBlockNumbers(row, info.Size)
+	if err != nil {
+		return nil, err
+	}
+
+	if proof.BlockHash, proof.RowIndex, proof.RowSiblings, err = b.proofHashes(blockNum, rowBlocks); err != nil {
+		return nil, err
+	}
+
+	colBlocks, err := columnBlockNumbers(col, info.Size)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, proof.ColumnIndex, proof.ColumnSiblings, err = b.proofHashes(blockNum, colBlocks); err != nil {
+		return nil, err
+	}
+
+	return proof, nil
+}
+
+// proofHashes returns the hash of the block with the given number, its position in blockNums, and the hashes of the
+// other blocks in blockNums.
+func (b *BlockMatrix) proofHashes(blockNum int, blockNums []int) ([]byte, int, [][]byte, error) {
+	hashes, err := b.blockHashes(blockNums)
+	if err != nil {
+		return nil, 0, nil, err
+	}
+
+	index := leafIndex(blockNums, blockNum)
+	if index < 0 {
+		return nil, 0, nil, fmt.Errorf("block %d is not in its own row or column", blockNum)
+	}
+
+	siblings := append(append(make([][]byte, 0, len(hashes)-1), hashes[:index]...), hashes[index+1:]...)
+	return hashes[index], index, siblings, nil
+}
+
+// VerifyProof checks that the block hash of the proof contributes to rowHash and colHash, the hashes of the block's row
+// and column.  The block's row, column, and positions in them must match its block number, and the number of siblings
+// must match between the row and the column.  Proofs of block matrices created WithHasher are not verified since the
+// hash function cannot be recovered from its fingerprint, use VerifyExternalProof with the hash function instead.
+func VerifyProof(proof *Proof, rowHash, colHash []byte) bool {
+	if proof == nil || proof.Hasher != "" || len(proof.RowSiblings) != len(proof.ColumnSiblings) {
+		return false
+	}
+
+	size := len(proof.RowSiblings) + 2
+	if row, col := locateBlock(proof.BlockNumber); proof.BlockNumber < 1 || row != proof.Row || col != proof.Col ||
+		row >= size || col >= size {
+		return false
+	}
+
+	rowBlocks, err := rowBlockNumbers(proof.Row, size)
+	if err != nil || leafIndex(rowBlocks, proof.BlockNumber) != proof.RowIndex {
+		return false
+	}
+
+	colBlocks, err := columnBlockNumbers(proof.Col, size)
+	if err != nil || leafIndex(colBlocks, proof.BlockNumber) != proof.ColumnIndex {
+		return false
+	}
+
+	return VerifyExternalProof(proof.BlockHash, proof.RowSiblings, proof.RowIndex, rowHash, nil) &&
+		VerifyExternalProof(proof.BlockHash, proof.ColumnSiblings, proof.ColumnIndex, colHash, nil)
+}
+
+// leafIndex returns the position of blockNum in blockNums, or -1 if it is not in it.
+func leafIndex(blockNums []int, blockNum int) int {
+	for i, num := range blockNums {
+		if num == blockNum {
+			return i
+		}
+	}
+
+	return -1
+}
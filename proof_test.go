@@ -0,0 +1,101 @@
+package blockmatrix
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestVerifyExternalProof(t *testing.T) {
+	leaf := func(s string) []byte {
+		hash := sha256.Sum256([]byte(s))
+		return hash[:]
+	}
+	root := func(s string) []byte {
+		bytes, err := hex.DecodeString(s)
+		require.NoError(t, err)
+		return bytes
+	}
+
+	// sha256(sha256("a") || sha256("b") || sha256("c"))
+	abc := root("3a050f1d08fb8581d3d72ae727651e981043de0d6ca8e744328758f716602beb")
+	a, b, c := leaf("a"), leaf("b"), leaf("c")
+
+	// the leaf first, between, and last with its siblings to the right, on both sides, and to the left
+	require.True(t, VerifyExternalProof(a, [][]byte{b, c}, 0, abc, nil))
+	require.True(t, VerifyExternalProof(b, [][]byte{a, c}, 1, abc, nil))
+	require.True(t, VerifyExternalProof(c, [][]byte{a, b}, 2, abc, sha256.New))
+
+	// a row or column with a single block hashes to the hash of its block hash
+	require.True(t, VerifyExternalProof(a, nil, 0,
+		root("bf5d3affb73efd2ec6c36ad3112dd933efed63c4e1cbffcfa88e2759c144f2d8"), nil))
+
+	require.False(t, VerifyExternalProof(a, [][]byte{b, c}, 1, abc, nil))
+	require.False(t, VerifyExternalProof(b, [][]byte{c, a}, 1, abc, nil))
+	require.False(t, VerifyExternalProof(leaf("d"), [][]byte{b, c}, 0, abc, nil))
+	require.False(t, VerifyExternalProof(a, [][]byte{b, c}, -1, abc, nil))
+	require.False(t, VerifyExternalProof(a, [][]byte{b, c}, 3, abc, nil))
+
+	// the row hashes of a block matrix verify with the block hashes in leaf order
+	bm, err := New(NewMemStore())
+	require.NoError(t, err)
+	require.NoError(t, createTestBlocks(bm, 9))
+	info, err := bm.GetBlockMatrixInfo()
+	require.NoError(t, err)
+
+	order, err := bm.RowLeafOrder(2)
+	require.NoError(t, err)
+	hashes, err := bm.blockHashes(order)
+	require.NoError(t, err)
+	for index := range hashes {
+		siblings := append(append([][]byte{}, hashes[:index]...), hashes[index+1:]...)
+		require.True(t, VerifyExternalProof(hashes[index], siblings, index, info.Rows[2], nil))
+	}
+}
+
+func TestInclusionProof(t *testing.T) {
+	bm, err := New(NewMemStore())
+	require.NoError(t, err)
+	require.NoError(t, createTestBlocks(bm, 12))
+	info, err := bm.GetBlockMatrixInfo()
+	require.NoError(t, err)
+
+	for _, key := range []string{"key1", "key2", "key7", "key12"} {
+		proof, err := bm.InclusionProof(key)
+		require.NoError(t, err)
+		block, err := bm.GetBlock(key)
+		require.NoError(t, err)
+		require.Equal(t, block.Hash, proof.BlockHash)
+		require.Len(t, proof.RowSiblings, info.Size-2)
+		require.True(t, VerifyProof(proof, info.Rows[proof.Row], info.Cols[proof.Col]), key)
+	}
+
+	proof, err := bm.InclusionProof("key5")
+	require.NoError(t, err)
+	rowHash, colHash := info.Rows[proof.Row], info.Cols[proof.Col]
+
+	// tampered block hash
+	tampered := *proof
+	tampered.BlockHash = NewBlock([]byte("tampered")).Hash
+	require.False(t, VerifyProof(&tampered, rowHash, colHash))
+
+	// the block moved to another position in its row
+	tampered = *proof
+	tampered.RowIndex = (proof.RowIndex + 1) % len(proof.RowSiblings)
+	require.False(t, VerifyProof(&tampered, rowHash, colHash))
+
+	// the row and column hashes swapped
+	require.False(t, VerifyProof(proof, colHash, rowHash))
+
+	// the proof no longer verifies once block 9, in the same row, changes
+	row, _ := locateBlock(9)
+	require.Equal(t, proof.Row, row)
+	require.NoError(t, bm.UpdateBlock("key9", []byte("updated")))
+	info, err = bm.GetBlockMatrixInfo()
+	require.NoError(t, err)
+	require.False(t, VerifyProof(proof, info.Rows[proof.Row], info.Cols[proof.Col]))
+
+	_, err = bm.InclusionProof("missing")
+	require.ErrorIs(t, err, ErrNotFound)
+}
@@ -0,0 +1,88 @@
+package blockmatrix
+
+import (
+	"context"
+	"fmt"
+)
+
+// quarantinePrefix prefixes the entries holding the original bytes of quarantined blocks.
+const quarantinePrefix = "quarantine:"
+
+func quarantineEntry(blockNum int) []byte {
+	return []byte(fmt.Sprintf("%s%d", quarantinePrefix, blockNum))
+}
+
+// Quarantine isolates every block whose stored hash does not match its data so the rest of the block matrix remains
+// queryable.  The corrupt block's bytes are moved to a quarantine entry, the block is replaced with an erased block, its
+// key is removed, and the affected row and column hashes are updated.  The numbers of the quarantined blocks are
+// returned, use QuarantinedBlock to inspect them.
+func (b *BlockMatrix) Quarantine() ([]int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	corrupt, err := b.corruptBlocks(context.Background())
+	if err != nil {
+		return nil, err
+	}
+
+	if len(corrupt) == 0 {
+		return corrupt, nil
+	}
+
+	keys, err := b.blockKeys()
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := b.GetBlockMatrixInfo()
+	if err != nil {
+		return nil, err
+	}
+
+	emptyBytes, err := b.encodeBlock(b.emptyBlock())
+	if err != nil {
+		return nil, err
+	}
+
+	for _, blockNum := range corrupt {
+		blockNumBytes := []byte(fmt.Sprint(blockNum))
+		bytes, err := b.get(blockNumBytes)
+		if err != nil {
+			return nil, err
+		}
+
+		if err = b.put(quarantineEntry(blockNum), bytes); err != nil {
+			return nil, err
+		}
+
+		if err = b.put(blockNumBytes, emptyBytes); err != nil {
+			return nil, err
+		}
+
+		if key, ok := keys[blockNum]; ok {
+			if err = b.delete(b.dbKey(key)); err != nil {
+				return nil, err
+			}
+
+			if err = b.delete(keyIndexEntry(b.dbKey(key))); err != nil {
+				return nil, err
+			}
+		}
+
+		if err = b.updateBlockMatrixInfo(info, blockNum); err != nil {
+			return nil, err
+		}
+	}
+
+	return corrupt, nil
+}
+
+// QuarantinedBlock returns the block that was quarantined from the given block number.
+func (b *BlockMatrix) QuarantinedBlock(blockNum int) (*Block, error) {
+	bytes, err := b.get(quarantineEntry(blockNum))
+	if err != nil {
+		return nil, err
+	}
+
+	return b.unmarshalBlock(bytes)
+}
@@ -0,0 +1,38 @@
+package blockmatrix
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestQuarantine(t *testing.T) {
+	db := newTestDB(t)
+	bm, err := NewWithLevelDB(db)
+	require.NoError(t, err)
+	require.NoError(t, createTestBlocks(bm, 6))
+
+	quarantined, err := bm.Quarantine()
+	require.NoError(t, err)
+	require.Empty(t, quarantined)
+
+	corruptBlockData(t, db, 4, []byte("tampered"))
+
+	quarantined, err = bm.Quarantine()
+	require.NoError(t, err)
+	require.Equal(t, []int{4}, quarantined)
+
+	_, err = bm.GetBlock("key4")
+	require.Error(t, err)
+	block, err := bm.QuarantinedBlock(4)
+	require.NoError(t, err)
+	require.Equal(t, []byte("tampered"), block.Data)
+
+	for _, key := range []string{"key1", "key2", "key3", "key5", "key6"} {
+		_, err = bm.GetBlock(key)
+		require.NoError(t, err)
+	}
+
+	result, err := bm.Validate()
+	require.NoError(t, err)
+	require.True(t, result.OK)
+}
@@ -0,0 +1,66 @@
+package blockmatrix
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// rateLimiter paces writes with a token bucket holding a single token, refilled once per interval, so writes are spread
+// evenly instead of arriving in bursts.  Taking n tokens at once waits for the last of them.
+type rateLimiter struct {
+	mu sync.Mutex
+	// interval is the time it takes to refill a token
+	interval time.Duration
+	// next is when the bucket next holds a token, reservations past it are made by moving it forward
+	next time.Time
+}
+
+func newRateLimiter(opsPerSec int) *rateLimiter {
+	return &rateLimiter{interval: time.Second / time.Duration(opsPerSec)}
+}
+
+// wait blocks until n tokens are available and takes them, or returns the context's error as soon as ctx is done.  The
+// tokens of a wait that is abandoned are returned unless a later wait has already reserved tokens after them.
+func (l *rateLimiter) wait(ctx context.Context, n int) error {
+	l.mu.Lock()
+	now := time.Now()
+	start := l.next
+	if start.Before(now) {
+		start = now
+	}
+	end := start.Add(time.Duration(n) * l.interval)
+	l.next = end
+	l.mu.Unlock()
+
+	// the last of the n tokens is available one interval before the bucket is refilled after them
+	delay := end.Add(-l.interval).Sub(now)
+	if delay <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		l.mu.Lock()
+		if l.next.Equal(end) {
+			l.next = start
+		}
+		l.mu.Unlock()
+
+		return ctx.Err()
+	}
+}
+
+// throttleWrites waits until the write rate limit allows n more blocks to be added, if there is one.
+func (b *BlockMatrix) throttleWrites(ctx context.Context, n int) error {
+	if b.writeLimiter == nil {
+		return nil
+	}
+
+	return b.writeLimiter.wait(ctx, n)
+}
@@ -0,0 +1,53 @@
+package blockmatrix
+
+import (
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestWithWriteRateLimit(t *testing.T) {
+	const opsPerSec = 50
+
+	bm, err := New(NewMemStore(), WithWriteRateLimit(opsPerSec))
+	require.NoError(t, err)
+
+	// the first add is not delayed and each of the others waits for its own slot
+	start := time.Now()
+	for i := 1; i <= 6; i++ {
+		require.NoError(t, bm.AddBlock(fmt.Sprint("key", i), []byte{byte(i)}))
+	}
+	require.GreaterOrEqual(t, time.Since(start), 5*time.Second/opsPerSec)
+
+	// once the bucket is refilled a bulk add takes the first slot right away and waits for a slot per other block
+	time.Sleep(time.Second / opsPerSec)
+	start = time.Now()
+	require.NoError(t, bm.AddBlocks(testData(5)))
+	require.GreaterOrEqual(t, time.Since(start), 4*time.Second/opsPerSec)
+
+	ok, err := bm.IsValid()
+	require.NoError(t, err)
+	require.True(t, ok)
+}
+
+func TestWriteRateLimitCancellation(t *testing.T) {
+	bm, err := New(NewMemStore(), WithWriteRateLimit(1))
+	require.NoError(t, err)
+	require.NoError(t, bm.AddBlock("key1", []byte{1}))
+
+	// the next slot is a second away, the wait is abandoned when the context is done
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	require.ErrorIs(t, bm.AddBlockContext(ctx, "key2", []byte{2}), context.DeadlineExceeded)
+	require.ErrorIs(t, bm.AddBlocksContext(ctx, testData(3)), context.DeadlineExceeded)
+	require.Less(t, int64(time.Since(start)), int64(500*time.Millisecond))
+
+	_, err = bm.GetBlock("key2")
+	require.ErrorIs(t, err, ErrNotFound)
+	count, err := bm.BlockCount()
+	require.NoError(t, err)
+	require.Equal(t, 1, count)
+}
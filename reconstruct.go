@@ -0,0 +1,125 @@
+package blockmatrix
+
+import (
+	"reflect"
+	"strconv"
+)
+
+// VerifyReconstructable rebuilds the block matrix info from the stored blocks alone and compares it to the stored info,
+// returning true if they match.  This is stronger than IsValid, which trusts the stored size: the size is derived from
+// the block entries in the database, which fill every slot up to the capacity of the block matrix, and the row and
+// column hashes must be byte identical to the ones recomputed from the blocks.  Erased blocks at the end of the block
+// matrix are indistinguishable from empty ones so the block count, plus the slots freed by DeleteBlock, is only checked
+// to be at least the number of the last block with a key or data and no more than the capacity.  The ID cannot be
+// derived from the blocks and is not checked.
+func (b *BlockMatrix) VerifyReconstructable() (bool, error) {
+	defer b.startSpan("VerifyReconstructable")()
+
+	stored, err := b.GetBlockMatrixInfo()
+	if err != nil {
+		return false, err
+	}
+
+	rebuilt, minBlockCount, err := b.rebuildInfo()
+	if err != nil {
+		return false, err
+	}
+
+	if rebuilt == nil || rebuilt.Size != stored.Size {
+		return false, nil
+	}
+
+	last, err := b.lastBlock(stored)
+	if err != nil {
+		return false, err
+	}
+
+	if last < minBlockCount || last > capacity(stored.Size) {
+		return false, nil
+	}
+
+	return reflect.DeepEqual(rebuilt.Rows, stored.Rows) && reflect.DeepEqual(rebuilt.Cols, stored.Cols), nil
+}
+
+// rebuildInfo returns the info recomputed from the block entries in the database and the smallest block count, plus
+// free slots, consistent with them.  Nil info is returned if the block entries and the slots freed by DeleteBlock don't
+// fill a block matrix of any size.
+func (b *BlockMatrix) rebuildInfo() (*BlockMatrixInfo, int, error) {
+	slots, err := b.freeSlots()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	err = b.iterate(nil, func(key []byte, value []byte) error {
+		if isInternalEntry(key) {
+			return nil
+		}
+
+		if num, err := strconv.Atoi(string(key)); err == nil && strconv.Itoa(num) == string(key) {
+			slots[num] = true
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	size := 1
+	for capacity(size) < len(slots) {
+		size++
+	}
+
+	if capacity(size) != len(slots) {
+		return nil, 0, nil
+	}
+
+	for blockNum := 1; blockNum <= len(slots); blockNum++ {
+		if !slots[blockNum] {
+			return nil, 0, nil
+		}
+	}
+
+	info := &BlockMatrixInfo{
+		Size:          size,
+		Rows:          make([][]byte, size),
+		Cols:          make([][]byte, size),
+		BlockEncoding: blockEncodingID(b.blockEncoding),
+	}
+
+	for i := 0; i < size; i++ {
+		if info.Rows[i], err = b.calculateRowHash(i, size); err != nil {
+			return nil, 0, err
+		}
+
+		if info.Cols[i], err = b.calculateColumnHash(i, size); err != nil {
+			return nil, 0, err
+		}
+	}
+
+	keys, err := b.blockKeys()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	minBlockCount := 0
+	for blockNum := range keys {
+		if blockNum > minBlockCount {
+			minBlockCount = blockNum
+		}
+	}
+
+	for blockNum := len(slots); blockNum > minBlockCount; blockNum-- {
+		block, err := b.GetBlockByNumber(blockNum)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		if !block.IsEmpty() {
+			minBlockCount = blockNum
+			break
+		}
+	}
+
+	return info, minBlockCount, nil
+}
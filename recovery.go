@@ -0,0 +1,182 @@
+package blockmatrix
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+)
+
+// RecoverLatestConsistent brings a block matrix left inconsistent by a crash part way through a change back to the most
+// recent consistent state.  Changes write the key and block entries before the info, so a crash leaves entries the info
+// does not account for, which are resolved from the entries themselves:
+//
+// A key for a block beyond the block count whose block entry was written is rolled forward: the block count, and the
+// size if needed, are raised to include it.  A key whose block entry was never written, or cannot be read, is rolled
+// back by removing the key.  A block with data but no key, left by an erase that removed the key before clearing the
+// block, is rolled forward by clearing it.  Block entries outside the capacity of the block matrix are removed and
+// missing slots are filled with empty blocks, as by RepairSlots.  Finally every row and column hash is recalculated and
+// the info is written.  Each fix is logged.  Running it on a consistent block matrix only rewrites the info.
+func (b *BlockMatrix) RecoverLatestConsistent() error {
+	defer b.startSpan("RecoverLatestConsistent")()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	info, err := b.GetBlockMatrixInfo()
+	if err != nil {
+		return err
+	}
+
+	keys, err := b.blockKeys()
+	if err != nil {
+		return err
+	}
+
+	blockNums := make([]int, 0, len(keys))
+	for blockNum := range keys {
+		blockNums = append(blockNums, blockNum)
+	}
+	sort.Ints(blockNums)
+
+	free, err := b.freeSlots()
+	if err != nil {
+		return err
+	}
+
+	// roll forward the blocks added after the info was last written, roll back the keys added without their block
+	last := info.BlockCount + len(free)
+	for _, blockNum := range blockNums {
+		ok, err := b.hasReadableBlock(blockNum)
+		if err != nil {
+			return err
+		}
+
+		if ok && blockNum > last {
+			b.logger.Info("rolling forward block", "block", blockNum, "key", keys[blockNum])
+			last = blockNum
+		} else if !ok {
+			b.logger.Warn("rolling back key added without its block", "key", keys[blockNum], "block", blockNum)
+			if err = b.removeKey(keys[blockNum]); err != nil {
+				return err
+			}
+
+			delete(keys, blockNum)
+		}
+	}
+
+	if newSize := b.Size(last); newSize > info.Size {
+		for i := info.Size; i < newSize; i++ {
+			info.Rows = append(info.Rows, make([]byte, 0))
+			info.Cols = append(info.Cols, make([]byte, 0))
+		}
+		info.Size = newSize
+	}
+	info.BlockCount = last - len(free)
+
+	if err = b.recoverSlots(info, keys, free); err != nil {
+		return err
+	}
+
+	return b.updateAllHashes(info)
+}
+
+// hasReadableBlock returns true if the block entry with the given number exists and can be decoded.
+func (b *BlockMatrix) hasReadableBlock(blockNum int) (bool, error) {
+	bytes, err := b.get([]byte(strconv.Itoa(blockNum)))
+	if errors.Is(err, ErrNotFound) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	_, err = b.decodeBlock(bytes)
+	return err == nil, nil
+}
+
+// removeKey removes the entries of key without touching its block.
+func (b *BlockMatrix) removeKey(key string) error {
+	dbKey := b.dbKey(key)
+	if err := b.delete(dbKey); err != nil {
+		return err
+	}
+
+	if b.keyTransform != nil {
+		if err := b.delete(originalKeyEntry(dbKey)); err != nil {
+			return err
+		}
+	}
+
+	return b.delete(keyIndexEntry(dbKey))
+}
+
+// recoverSlots removes block entries outside the capacity of the block matrix described by info, fills missing slots
+// with empty blocks, and clears blocks beyond the block count or without a key in keys that still hold data.  The free
+// slots in free have no block entry and are left as they are.
+func (b *BlockMatrix) recoverSlots(info *BlockMatrixInfo, keys map[int]string, free map[int]bool) error {
+	stray := make([][]byte, 0)
+	err := b.iterate(nil, func(key []byte, value []byte) error {
+		if isInternalEntry(key) {
+			return nil
+		}
+
+		num, err := strconv.Atoi(string(key))
+		if err != nil || strconv.Itoa(num) != string(key) {
+			return nil
+		}
+
+		if num < 1 || num > capacity(info.Size) {
+			stray = append(stray, copyBytes(key))
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, key := range stray {
+		b.logger.Warn("removing stray block entry", "key", string(key))
+		if err = b.delete(key); err != nil {
+			return err
+		}
+	}
+
+	emptyBytes, err := b.encodeBlock(b.emptyBlock())
+	if err != nil {
+		return err
+	}
+
+	for blockNum := 1; blockNum <= capacity(info.Size); blockNum++ {
+		bytes, err := b.get([]byte(strconv.Itoa(blockNum)))
+		if errors.Is(err, ErrNotFound) {
+			if b.lazyEmptyBlocks || free[blockNum] {
+				continue
+			}
+
+			b.logger.Warn("filling missing block entry with an empty block", "block", blockNum)
+			if err = b.put([]byte(strconv.Itoa(blockNum)), emptyBytes); err != nil {
+				return err
+			}
+
+			continue
+		} else if err != nil {
+			return err
+		}
+
+		if _, ok := keys[blockNum]; ok {
+			continue
+		}
+
+		block, err := b.decodeBlock(bytes)
+		if err == nil && block.IsEmpty() {
+			continue
+		}
+
+		b.logger.Warn("clearing block without a key", "block", blockNum)
+		if err = b.put([]byte(strconv.Itoa(blockNum)), emptyBytes); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
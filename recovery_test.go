@@ -0,0 +1,75 @@
+package blockmatrix
+
+import (
+	"github.com/stretchr/testify/require"
+	"strconv"
+	"testing"
+)
+
+func TestRecoverLatestConsistent(t *testing.T) {
+	bm, err := NewWithLevelDB(newTestDB(t))
+	require.NoError(t, err)
+	require.NoError(t, createTestBlocks(bm, 6))
+
+	// crash after the block that grows the block matrix was written but before the info was
+	staleInfo, err := bm.get(InfoKey)
+	require.NoError(t, err)
+	require.NoError(t, bm.AddBlock("key7", []byte{7}))
+	require.NoError(t, bm.store.Put(InfoKey, staleInfo))
+
+	// and a key written without its block
+	require.NoError(t, bm.store.Put([]byte("orphan"), []byte(strconv.Itoa(20))))
+
+	// the stale info is consistent with the blocks that fit in it but not with the block entries written for the growth
+	ok, err := bm.VerifyReconstructable()
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	require.NoError(t, bm.RecoverLatestConsistent())
+
+	ok, err = bm.IsValid()
+	require.NoError(t, err)
+	require.True(t, ok)
+	ok, err = bm.VerifyReconstructable()
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	info, err := bm.GetBlockMatrixInfo()
+	require.NoError(t, err)
+	require.Equal(t, 7, info.BlockCount)
+	require.Equal(t, 4, info.Size)
+
+	block, err := bm.GetBlock("key7")
+	require.NoError(t, err)
+	require.Equal(t, []byte{7}, block.Data)
+	_, err = bm.GetBlock("orphan")
+	require.ErrorIs(t, err, ErrNotFound)
+
+	// the recovered block matrix keeps working
+	require.NoError(t, bm.AddBlock("key8", []byte{8}))
+	require.NoError(t, bm.EraseBlock("key3"))
+	ok, err = bm.IsValid()
+	require.NoError(t, err)
+	require.True(t, ok)
+}
+
+func TestRecoverLatestConsistentInterruptedErase(t *testing.T) {
+	bm, err := NewWithLevelDB(newTestDB(t))
+	require.NoError(t, err)
+	require.NoError(t, createTestBlocks(bm, 4))
+
+	// crash after the key of block 2 was removed but before the block was cleared
+	require.NoError(t, bm.store.Delete([]byte("key2")))
+	require.NoError(t, bm.RecoverLatestConsistent())
+
+	block, err := bm.GetBlockByNumber(2)
+	require.NoError(t, err)
+	require.True(t, block.IsEmpty())
+	block, err = bm.GetBlock("key3")
+	require.NoError(t, err)
+	require.Equal(t, []byte{3}, block.Data)
+
+	ok, err := bm.IsValid()
+	require.NoError(t, err)
+	require.True(t, ok)
+}
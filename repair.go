@@ -0,0 +1,92 @@
+package blockmatrix
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// RepairSlots makes sure every block number from 1 to the capacity of the block matrix has a block entry, other than
+// the slots freed by DeleteBlock, and that no block entries exist outside of that range.  Missing slots are filled with
+// empty blocks, stray block entries are deleted, and every row and column hash is recalculated.  Each fix is logged.
+// This normalizes a block matrix after an interrupted grow or other corruption of the slot entries.
+func (b *BlockMatrix) RepairSlots() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	info, err := b.GetBlockMatrixInfo()
+	if err != nil {
+		return err
+	}
+
+	// find block entries that are not in the capacity of the block matrix
+	stray := make([][]byte, 0)
+	err = b.iterate(nil, func(key []byte, value []byte) error {
+		num, err := strconv.Atoi(string(key))
+		if err != nil || strconv.Itoa(num) != string(key) {
+			return nil
+		}
+
+		if num < 1 || num > capacity(info.Size) {
+			stray = append(stray, append([]byte{}, key...))
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, key := range stray {
+		b.logger.Warn("removing stray block entry", "key", string(key))
+		if err = b.delete(key); err != nil {
+			return err
+		}
+	}
+
+	emptyBytes, err := b.encodeBlock(b.emptyBlock())
+	if err != nil {
+		return err
+	}
+
+	free, err := b.freeSlots()
+	if err != nil {
+		return err
+	}
+
+	for blockNum := 1; blockNum <= capacity(info.Size); blockNum++ {
+		key := []byte(fmt.Sprint(blockNum))
+		if ok, err := b.has(key); err != nil {
+			return err
+		} else if ok || free[blockNum] {
+			continue
+		}
+
+		b.logger.Warn("filling missing block entry with an empty block", "block", blockNum)
+		if err = b.put(key, emptyBytes); err != nil {
+			return err
+		}
+	}
+
+	return b.updateAllHashes(info)
+}
+
+// RepairHashes recalculates every row and column hash from the stored blocks and rewrites the block matrix info, for
+// example after importing a database whose info was stale or partially written.  The size and block count in the info
+// are trusted, use RepairSlots first if the slot entries may not match them.  Block hashes are not changed.
+func (b *BlockMatrix) RepairHashes() error {
+	defer b.startSpan("RepairHashes")()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	info, err := b.GetBlockMatrixInfo()
+	if err != nil {
+		return err
+	}
+
+	// a partially written info may not have a hash for every row and column
+	info.Rows = make([][]byte, info.Size)
+	info.Cols = make([][]byte, info.Size)
+
+	return b.updateAllHashes(info)
+}
@@ -0,0 +1,75 @@
+package blockmatrix
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestRepairSlots(t *testing.T) {
+	logger := &capturingLogger{}
+	db := newTestDB(t)
+	bm, err := NewWithLevelDB(db, WithLogger(logger))
+	require.NoError(t, err)
+	require.NoError(t, createTestBlocks(bm, 8))
+
+	// simulate an interrupted grow that left out slot 10 and a stray slot beyond the capacity
+	emptyBytes, err := json.Marshal(EmptyBlock())
+	require.NoError(t, err)
+	require.NoError(t, db.Delete([]byte("10"), nil))
+	require.NoError(t, db.Put([]byte("13"), emptyBytes, nil))
+
+	require.NoError(t, bm.RepairSlots())
+	require.Contains(t, logger.lines, "WARN removing stray block entry [key 13]")
+	require.Contains(t, logger.lines, "WARN filling missing block entry with an empty block [block 10]")
+
+	ok, err := db.Has([]byte("13"), nil)
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	block, err := bm.GetBlockByNumber(10)
+	require.NoError(t, err)
+	require.True(t, block.IsEmpty())
+
+	result, err := bm.Validate()
+	require.NoError(t, err)
+	require.True(t, result.OK)
+
+	block, err = bm.GetBlock("key8")
+	require.NoError(t, err)
+	require.Equal(t, []byte{8}, block.Data)
+}
+
+func TestRepairHashes(t *testing.T) {
+	db := newTestDB(t)
+	bm, err := NewWithLevelDB(db)
+	require.NoError(t, err)
+	require.NoError(t, createTestBlocks(bm, 8))
+	require.NoError(t, bm.EraseBlock("key3"))
+	expected, err := bm.GetBlockMatrixInfo()
+	require.NoError(t, err)
+
+	// a stale row hash and a column hash that was never written
+	info, err := bm.GetBlockMatrixInfo()
+	require.NoError(t, err)
+	info.Rows[1] = []byte("stale")
+	info.Cols = info.Cols[:info.Size-1]
+	infoBytes, err := encodeInfo(info)
+	require.NoError(t, err)
+	require.NoError(t, db.Put(InfoKey, infoBytes, nil))
+
+	result, err := bm.Validate()
+	require.NoError(t, err)
+	require.Equal(t, []int{1}, result.RowErrors)
+	require.Equal(t, []int{info.Size - 1}, result.ColumnErrors)
+
+	require.NoError(t, bm.RepairHashes())
+
+	ok, err := bm.IsValid()
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	info, err = bm.GetBlockMatrixInfo()
+	require.NoError(t, err)
+	require.Equal(t, expected, info)
+}
@@ -0,0 +1,310 @@
+package blockmatrix
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// StalenessAgainst reports whether this block matrix is stale relative to a primary with the given root hash.  It is
+// intended for read-only replicas opened over a copy of a primary's leveldb database: the primary ships its RootHash and
+// the replica returns true if its own root hash differs, meaning the primary has changed since the copy was taken.
+func (b *BlockMatrix) StalenessAgainst(primaryRoot []byte) (bool, error) {
+	root, err := b.RootHash()
+	if err != nil {
+		return false, err
+	}
+
+	return !bytes.Equal(root, primaryRoot), nil
+}
+
+// ReplicateTo copies the block matrix into dst, replacing everything dst had.  Every entry is copied as stored,
+// including the change log, so dst must be opened with the same options and ChangesSince and ReplicateIncremental work
+// on dst as they do on the source.  The source is read while holding its read lock, then written to dst atomically
+// while holding dst's write lock.  The two locks are never held together, so block matrices replicating to each other
+// at the same time can't deadlock.  Replicating a block matrix to itself does nothing.
+func (b *BlockMatrix) ReplicateTo(dst *BlockMatrix) error {
+	defer b.startSpan("ReplicateTo")()
+
+	if dst == b {
+		return nil
+	}
+
+	b.mu.RLock()
+	writes, err := b.replicaWrites(dst)
+	b.mu.RUnlock()
+	if err != nil {
+		return err
+	}
+
+	dst.mu.Lock()
+	defer dst.mu.Unlock()
+
+	return dst.replaceEntries(writes)
+}
+
+// replicateTo copies the block matrix into dst like ReplicateTo without locking either of them.
+func (b *BlockMatrix) replicateTo(dst *BlockMatrix) error {
+	writes, err := b.replicaWrites(dst)
+	if err != nil {
+		return err
+	}
+
+	return dst.replaceEntries(writes)
+}
+
+// replicaWrites returns the writes that put every entry of the block matrix, other than the clean shutdown marker, into
+// dst.
+func (b *BlockMatrix) replicaWrites(dst *BlockMatrix) ([]StoreWrite, error) {
+	if err := b.checkReplica(dst); err != nil {
+		return nil, err
+	}
+
+	writes := make([]StoreWrite, 0)
+	err := b.iterate(nil, func(key []byte, value []byte) error {
+		if string(key) == cleanShutdownKey {
+			return nil
+		}
+
+		writes = append(writes, StoreWrite{Key: copyBytes(key), Value: copyBytes(value)})
+		return nil
+	})
+
+	return writes, err
+}
+
+// replaceEntries atomically replaces every entry of the block matrix, other than the clean shutdown marker, with the
+// entries put by writes.
+func (b *BlockMatrix) replaceEntries(writes []StoreWrite) error {
+	entries := make(map[string]bool, len(writes))
+	encoding := b.blockEncoding
+	for _, write := range writes {
+		entries[string(write.Key)] = true
+
+		// the blocks are replaced by blocks stored with the encoding recorded in the replacing info
+		if bytes.Equal(write.Key, InfoKey) && !write.Delete {
+			info, err := decodeInfo(write.Value)
+			if err != nil {
+				return fmt.Errorf("error decoding block matrix info: %w", err)
+			}
+
+			if encoding, err = b.recordedBlockEncoding(info); err != nil {
+				return err
+			}
+		}
+	}
+
+	err := b.iterate(nil, func(key []byte, value []byte) error {
+		if string(key) != cleanShutdownKey && !entries[string(key)] {
+			writes = append(writes, StoreWrite{Key: copyBytes(key), Delete: true})
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if err = b.write(writes); err != nil {
+		return err
+	}
+
+	b.blockEncoding = encoding
+
+	// the change log, erase journal, and key index were replaced so their next sequence numbers must be scanned again
+	b.changeLogSeq = 0
+	b.eraseJournalSeq = 0
+	b.keyIndexSeq = 0
+	b.changed = nil
+	return nil
+}
+
+// ReplicateIncremental copies the blocks changed since sinceRoot, as reported by ChangesSince, into dst along with their
+// keys, their hash index entries, and the info.  Aliases are not part of the change log so every alias is copied and
+// aliases the source no longer has are removed.  dst must be a replica at sinceRoot, for example one last brought up to
+// date by ReplicateTo or ReplicateIncremental when the source had that root, otherwise an error wrapping
+// ErrReplicaDiverged is returned.  A single change log entry covering all of the copied blocks is appended to dst's
+// change log.  Like ReplicateTo, the source is read under its read lock before dst is locked, and the changes are
+// written to dst atomically.
+func (b *BlockMatrix) ReplicateIncremental(dst *BlockMatrix, sinceRoot []byte) error {
+	defer b.startSpan("ReplicateIncremental")()
+
+	if dst == b {
+		return nil
+	}
+
+	b.mu.RLock()
+	replica, err := b.incrementalWrites(dst, sinceRoot)
+	b.mu.RUnlock()
+	if err != nil {
+		return err
+	}
+
+	dst.mu.Lock()
+	defer dst.mu.Unlock()
+
+	dstRoot, err := dst.RootHash()
+	if err != nil {
+		return err
+	}
+
+	if !bytes.Equal(dstRoot, sinceRoot) {
+		return fmt.Errorf("%w: replica root is %x, not %x", ErrReplicaDiverged, dstRoot, sinceRoot)
+	}
+
+	// entries of the changed blocks, and aliases, that dst has and the source no longer has are removed from dst
+	writes := replica.writes
+	err = dst.iterate(nil, func(key []byte, value []byte) error {
+		switch {
+		case bytes.HasPrefix(key, []byte(aliasPrefix)):
+			if !replica.entries[string(key)] {
+				writes = append(writes, StoreWrite{Key: copyBytes(key), Delete: true})
+			}
+		case bytes.HasPrefix(key, []byte(hashIndexPrefix)):
+			if blockNum, err := strconv.Atoi(string(value)); err == nil && replica.changed[blockNum] &&
+				!replica.entries[string(key)] {
+				writes = append(writes, StoreWrite{Key: copyBytes(key), Delete: true})
+			}
+		default:
+			blockNum, ok := keyEntryBlock(key, value)
+			if ok && replica.changed[blockNum] && !replica.entries[string(key)] {
+				writes = append(writes, StoreWrite{Key: copyBytes(key), Delete: true},
+					StoreWrite{Key: originalKeyEntry(key), Delete: true},
+					StoreWrite{Key: keyIndexEntry(key), Delete: true})
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	seq, err := dst.nextChangeLogSeq()
+	if err != nil {
+		return err
+	}
+	writes = append(writes, StoreWrite{Key: changeLogKey(seq), Value: replica.changeLogEntry})
+
+	if err = dst.write(writes); err != nil {
+		return err
+	}
+
+	// the replicated key index entries carry sequence numbers of the source
+	dst.keyIndexSeq = 0
+	return nil
+}
+
+// incrementalReplica holds what ReplicateIncremental reads from the source before it locks dst.
+type incrementalReplica struct {
+	// writes put the info and the entries of the changed blocks, their keys, and the aliases
+	writes []StoreWrite
+	// entries are the key, alias, and hash index entries put by writes
+	entries map[string]bool
+	// changed are the numbers of the changed blocks
+	changed map[int]bool
+	// changeLogEntry is the change log entry to append to dst
+	changeLogEntry []byte
+}
+
+// incrementalWrites reads the entries ReplicateIncremental copies to dst from the block matrix.
+func (b *BlockMatrix) incrementalWrites(dst *BlockMatrix, sinceRoot []byte) (*incrementalReplica, error) {
+	if err := b.checkReplica(dst); err != nil {
+		return nil, err
+	}
+
+	changed, err := b.ChangesSince(sinceRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := b.GetBlockMatrixInfo()
+	if err != nil {
+		return nil, err
+	}
+
+	replica := &incrementalReplica{
+		writes:  make([]StoreWrite, 0),
+		entries: make(map[string]bool),
+		changed: make(map[int]bool, len(changed)),
+	}
+	replicate := func(key []byte) error {
+		value, err := b.get(key)
+		if errors.Is(err, ErrNotFound) {
+			replica.writes = append(replica.writes, StoreWrite{Key: key, Delete: true})
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		replica.writes = append(replica.writes, StoreWrite{Key: key, Value: value})
+		return nil
+	}
+	put := func(key []byte, value []byte) {
+		replica.entries[string(key)] = true
+		replica.writes = append(replica.writes, StoreWrite{Key: copyBytes(key), Value: copyBytes(value)})
+	}
+
+	if err = replicate(InfoKey); err != nil {
+		return nil, err
+	}
+
+	for _, blockNum := range changed {
+		replica.changed[blockNum] = true
+		for _, key := range [][]byte{[]byte(strconv.Itoa(blockNum)), reservedEntry(blockNum), freeEntry(blockNum),
+			quarantineEntry(blockNum)} {
+			if err = replicate(key); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	err = b.iterate(nil, func(key []byte, value []byte) error {
+		switch {
+		case bytes.HasPrefix(key, []byte(aliasPrefix)):
+			put(key, value)
+		case bytes.HasPrefix(key, []byte(hashIndexPrefix)):
+			if blockNum, err := strconv.Atoi(string(value)); err == nil && replica.changed[blockNum] {
+				put(key, value)
+			}
+		default:
+			if blockNum, ok := keyEntryBlock(key, value); ok && replica.changed[blockNum] {
+				put(key, value)
+				if err := replicate(keyIndexEntry(key)); err != nil {
+					return err
+				}
+
+				return replicate(originalKeyEntry(key))
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	replica.changeLogEntry, err = json.Marshal(changeLogEntry{
+		Root:   b.rootHash(info),
+		Blocks: changed,
+		Time:   time.Now().UnixNano(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return replica, nil
+}
+
+// checkReplica returns an error if dst uses a different hash function than the block matrix.
+func (b *BlockMatrix) checkReplica(dst *BlockMatrix) error {
+	info, err := b.GetBlockMatrixInfo()
+	if err != nil {
+		return err
+	}
+
+	return dst.checkHasher(info)
+}
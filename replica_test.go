@@ -0,0 +1,182 @@
+package blockmatrix
+
+import (
+	"github.com/stretchr/testify/require"
+	"github.com/syndtr/goleveldb/leveldb"
+	"testing"
+	"time"
+)
+
+// copyTestDB copies every entry in src into a new test database.
+func copyTestDB(t *testing.T, src *leveldb.DB) *leveldb.DB {
+	dst := newTestDB(t)
+
+	iter := src.NewIterator(nil, nil)
+	defer iter.Release()
+	for iter.Next() {
+		require.NoError(t, dst.Put(iter.Key(), iter.Value(), nil))
+	}
+	require.NoError(t, iter.Error())
+
+	return dst
+}
+
+func TestStalenessAgainst(t *testing.T) {
+	primaryDB := newTestDB(t)
+	primary, err := NewWithLevelDB(primaryDB)
+	require.NoError(t, err)
+	require.NoError(t, createTestBlocks(primary, 5))
+
+	replica, err := NewWithLevelDB(copyTestDB(t, primaryDB))
+	require.NoError(t, err)
+
+	root, err := primary.RootHash()
+	require.NoError(t, err)
+	stale, err := replica.StalenessAgainst(root)
+	require.NoError(t, err)
+	require.False(t, stale)
+
+	require.NoError(t, primary.AddBlock("key6", []byte{6}))
+	root, err = primary.RootHash()
+	require.NoError(t, err)
+	stale, err = replica.StalenessAgainst(root)
+	require.NoError(t, err)
+	require.True(t, stale)
+}
+
+func TestReplicate(t *testing.T) {
+	src, err := NewWithLevelDB(newTestDB(t))
+	require.NoError(t, err)
+	require.NoError(t, createTestBlocks(src, 5))
+
+	dst, err := New(NewMemStore())
+	require.NoError(t, err)
+	require.NoError(t, dst.AddBlock("stale", []byte("stale")))
+
+	require.NoError(t, src.ReplicateTo(dst))
+	requireReplica(t, src, dst)
+	_, err = dst.GetBlock("stale")
+	require.ErrorIs(t, err, ErrNotFound)
+
+	root, err := src.RootHash()
+	require.NoError(t, err)
+
+	// grow the source and change existing blocks
+	require.NoError(t, src.AddBlock("key6", []byte{6}))
+	require.NoError(t, src.AddBlock("key7", []byte{7}))
+	require.NoError(t, src.UpdateBlock("key3", []byte("updated")))
+	require.NoError(t, src.EraseBlock("key1"))
+	require.NoError(t, src.DeleteBlock("key4"))
+	_, err = src.ReserveBlock("reserved")
+	require.NoError(t, err)
+
+	require.NoError(t, src.ReplicateIncremental(dst, root))
+	requireReplica(t, src, dst)
+	// the reserved block reused the slot freed by DeleteBlock
+	free, err := dst.IsFree(4)
+	require.NoError(t, err)
+	require.False(t, free)
+	reserved, err := dst.IsReserved(4)
+	require.NoError(t, err)
+	require.True(t, reserved)
+
+	changes, err := dst.ChangesSince(root)
+	require.NoError(t, err)
+	srcChanges, err := src.ChangesSince(root)
+	require.NoError(t, err)
+	require.Equal(t, srcChanges, changes)
+
+	// the replica is no longer at the old root
+	err = src.ReplicateIncremental(dst, root)
+	require.ErrorIs(t, err, ErrReplicaDiverged)
+}
+
+func TestReplicateIncrementalAliases(t *testing.T) {
+	src, err := NewWithLevelDB(newTestDB(t), WithHashCollisionCheck(nil))
+	require.NoError(t, err)
+	require.NoError(t, createTestBlocks(src, 3))
+	require.NoError(t, src.AddAlias("key1", "old"))
+
+	dst, err := New(NewMemStore(), WithHashCollisionCheck(nil))
+	require.NoError(t, err)
+	require.NoError(t, src.ReplicateTo(dst))
+	root, err := src.RootHash()
+	require.NoError(t, err)
+
+	require.NoError(t, src.AddAlias("key2", "alias"))
+	require.NoError(t, src.EraseBlock("key1"))
+	require.NoError(t, src.AddBlock("key4", []byte{4}))
+	require.NoError(t, src.ReplicateIncremental(dst, root))
+	requireReplica(t, src, dst)
+
+	// the new alias resolves on the replica and the alias of the erased block is gone
+	block, err := dst.GetBlock("alias")
+	require.NoError(t, err)
+	require.Equal(t, []byte{2}, block.Data)
+	_, err = dst.GetBlock("old")
+	require.ErrorIs(t, err, ErrNotFound)
+
+	// the hash index entry of the new block was copied
+	block, err = src.GetBlock("key4")
+	require.NoError(t, err)
+	entry, err := dst.get(hashIndexEntry(block.Hash))
+	require.NoError(t, err)
+	require.Equal(t, []byte("4"), entry)
+}
+
+func TestReplicateBothWays(t *testing.T) {
+	a, err := NewWithLevelDB(newTestDB(t))
+	require.NoError(t, err)
+	require.NoError(t, createTestBlocks(a, 3))
+	b, err := NewWithLevelDB(newTestDB(t))
+	require.NoError(t, err)
+
+	// each replication locks its source before its destination, which used to deadlock when they ran both ways at once
+	done := make(chan error, 2)
+	for _, pair := range [][2]*BlockMatrix{{a, b}, {b, a}} {
+		src, dst := pair[0], pair[1]
+		go func() {
+			for i := 0; i < 100; i++ {
+				if err := src.ReplicateTo(dst); err != nil {
+					done <- err
+					return
+				}
+			}
+			done <- nil
+		}()
+	}
+
+	for i := 0; i < 2; i++ {
+		select {
+		case err := <-done:
+			require.NoError(t, err)
+		case <-time.After(10 * time.Second):
+			t.Fatal("replicating both ways deadlocked")
+		}
+	}
+}
+
+// requireReplica checks dst holds the same blocks, keys, and info as src and is valid.
+func requireReplica(t *testing.T, src *BlockMatrix, dst *BlockMatrix) {
+	hashes, err := src.ExportHashes()
+	require.NoError(t, err)
+	diverged, err := dst.VerifyAgainstHashes(hashes)
+	require.NoError(t, err)
+	require.Empty(t, diverged)
+
+	info, err := src.GetBlockMatrixInfo()
+	require.NoError(t, err)
+	dstInfo, err := dst.GetBlockMatrixInfo()
+	require.NoError(t, err)
+	require.Equal(t, info, dstInfo)
+
+	keys, err := src.blockKeys()
+	require.NoError(t, err)
+	dstKeys, err := dst.blockKeys()
+	require.NoError(t, err)
+	require.Equal(t, keys, dstKeys)
+
+	ok, err := dst.IsValid()
+	require.NoError(t, err)
+	require.True(t, ok)
+}
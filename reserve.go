@@ -0,0 +1,94 @@
+package blockmatrix
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// reservedPrefix prefixes the entries marking block numbers that were reserved but not yet filled.
+const reservedPrefix = "reserved:"
+
+func reservedEntry(blockNum int) []byte {
+	return []byte(fmt.Sprintf("%s%d", reservedPrefix, blockNum))
+}
+
+// ReserveBlock allocates the next block number for the given key without any data.  The reserved block is empty, and
+// hashed as such, until it is populated with FillReserved.  Unlike an erased block, a reserved block keeps its key and
+// is reported by IsReserved.  The reserved block number is returned.
+func (b *BlockMatrix) ReserveBlock(key string) (int, error) {
+	defer b.startSpan("ReserveBlock")()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	blockNum, err := b.addBlock(key, b.emptyBlock())
+	if err != nil {
+		return 0, err
+	}
+
+	if err = b.put(reservedEntry(blockNum), []byte(key)); err != nil {
+		return 0, err
+	}
+
+	return blockNum, nil
+}
+
+// FillReserved stores data in the block reserved for the given key and updates the row and column hashes.  An error
+// wrapping ErrNotReserved is returned if the key's block is not reserved.
+func (b *BlockMatrix) FillReserved(key string, data []byte) error {
+	defer b.startSpan("FillReserved")()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	value, err := b.get(b.dbKey(key))
+	if err != nil {
+		return err
+	}
+
+	blockNum, err := strconv.Atoi(string(value))
+	if err != nil {
+		return err
+	}
+
+	reserved, err := b.IsReserved(blockNum)
+	if err != nil {
+		return err
+	} else if !reserved {
+		return fmt.Errorf("%w: key %q", ErrNotReserved, key)
+	}
+
+	block := b.newBlock(data)
+	if err = b.checkHashCollision(block, b.get, b.getSlot); err != nil {
+		return err
+	}
+
+	bytes, err := b.encodeBlock(block)
+	if err != nil {
+		return err
+	}
+
+	if err = b.put([]byte(strconv.Itoa(blockNum)), bytes); err != nil {
+		return err
+	}
+
+	if err = b.indexBlockHash(blockNum, block); err != nil {
+		return err
+	}
+
+	if err = b.delete(reservedEntry(blockNum)); err != nil {
+		return err
+	}
+
+	info, err := b.GetBlockMatrixInfo()
+	if err != nil {
+		return err
+	}
+
+	return b.updateBlockMatrixInfo(info, blockNum)
+}
+
+// IsReserved returns true if the block number was reserved with ReserveBlock and has not been filled or erased since.
+func (b *BlockMatrix) IsReserved(blockNum int) (bool, error) {
+	return b.has(reservedEntry(blockNum))
+}
@@ -0,0 +1,48 @@
+package blockmatrix
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestReserveBlock(t *testing.T) {
+	bm, err := NewWithLevelDB(newTestDB(t))
+	require.NoError(t, err)
+	require.NoError(t, createTestBlocks(bm, 3))
+	require.NoError(t, bm.EraseBlock("key3"))
+
+	blockNum, err := bm.ReserveBlock("reserved")
+	require.NoError(t, err)
+	require.Equal(t, 4, blockNum)
+
+	block, err := bm.GetBlock("reserved")
+	require.NoError(t, err)
+	require.True(t, block.IsEmpty())
+
+	reserved, err := bm.IsReserved(4)
+	require.NoError(t, err)
+	require.True(t, reserved)
+	reserved, err = bm.IsReserved(3)
+	require.NoError(t, err)
+	require.False(t, reserved)
+
+	result, err := bm.Validate()
+	require.NoError(t, err)
+	require.True(t, result.OK)
+
+	require.ErrorIs(t, bm.FillReserved("key1", []byte("data")), ErrNotReserved)
+	require.NoError(t, bm.FillReserved("reserved", []byte("data")))
+
+	block, err = bm.GetBlock("reserved")
+	require.NoError(t, err)
+	require.Equal(t, []byte("data"), block.Data)
+
+	reserved, err = bm.IsReserved(4)
+	require.NoError(t, err)
+	require.False(t, reserved)
+	require.ErrorIs(t, bm.FillReserved("reserved", []byte("again")), ErrNotReserved)
+
+	result, err = bm.Validate()
+	require.NoError(t, err)
+	require.True(t, result.OK)
+}
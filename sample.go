@@ -0,0 +1,48 @@
+package blockmatrix
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+)
+
+// SampleBlocks returns k distinct live block numbers chosen at random using the given seed.  The same seed always yields
+// the same sample of the same block matrix, so spot checks can be reproduced.  An error is returned if k is negative or
+// larger than the number of live blocks.
+func (b *BlockMatrix) SampleBlocks(k int, seed int64) ([]int, error) {
+	info, err := b.GetBlockMatrixInfo()
+	if err != nil {
+		return nil, err
+	}
+
+	last, err := b.lastBlock(info)
+	if err != nil {
+		return nil, err
+	}
+
+	blocks, err := b.GetBlocksByNumbers(blockRange(last))
+	if err != nil {
+		return nil, err
+	}
+
+	live := make([]int, 0, len(blocks))
+	for blockNum, block := range blocks {
+		if !block.IsEmpty() {
+			live = append(live, blockNum)
+		}
+	}
+
+	if k < 0 || k > len(live) {
+		return nil, fmt.Errorf("cannot sample %d blocks from %d live blocks", k, len(live))
+	}
+
+	// sort the live blocks so the sample only depends on the seed and not on map iteration order
+	sort.Ints(live)
+
+	sample := make([]int, k)
+	for i, j := range rand.New(rand.NewSource(seed)).Perm(len(live))[:k] {
+		sample[i] = live[j]
+	}
+
+	return sample, nil
+}
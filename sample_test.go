@@ -0,0 +1,39 @@
+package blockmatrix
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestSampleBlocks(t *testing.T) {
+	bm, err := NewWithLevelDB(newTestDB(t))
+	require.NoError(t, err)
+	require.NoError(t, createTestBlocks(bm, 9))
+	require.NoError(t, bm.EraseBlock("key2"))
+	require.NoError(t, bm.EraseBlock("key7"))
+
+	sample, err := bm.SampleBlocks(4, 42)
+	require.NoError(t, err)
+	require.Len(t, sample, 4)
+
+	again, err := bm.SampleBlocks(4, 42)
+	require.NoError(t, err)
+	require.Equal(t, sample, again)
+
+	seen := make(map[int]bool)
+	for _, blockNum := range sample {
+		require.False(t, seen[blockNum])
+		seen[blockNum] = true
+
+		block, err := bm.GetBlockByNumber(blockNum)
+		require.NoError(t, err)
+		require.False(t, block.IsEmpty())
+	}
+
+	all, err := bm.SampleBlocks(7, 1)
+	require.NoError(t, err)
+	require.ElementsMatch(t, []int{1, 3, 4, 5, 6, 8, 9}, all)
+
+	_, err = bm.SampleBlocks(8, 1)
+	require.Error(t, err)
+}
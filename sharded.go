@@ -0,0 +1,252 @@
+package blockmatrix
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/iterator"
+	"github.com/syndtr/goleveldb/leveldb/util"
+	"hash/fnv"
+	"sync"
+)
+
+// shardIntentKey is the key in the first shard of a ShardedStore holding the writes of a Write spanning several shards
+// until they are applied to every shard.
+var shardIntentKey = []byte("\x00sharded_store:intent")
+
+// ShardedStore is a Store that spreads the entries of a block matrix over several leveldb databases so that reads and
+// writes are not limited by a single database.  Every key is routed to the shard selected by its FNV-1a hash, so the
+// info, keys, blocks, and indexes are always looked up in the shard they were written to, as long as the same
+// databases are passed to NewShardedStore in the same order every time.  Iterate merges the shards in key order.
+//
+// A Write whose entries all route to one shard is applied with a single leveldb batch.  A Write spanning several shards
+// is first recorded in the first shard, then applied shard by shard, and the record is removed once every shard has
+// been written.  If the process stops or a shard fails part way the record is replayed by the next Write, or by
+// NewShardedStore when the shards are reopened, so the writes are eventually applied in full.  Until then the writes
+// applied to the shards that succeeded are visible.
+type ShardedStore struct {
+	shards []*leveldb.DB
+	// mu serializes the writes spanning several shards so their records are replayed in order
+	mu sync.Mutex
+	// pending is true while a write spanning several shards has been recorded but not applied to every shard
+	pending bool
+}
+
+// NewShardedStore returns a store routing keys to the given databases, replaying a write spanning several shards that
+// was interrupted.  Use New to create or open the block matrix stored in it.
+func NewShardedStore(shards ...*leveldb.DB) (*ShardedStore, error) {
+	if len(shards) == 0 {
+		return nil, errors.New("a sharded store needs at least one shard")
+	}
+
+	s := &ShardedStore{shards: shards}
+	if err := s.replayIntent(); err != nil {
+		return nil, fmt.Errorf("error replaying interrupted sharded write: %w", err)
+	}
+
+	return s, nil
+}
+
+// shardIndex returns the index of the shard key is stored in.
+func (s *ShardedStore) shardIndex(key []byte) int {
+	h := fnv.New32a()
+	h.Write(key)
+	return int(h.Sum32() % uint32(len(s.shards)))
+}
+
+func (s *ShardedStore) shard(key []byte) *leveldb.DB {
+	return s.shards[s.shardIndex(key)]
+}
+
+func (s *ShardedStore) Get(key []byte) ([]byte, error) {
+	return s.shard(key).Get(key, nil)
+}
+
+func (s *ShardedStore) Has(key []byte) (bool, error) {
+	return s.shard(key).Has(key, nil)
+}
+
+func (s *ShardedStore) Put(key []byte, value []byte) error {
+	return s.shard(key).Put(key, value, nil)
+}
+
+func (s *ShardedStore) Delete(key []byte) error {
+	return s.shard(key).Delete(key, nil)
+}
+
+func (s *ShardedStore) Write(writes []StoreWrite) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.pending {
+		if err := s.replayIntent(); err != nil {
+			return fmt.Errorf("error replaying interrupted sharded write: %w", err)
+		}
+	}
+
+	batches := s.batches(writes)
+	if len(batches) == 0 {
+		return nil
+	} else if len(batches) == 1 {
+		for i, batch := range batches {
+			return s.shards[i].Write(batch, nil)
+		}
+	}
+
+	if err := s.shards[0].Put(shardIntentKey, encodeShardIntent(writes), nil); err != nil {
+		return err
+	}
+	s.pending = true
+
+	return s.applyIntent(batches)
+}
+
+// batches groups the writes into one batch per shard, keeping their order within each shard.
+func (s *ShardedStore) batches(writes []StoreWrite) map[int]*leveldb.Batch {
+	batches := make(map[int]*leveldb.Batch)
+	for _, write := range writes {
+		i := s.shardIndex(write.Key)
+		if batches[i] == nil {
+			batches[i] = new(leveldb.Batch)
+		}
+
+		if write.Delete {
+			batches[i].Delete(write.Key)
+		} else {
+			batches[i].Put(write.Key, write.Value)
+		}
+	}
+
+	return batches
+}
+
+// applyIntent writes each batch to its shard and then removes the recorded intent.  Applying the batches again after a
+// failure is harmless since they only put and delete whole entries.
+func (s *ShardedStore) applyIntent(batches map[int]*leveldb.Batch) error {
+	for i, batch := range batches {
+		if err := s.shards[i].Write(batch, nil); err != nil {
+			return err
+		}
+	}
+
+	if err := s.shards[0].Delete(shardIntentKey, nil); err != nil {
+		return err
+	}
+	s.pending = false
+
+	return nil
+}
+
+// replayIntent applies the writes recorded by a write spanning several shards that did not complete.
+func (s *ShardedStore) replayIntent() error {
+	record, err := s.shards[0].Get(shardIntentKey, nil)
+	if errors.Is(err, leveldb.ErrNotFound) {
+		s.pending = false
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	writes, err := decodeShardIntent(record)
+	if err != nil {
+		return err
+	}
+
+	s.pending = true
+	return s.applyIntent(s.batches(writes))
+}
+
+// Iterate merges the entries of every shard in key order.
+func (s *ShardedStore) Iterate(prefix []byte, fn func(key []byte, value []byte) error) error {
+	iters := make([]iterator.Iterator, len(s.shards))
+	valid := make([]bool, len(s.shards))
+	for i, shard := range s.shards {
+		iters[i] = shard.NewIterator(util.BytesPrefix(prefix), nil)
+		defer iters[i].Release()
+		valid[i] = iters[i].Next()
+	}
+
+	for {
+		next := -1
+		for i, iter := range iters {
+			if valid[i] && (next < 0 || bytes.Compare(iter.Key(), iters[next].Key()) < 0) {
+				next = i
+			}
+		}
+
+		if next < 0 {
+			break
+		}
+
+		iter := iters[next]
+		if next != 0 || !bytes.Equal(iter.Key(), shardIntentKey) {
+			if err := fn(iter.Key(), iter.Value()); err != nil {
+				return err
+			}
+		}
+		valid[next] = iter.Next()
+	}
+
+	for _, iter := range iters {
+		if err := iter.Error(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// encodeShardIntent serializes writes as a sequence of records, each a flag byte that is 1 for a delete, followed by
+// the uvarint length prefixed key and value.
+func encodeShardIntent(writes []StoreWrite) []byte {
+	buf := make([]byte, 0)
+	for _, write := range writes {
+		if write.Delete {
+			buf = append(buf, 1)
+		} else {
+			buf = append(buf, 0)
+		}
+
+		buf = appendUvarint(buf, uint64(len(write.Key)))
+		buf = append(buf, write.Key...)
+		buf = appendUvarint(buf, uint64(len(write.Value)))
+		buf = append(buf, write.Value...)
+	}
+
+	return buf
+}
+
+// decodeShardIntent reverses encodeShardIntent.
+func decodeShardIntent(record []byte) ([]StoreWrite, error) {
+	writes := make([]StoreWrite, 0)
+	next := func() ([]byte, bool) {
+		n, size := binary.Uvarint(record)
+		if size <= 0 || uint64(len(record)-size) < n {
+			return nil, false
+		}
+
+		field := record[size : size+int(n)]
+		record = record[size+int(n):]
+		return field, true
+	}
+
+	for len(record) > 0 {
+		write := StoreWrite{Delete: record[0] == 1}
+		record = record[1:]
+
+		var ok bool
+		if write.Key, ok = next(); !ok {
+			return nil, errors.New("sharded write record is malformed")
+		}
+
+		if write.Value, ok = next(); !ok {
+			return nil, errors.New("sharded write record is malformed")
+		}
+
+		writes = append(writes, write)
+	}
+
+	return writes, nil
+}
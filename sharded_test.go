@@ -0,0 +1,96 @@
+package blockmatrix
+
+import (
+	"github.com/stretchr/testify/require"
+	"github.com/syndtr/goleveldb/leveldb"
+	"testing"
+)
+
+// shardTestCycle adds, updates, erases, and deletes blocks and returns the resulting root hash, keys, and matrix.
+func shardTestCycle(t *testing.T, bm *BlockMatrix) ([]byte, []string, [][]*Block) {
+	require.NoError(t, createTestBlocks(bm, 20))
+	require.NoError(t, bm.UpdateBlock("key3", []byte("updated")))
+	require.NoError(t, bm.EraseBlock("key5"))
+	require.NoError(t, bm.DeleteBlock("key11"))
+	require.NoError(t, bm.AddBlock("reused", []byte("reused")))
+	require.NoError(t, bm.AddAlias("key7", "seventh"))
+
+	tx := bm.Begin()
+	require.NoError(t, tx.AddBlock("tx", []byte("tx")))
+	require.NoError(t, tx.EraseBlock("seventh"))
+	require.NoError(t, tx.Commit())
+
+	ok, err := bm.IsValid()
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	root, err := bm.RootHash()
+	require.NoError(t, err)
+	keys, err := bm.Keys()
+	require.NoError(t, err)
+	matrix, err := bm.Matrix()
+	require.NoError(t, err)
+
+	return root, keys, matrix
+}
+
+func TestShardedStore(t *testing.T) {
+	single, err := NewWithLevelDB(newTestDB(t), WithClock(testClock))
+	require.NoError(t, err)
+	root, keys, matrix := shardTestCycle(t, single)
+
+	shards := []*leveldb.DB{newTestDB(t), newTestDB(t), newTestDB(t), newTestDB(t)}
+	store, err := NewShardedStore(shards...)
+	require.NoError(t, err)
+	sharded, err := New(store, WithClock(testClock))
+	require.NoError(t, err)
+	shardedRoot, shardedKeys, shardedMatrix := shardTestCycle(t, sharded)
+	require.Equal(t, root, shardedRoot)
+	require.Equal(t, keys, shardedKeys)
+	require.Equal(t, matrix, shardedMatrix)
+
+	// every shard holds part of the block matrix and no write is left pending
+	for _, shard := range shards {
+		iter := shard.NewIterator(nil, nil)
+		require.True(t, iter.Next())
+		iter.Release()
+
+		ok, err := shard.Has(shardIntentKey, nil)
+		require.NoError(t, err)
+		require.False(t, ok)
+	}
+
+	// reopening the shards reopens the block matrix
+	store, err = NewShardedStore(shards...)
+	require.NoError(t, err)
+	reopened, err := New(store)
+	require.NoError(t, err)
+	reopenedRoot, err := reopened.RootHash()
+	require.NoError(t, err)
+	require.Equal(t, root, reopenedRoot)
+}
+
+func TestShardedStoreInterruptedWrite(t *testing.T) {
+	shards := []*leveldb.DB{newTestDB(t), newTestDB(t), newTestDB(t)}
+	store, err := NewShardedStore(shards...)
+	require.NoError(t, err)
+
+	// a write spanning the shards that was recorded but never applied
+	writes := make([]StoreWrite, 0)
+	for _, key := range []string{"a", "b", "c", "d", "e", "f"} {
+		writes = append(writes, StoreWrite{Key: []byte(key), Value: []byte(key)})
+	}
+	writes = append(writes, StoreWrite{Key: []byte("a"), Delete: true})
+	require.Greater(t, len(store.batches(writes)), 1)
+	require.NoError(t, shards[0].Put(shardIntentKey, encodeShardIntent(writes), nil))
+
+	store, err = NewShardedStore(shards...)
+	require.NoError(t, err)
+
+	entries := make([]string, 0)
+	require.NoError(t, store.Iterate(nil, func(key []byte, value []byte) error {
+		entries = append(entries, string(key))
+		return nil
+	}))
+	require.Equal(t, []string{"b", "c", "d", "e", "f"}, entries)
+}
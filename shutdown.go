@@ -0,0 +1,67 @@
+package blockmatrix
+
+import (
+	"bytes"
+	"errors"
+)
+
+// cleanShutdownKey is the database key of the clean shutdown flag.  The flag is cleared before the first write after
+// the block matrix is opened and set again by Close, so a cleared flag on open means the last writer didn't close the
+// block matrix.
+const cleanShutdownKey = "clean_shutdown"
+
+var (
+	cleanShutdown   = []byte{1}
+	uncleanShutdown = []byte{0}
+)
+
+// WasCleanlyClosed returns true if the block matrix was closed with Close after it was last written to, as recorded
+// when it was opened.  False means the process writing the block matrix may have crashed part way through a change and
+// the block matrix should be checked with Validate or VerifyReconstructable before it is trusted.  Block matrices
+// created before the flag was introduced are reported as cleanly closed.
+func (b *BlockMatrix) WasCleanlyClosed() (bool, error) {
+	return b.cleanlyClosed, nil
+}
+
+// readCleanShutdown reads the clean shutdown flag, a missing flag is clean.
+func (b *BlockMatrix) readCleanShutdown() (bool, error) {
+	value, err := b.get([]byte(cleanShutdownKey))
+	if errors.Is(err, ErrNotFound) {
+		return true, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	return bytes.Equal(value, cleanShutdown), nil
+}
+
+// markUnclean clears the clean shutdown flag if it is set.  It is called before every write.
+func (b *BlockMatrix) markUnclean() error {
+	if b.unclean {
+		return nil
+	}
+
+	if err := b.store.Put([]byte(cleanShutdownKey), uncleanShutdown); err != nil {
+		return storageErr(err)
+	}
+
+	b.unclean = true
+	return nil
+}
+
+// markClean sets the clean shutdown flag if it is cleared.
+func (b *BlockMatrix) markClean() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.unclean {
+		return nil
+	}
+
+	if err := b.store.Put([]byte(cleanShutdownKey), cleanShutdown); err != nil {
+		return storageErr(err)
+	}
+
+	b.unclean = false
+	return nil
+}
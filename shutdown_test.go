@@ -0,0 +1,56 @@
+package blockmatrix
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestWasCleanlyClosed(t *testing.T) {
+	db := newTestDB(t)
+	bm, err := NewWithLevelDB(db)
+	require.NoError(t, err)
+	ok, err := bm.WasCleanlyClosed()
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	require.NoError(t, createTestBlocks(bm, 4))
+	require.NoError(t, bm.Close())
+
+	bm, err = NewWithLevelDB(db)
+	require.NoError(t, err)
+	ok, err = bm.WasCleanlyClosed()
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	// write without closing to simulate a crash
+	require.NoError(t, bm.AddBlock("key5", []byte{5}))
+
+	bm, err = NewWithLevelDB(db)
+	require.NoError(t, err)
+	ok, err = bm.WasCleanlyClosed()
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	// closing after the crash sets the flag even without writing
+	require.NoError(t, bm.Close())
+	bm, err = NewWithLevelDB(db)
+	require.NoError(t, err)
+	ok, err = bm.WasCleanlyClosed()
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	tx := bm.Begin()
+	require.NoError(t, tx.AddBlock("key6", []byte{6}))
+	require.NoError(t, tx.Commit())
+	require.NoError(t, bm.Close())
+
+	bm, err = NewWithLevelDB(db)
+	require.NoError(t, err)
+	ok, err = bm.WasCleanlyClosed()
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	valid, err := bm.VerifyReconstructable()
+	require.NoError(t, err)
+	require.True(t, valid)
+}
@@ -0,0 +1,85 @@
+//go:build sqlite
+// +build sqlite
+
+package blockmatrix
+
+import (
+	"database/sql"
+	"fmt"
+	_ "modernc.org/sqlite"
+)
+
+// ExportSQLite writes the block matrix to a new SQLite database at the given path.  The database has a matrix_info
+// table with a single row holding the size, block count, and root hash, and a blocks table with one row per block
+// holding its number, row, column, key, data, hash, and whether it was erased.  Erased blocks have a NULL key.
+//
+// ExportSQLite uses the pure Go modernc.org/sqlite driver and is only built with the sqlite build tag:
+//
+//	go get modernc.org/sqlite
+//	go build -tags sqlite
+func (b *BlockMatrix) ExportSQLite(path string) error {
+	info, err := b.GetBlockMatrixInfo()
+	if err != nil {
+		return err
+	}
+
+	keys, err := b.blockKeys()
+	if err != nil {
+		return err
+	}
+
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return fmt.Errorf("error opening sqlite database: %w", err)
+	}
+	defer db.Close()
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err = tx.Exec(`CREATE TABLE matrix_info (size INTEGER NOT NULL, block_count INTEGER NOT NULL, root BLOB NOT NULL)`); err != nil {
+		return fmt.Errorf("error creating matrix_info table: %w", err)
+	}
+
+	if _, err = tx.Exec(`INSERT INTO matrix_info (size, block_count, root) VALUES (?, ?, ?)`,
+		info.Size, info.BlockCount, b.rootHash(info)); err != nil {
+		return fmt.Errorf("error inserting matrix info: %w", err)
+	}
+
+	if _, err = tx.Exec(`CREATE TABLE blocks (num INTEGER PRIMARY KEY, row INTEGER NOT NULL, col INTEGER NOT NULL, key TEXT, data BLOB NOT NULL, hash BLOB NOT NULL, erased INTEGER NOT NULL)`); err != nil {
+		return fmt.Errorf("error creating blocks table: %w", err)
+	}
+
+	stmt, err := tx.Prepare(`INSERT INTO blocks (num, row, col, key, data, hash, erased) VALUES (?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	last, err := b.lastBlock(info)
+	if err != nil {
+		return err
+	}
+
+	for blockNum := 1; blockNum <= last; blockNum++ {
+		block, err := b.GetBlockByNumber(blockNum)
+		if err != nil {
+			return err
+		}
+
+		var key interface{}
+		if k, ok := keys[blockNum]; ok {
+			key = k
+		}
+
+		row, col := locateBlock(blockNum)
+		if _, err = stmt.Exec(blockNum, row, col, key, block.Data, block.Hash, block.IsEmpty()); err != nil {
+			return fmt.Errorf("error inserting block %d: %w", blockNum, err)
+		}
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,53 @@
+//go:build sqlite
+// +build sqlite
+
+package blockmatrix
+
+import (
+	"database/sql"
+	"github.com/stretchr/testify/require"
+	"path/filepath"
+	"testing"
+)
+
+func TestExportSQLite(t *testing.T) {
+	bm, err := NewWithLevelDB(newTestDB(t))
+	require.NoError(t, err)
+	require.NoError(t, createTestBlocks(bm, 5))
+	require.NoError(t, bm.EraseBlock("key2"))
+
+	path := filepath.Join(t.TempDir(), "matrix.sqlite")
+	require.NoError(t, bm.ExportSQLite(path))
+
+	db, err := sql.Open("sqlite", path)
+	require.NoError(t, err)
+	defer db.Close()
+
+	var size, blockCount int
+	require.NoError(t, db.QueryRow(`SELECT size, block_count FROM matrix_info`).Scan(&size, &blockCount))
+	require.Equal(t, 3, size)
+	require.Equal(t, 5, blockCount)
+
+	var count int
+	require.NoError(t, db.QueryRow(`SELECT COUNT(*) FROM blocks`).Scan(&count))
+	require.Equal(t, 5, count)
+
+	var (
+		key      sql.NullString
+		data     []byte
+		row, col int
+		erased   bool
+	)
+	require.NoError(t, db.QueryRow(`SELECT key, data, row, col, erased FROM blocks WHERE num = 3`).
+		Scan(&key, &data, &row, &col, &erased))
+	require.Equal(t, "key3", key.String)
+	require.Equal(t, []byte{3}, data)
+	expectedRow, expectedCol := locateBlock(3)
+	require.Equal(t, expectedRow, row)
+	require.Equal(t, expectedCol, col)
+	require.False(t, erased)
+
+	require.NoError(t, db.QueryRow(`SELECT key, erased FROM blocks WHERE num = 2`).Scan(&key, &erased))
+	require.False(t, key.Valid)
+	require.True(t, erased)
+}
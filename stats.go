@@ -0,0 +1,207 @@
+package blockmatrix
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+// BlockSize is the stored size of a block.
+type BlockSize struct {
+	// Key is the key the block was added with
+	Key string `json:"key"`
+	// BlockNumber is the number of the block
+	BlockNumber int `json:"block_number"`
+	// Size is the number of bytes the block takes up in the database
+	Size int `json:"size"`
+}
+
+// KeyspaceStats counts the database entries of a block matrix by category.
+type KeyspaceStats struct {
+	// Total is the number of entries in the database
+	Total int `json:"total"`
+	// Info is the number of info entries, 1 for a block matrix
+	Info int `json:"info"`
+	// Blocks is the number of block entries, one for every written slot up to the capacity of the block matrix
+	Blocks int `json:"blocks"`
+	// Keys is the number of entries mapping a key to its block number
+	Keys int `json:"keys"`
+	// Internal is the number of bookkeeping entries by prefix, such as the original keys of transformed keys, reserved
+	// block markers, quarantined blocks, and the change log
+	Internal map[string]int `json:"internal"`
+	// Other is the number of entries that do not belong to any category, which are not written by the block matrix
+	Other int `json:"other"`
+}
+
+// RowFillCounts returns the number of live blocks in each row of the block matrix.  Empty and erased blocks are not
+// counted.
+func (b *BlockMatrix) RowFillCounts() ([]int, error) {
+	rows, _, err := b.fillCounts()
+	return rows, err
+}
+
+// ColumnFillCounts returns the number of live blocks in each column of the block matrix.  Empty and erased blocks are
+// not counted.
+func (b *BlockMatrix) ColumnFillCounts() ([]int, error) {
+	_, cols, err := b.fillCounts()
+	return cols, err
+}
+
+func (b *BlockMatrix) fillCounts() ([]int, []int, error) {
+	matrix, err := b.Matrix()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rows := make([]int, len(matrix))
+	cols := make([]int, len(matrix))
+	for i := range matrix {
+		for j, block := range matrix[i] {
+			if i == j || block.IsEmpty() {
+				continue
+			}
+
+			rows[i]++
+			cols[j]++
+		}
+	}
+
+	return rows, cols, nil
+}
+
+// LargestBlocks returns the n blocks that take up the most space in the database, largest first.  Only blocks with a
+// key are considered, blocks of the same size are ordered by block number.
+func (b *BlockMatrix) LargestBlocks(n int) ([]BlockSize, error) {
+	sizes, err := b.blockSizes()
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(sizes, func(i, j int) bool {
+		return sizes[i].Size > sizes[j].Size
+	})
+
+	return firstBlockSizes(sizes, n), nil
+}
+
+// SmallestBlocks returns the n blocks that take up the least space in the database, smallest first.  Only blocks with a
+// key are considered, blocks of the same size are ordered by block number.
+func (b *BlockMatrix) SmallestBlocks(n int) ([]BlockSize, error) {
+	sizes, err := b.blockSizes()
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(sizes, func(i, j int) bool {
+		return sizes[i].Size < sizes[j].Size
+	})
+
+	return firstBlockSizes(sizes, n), nil
+}
+
+// blockSizes returns the stored size of every block with a key, in block number order.
+func (b *BlockMatrix) blockSizes() ([]BlockSize, error) {
+	keys, err := b.blockKeys()
+	if err != nil {
+		return nil, err
+	}
+
+	sizes := make([]BlockSize, 0, len(keys))
+	for blockNum, key := range keys {
+		bytes, err := b.get([]byte(fmt.Sprint(blockNum)))
+		if err != nil {
+			return nil, err
+		}
+
+		sizes = append(sizes, BlockSize{Key: key, BlockNumber: blockNum, Size: len(bytes)})
+	}
+
+	sort.Slice(sizes, func(i, j int) bool {
+		return sizes[i].BlockNumber < sizes[j].BlockNumber
+	})
+
+	return sizes, nil
+}
+
+func firstBlockSizes(sizes []BlockSize, n int) []BlockSize {
+	if n < len(sizes) {
+		return sizes[:n]
+	}
+
+	return sizes
+}
+
+// DeduplicationReport estimates how much space storing block data by content would save.  Every block that is not empty
+// is grouped with the blocks whose data has the same SHA-256 hash, regardless of their metadata.  uniqueBlocks is the
+// number of groups, totalBlocks the number of blocks, and savedBytes the length of the data of every block beyond the
+// first in its group, the data that would no longer be stored.  The estimate does not account for the block encoding or
+// codecs.
+func (b *BlockMatrix) DeduplicationReport() (uniqueBlocks int, totalBlocks int, savedBytes int64, err error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	seen := make(map[[sha256.Size]byte]bool)
+	err = b.forEachBlock(func(blockNum int, block *Block) error {
+		totalBlocks++
+
+		sum := sha256.Sum256(block.Data)
+		if seen[sum] {
+			savedBytes += int64(len(block.Data))
+			return nil
+		}
+
+		seen[sum] = true
+		uniqueBlocks++
+		return nil
+	})
+	if err != nil {
+		return 0, 0, 0, err
+	}
+
+	return uniqueBlocks, totalBlocks, savedBytes, nil
+}
+
+// KeyspaceStats scans the database and counts its entries by category.  Every entry is counted in exactly one category
+// so the categories add up to the total.
+func (b *BlockMatrix) KeyspaceStats() (*KeyspaceStats, error) {
+	stats := &KeyspaceStats{Internal: make(map[string]int, len(internalPrefixes))}
+	for _, prefix := range internalPrefixes {
+		stats.Internal[prefix] = 0
+	}
+
+	err := b.iterate(nil, func(key []byte, value []byte) error {
+		stats.Total++
+		stats.count(key, value)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
+
+func (s *KeyspaceStats) count(key []byte, value []byte) {
+	if bytes.Equal(key, InfoKey) {
+		s.Info++
+		return
+	}
+
+	for _, prefix := range internalPrefixes {
+		if bytes.HasPrefix(key, []byte(prefix)) {
+			s.Internal[prefix]++
+			return
+		}
+	}
+
+	// block entries are keyed by their number, key entries hold the number of their block
+	if _, err := strconv.Atoi(string(key)); err == nil {
+		s.Blocks++
+	} else if _, err = strconv.Atoi(string(value)); err == nil {
+		s.Keys++
+	} else {
+		s.Other++
+	}
+}
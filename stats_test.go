@@ -0,0 +1,130 @@
+package blockmatrix
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+func TestFillCounts(t *testing.T) {
+	bm, err := NewWithLevelDB(newTestDB(t))
+	require.NoError(t, err)
+	require.NoError(t, createTestBlocks(bm, 6))
+
+	rows, err := bm.RowFillCounts()
+	require.NoError(t, err)
+	require.Equal(t, []int{2, 2, 2}, rows)
+	cols, err := bm.ColumnFillCounts()
+	require.NoError(t, err)
+	require.Equal(t, []int{2, 2, 2}, cols)
+
+	// block 1 is at (0, 1) and block 6 is at (2, 1)
+	require.NoError(t, bm.EraseBlock("key1"))
+	require.NoError(t, bm.EraseBlock("key6"))
+
+	rows, err = bm.RowFillCounts()
+	require.NoError(t, err)
+	require.Equal(t, []int{1, 2, 1}, rows)
+	cols, err = bm.ColumnFillCounts()
+	require.NoError(t, err)
+	require.Equal(t, []int{2, 0, 2}, cols)
+}
+
+func TestLargestAndSmallestBlocks(t *testing.T) {
+	bm, err := NewWithLevelDB(newTestDB(t))
+	require.NoError(t, err)
+	for i, size := range []int{10, 300, 20, 300, 5} {
+		require.NoError(t, bm.AddBlock(fmt.Sprintf("key%d", i+1), make([]byte, size)))
+	}
+	require.NoError(t, bm.EraseBlock("key5"))
+
+	largest, err := bm.LargestBlocks(3)
+	require.NoError(t, err)
+	require.Len(t, largest, 3)
+	require.Equal(t, []string{"key2", "key4", "key3"}, blockSizeKeys(largest))
+	require.Equal(t, []int{2, 4, 3}, []int{largest[0].BlockNumber, largest[1].BlockNumber, largest[2].BlockNumber})
+	require.Equal(t, largest[0].Size, largest[1].Size)
+	require.Greater(t, largest[1].Size, largest[2].Size)
+
+	smallest, err := bm.SmallestBlocks(2)
+	require.NoError(t, err)
+	require.Equal(t, []string{"key1", "key3"}, blockSizeKeys(smallest))
+	require.Less(t, smallest[0].Size, smallest[1].Size)
+
+	all, err := bm.SmallestBlocks(10)
+	require.NoError(t, err)
+	require.Len(t, all, 4)
+}
+
+func TestKeyspaceStats(t *testing.T) {
+	bm, err := NewWithLevelDB(newTestDB(t), WithKeyTransform(strings.ToUpper))
+	require.NoError(t, err)
+	require.NoError(t, createTestBlocks(bm, 8))
+	_, err = bm.ReserveBlock("reserved")
+	require.NoError(t, err)
+	require.NoError(t, bm.EraseBlock("key3"))
+
+	stats, err := bm.KeyspaceStats()
+	require.NoError(t, err)
+	require.Equal(t, 1, stats.Info)
+	require.Equal(t, 12, stats.Blocks)
+	require.Equal(t, 8, stats.Keys)
+	require.Equal(t, 8, stats.Internal[originalKeyPrefix])
+	require.Equal(t, 1, stats.Internal[reservedPrefix])
+	require.Equal(t, 0, stats.Internal[quarantinePrefix])
+	require.Greater(t, stats.Internal[changeLogPrefix], 0)
+	require.Equal(t, 0, stats.Other)
+
+	sum := stats.Info + stats.Blocks + stats.Keys + stats.Other
+	for _, n := range stats.Internal {
+		sum += n
+	}
+	require.Equal(t, stats.Total, sum)
+
+	total := 0
+	require.NoError(t, bm.store.Iterate(nil, func(key []byte, value []byte) error {
+		total++
+		return nil
+	}))
+	require.Equal(t, total, stats.Total)
+}
+
+func blockSizeKeys(sizes []BlockSize) []string {
+	keys := make([]string, len(sizes))
+	for i, size := range sizes {
+		keys[i] = size.Key
+	}
+
+	return keys
+}
+
+func TestDeduplicationReport(t *testing.T) {
+	bm, err := New(NewMemStore())
+	require.NoError(t, err)
+
+	unique, total, saved, err := bm.DeduplicationReport()
+	require.NoError(t, err)
+	require.Equal(t, []interface{}{0, 0, int64(0)}, []interface{}{unique, total, saved})
+
+	payloads := map[string][]byte{
+		"a1": []byte(strings.Repeat("a", 100)),
+		"a2": []byte(strings.Repeat("a", 100)),
+		"a3": []byte(strings.Repeat("a", 100)),
+		"b1": []byte(strings.Repeat("b", 40)),
+		"b2": []byte(strings.Repeat("b", 40)),
+		"c1": []byte("c"),
+		"d1": []byte("d"),
+	}
+	require.NoError(t, bm.AddBlocks(payloads))
+	// the same data with metadata is still a duplicate
+	require.NoError(t, bm.AddBlockWithMetadata("c2", []byte("c"), map[string]string{"source": "test"}))
+	// erased blocks are not counted
+	require.NoError(t, bm.EraseBlock("d1"))
+
+	unique, total, saved, err = bm.DeduplicationReport()
+	require.NoError(t, err)
+	require.Equal(t, 3, unique)
+	require.Equal(t, 7, total)
+	require.Equal(t, int64(2*100+40+1), saved)
+}
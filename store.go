@@ -0,0 +1,138 @@
+package blockmatrix
+
+import (
+	"fmt"
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/storage"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+type (
+	// Store is the key-value storage a block matrix is kept in.  Get, Has, Put, and Delete mirror the leveldb calls the
+	// block matrix makes, Get returns ErrNotFound for a missing key.  Write applies a set of writes atomically and Iterate
+	// lists entries in key order.  Reads and writes may be made from inside an Iterate callback.
+	Store interface {
+		Get(key []byte) ([]byte, error)
+		Has(key []byte) (bool, error)
+		Put(key []byte, value []byte) error
+		Delete(key []byte) error
+		// Write applies the writes in order, either all of them or none
+		Write(writes []StoreWrite) error
+		// Iterate calls fn with every entry whose key starts with prefix in ascending key order, stopping at and
+		// returning the first error fn returns.  The key and value must not be used after fn returns.
+		Iterate(prefix []byte, fn func(key []byte, value []byte) error) error
+	}
+
+	// Snapshotter is implemented by stores that can take a read-only view of their entries as of a single point in time.
+	// The block matrix uses it, where the store provides it, to read several entries consistently without locking.
+	Snapshotter interface {
+		// Snapshot returns a view of the store's entries that later writes don't change, it must be released when done
+		Snapshot() (StoreSnapshot, error)
+	}
+
+	// StoreSnapshot is a read-only view of a store's entries taken by Snapshotter.  Get returns ErrNotFound for a missing
+	// key.
+	StoreSnapshot interface {
+		Get(key []byte) ([]byte, error)
+		Release()
+	}
+
+	// StoreWrite is a single put, or delete if Delete is true, applied by Store.Write.
+	StoreWrite struct {
+		Key    []byte
+		Value  []byte
+		Delete bool
+	}
+
+	// leveldbStore adapts a leveldb database to the Store interface.
+	leveldbStore struct {
+		db *leveldb.DB
+	}
+
+	// leveldbSnapshot adapts a leveldb snapshot to the StoreSnapshot interface.
+	leveldbSnapshot struct {
+		snapshot *leveldb.Snapshot
+	}
+)
+
+// NewWithLevelDB creates a new block matrix stored in the given leveldb database, see New.
+func NewWithLevelDB(db *leveldb.DB, opts ...Option) (*BlockMatrix, error) {
+	return New(&leveldbStore{db: db}, opts...)
+}
+
+// NewInMemory creates a new block matrix in a leveldb database kept in memory, for tests and block matrices that don't
+// outlive the process, without touching the filesystem.  The database is closed, and its contents discarded, by Close.
+func NewInMemory(opts ...Option) (*BlockMatrix, error) {
+	db, err := leveldb.Open(storage.NewMemStorage(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("error opening in-memory database: %w", err)
+	}
+
+	bm, err := NewWithLevelDB(db, opts...)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	bm.ownedDB = db
+	return bm, nil
+}
+
+func (s *leveldbStore) Get(key []byte) ([]byte, error) {
+	return s.db.Get(key, nil)
+}
+
+func (s *leveldbStore) Has(key []byte) (bool, error) {
+	return s.db.Has(key, nil)
+}
+
+func (s *leveldbStore) Put(key []byte, value []byte) error {
+	return s.db.Put(key, value, nil)
+}
+
+func (s *leveldbStore) Delete(key []byte) error {
+	return s.db.Delete(key, nil)
+}
+
+func (s *leveldbStore) Write(writes []StoreWrite) error {
+	batch := new(leveldb.Batch)
+	for _, write := range writes {
+		if write.Delete {
+			batch.Delete(write.Key)
+		} else {
+			batch.Put(write.Key, write.Value)
+		}
+	}
+
+	return s.db.Write(batch, nil)
+}
+
+func (s *leveldbStore) Iterate(prefix []byte, fn func(key []byte, value []byte) error) error {
+	iter := s.db.NewIterator(util.BytesPrefix(prefix), nil)
+	defer iter.Release()
+
+	for iter.Next() {
+		if err := fn(iter.Key(), iter.Value()); err != nil {
+			return err
+		}
+	}
+
+	return iter.Error()
+}
+
+func (s *leveldbStore) Snapshot() (StoreSnapshot, error) {
+	snapshot, err := s.db.GetSnapshot()
+	if err != nil {
+		return nil, err
+	}
+
+	return &leveldbSnapshot{snapshot: snapshot}, nil
+}
+
+func (s *leveldbSnapshot) Get(key []byte) ([]byte, error) {
+	return s.snapshot.Get(key, nil)
+}
+
+func (s *leveldbSnapshot) Release() {
+	s.snapshot.Release()
+}
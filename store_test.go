@@ -0,0 +1,95 @@
+package blockmatrix
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestStore(t *testing.T) {
+	store := NewMemStore()
+	bm, err := New(store)
+	require.NoError(t, err)
+	require.NoError(t, createTestBlocks(bm, 9))
+	root, err := bm.RootHash()
+	require.NoError(t, err)
+	require.NoError(t, bm.EraseBlock("key4"))
+	require.NoError(t, bm.UpdateBlock("key5", []byte("updated")))
+
+	tx := bm.Begin()
+	require.NoError(t, tx.AddBlock("key10", []byte{10}))
+	require.NoError(t, tx.EraseBlock("key2"))
+	require.NoError(t, tx.Commit())
+
+	block, err := bm.GetBlock("key5")
+	require.NoError(t, err)
+	require.Equal(t, []byte("updated"), block.Data)
+	_, err = bm.GetBlock("key2")
+	require.Error(t, err)
+
+	ok, err := bm.IsValid()
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	changes, err := bm.ChangesSince(root)
+	require.NoError(t, err)
+	require.Equal(t, []int{2, 4, 5, 10}, changes)
+
+	stats, err := bm.KeyspaceStats()
+	require.NoError(t, err)
+	require.Equal(t, 1, stats.Info)
+	require.Equal(t, 8, stats.Keys)
+
+	reopened, err := New(store)
+	require.NoError(t, err)
+	matrix, err := bm.Matrix()
+	require.NoError(t, err)
+	reopenedMatrix, err := reopened.Matrix()
+	require.NoError(t, err)
+	require.Equal(t, matrix, reopenedMatrix)
+}
+
+func TestNewInMemory(t *testing.T) {
+	bm, err := NewInMemory()
+	require.NoError(t, err)
+	require.NoError(t, createTestBlocks(bm, 9))
+
+	ok, err := bm.IsValid()
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	require.NoError(t, bm.EraseBlock("key4"))
+	ok, err = bm.IsValid()
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	// each in-memory block matrix has its own database
+	other, err := NewInMemory()
+	require.NoError(t, err)
+	count, err := other.BlockCount()
+	require.NoError(t, err)
+	require.Equal(t, 0, count)
+
+	require.NoError(t, bm.Close())
+	_, err = bm.GetBlock("key1")
+	require.ErrorIs(t, err, ErrStorage)
+	require.NoError(t, other.Close())
+}
+
+func TestLevelDBSnapshot(t *testing.T) {
+	var store Store = &leveldbStore{db: newTestDB(t)}
+	snapshotter, ok := store.(Snapshotter)
+	require.True(t, ok)
+
+	require.NoError(t, store.Put([]byte("key"), []byte{1}))
+	snapshot, err := snapshotter.Snapshot()
+	require.NoError(t, err)
+	defer snapshot.Release()
+	require.NoError(t, store.Put([]byte("key"), []byte{2}))
+	require.NoError(t, store.Put([]byte("other"), []byte{3}))
+
+	value, err := snapshot.Get([]byte("key"))
+	require.NoError(t, err)
+	require.Equal(t, []byte{1}, value)
+	_, err = snapshot.Get([]byte("other"))
+	require.ErrorIs(t, err, ErrNotFound)
+}
@@ -0,0 +1,69 @@
+package blockmatrix
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+)
+
+// svgCellSize is the width and height in pixels of a cell in the image written by ExportSVG.
+const svgCellSize = 40
+
+// svgStyle colors the cells of the image written by ExportSVG by their status.
+const svgStyle = `.live{fill:#4caf50}.empty{fill:#ffffff}.erased{fill:#e57373}.diagonal{fill:#bdbdbd}` +
+	`rect{stroke:#424242;stroke-width:1}text{font-family:monospace;font-size:12px;text-anchor:middle;` +
+	`dominant-baseline:central}`
+
+// ExportSVG writes the geometry of the block matrix to w as an SVG image of its size x size grid, for documentation
+// and debugging.  Every cell is a rect whose class is its status as reported by ExportGeometry, live, empty, or
+// erased, and non-diagonal cells are labeled with the number of the block they hold.  Diagonal cells, which never hold
+// a block, have the diagonal class and are grey.  The image has no dependencies, the colors are in an embedded style
+// sheet.
+func (b *BlockMatrix) ExportSVG(w io.Writer) error {
+	geometry, err := b.ExportGeometry()
+	if err != nil {
+		return err
+	}
+
+	status := make(map[Cell]string, geometry.Capacity)
+	for _, cells := range []struct {
+		class string
+		cells []Cell
+	}{{"live", geometry.Live}, {"erased", geometry.Erased}, {"empty", geometry.Empty}} {
+		for _, cell := range cells.cells {
+			status[cell] = cells.class
+		}
+	}
+
+	blockNums := make(map[Cell]int, geometry.Capacity)
+	for blockNum := 1; blockNum <= geometry.Capacity; blockNum++ {
+		row, col := locateBlock(blockNum)
+		blockNums[Cell{Row: row, Col: col}] = blockNum
+	}
+
+	width := geometry.Size * svgCellSize
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "<svg xmlns=\"http://www.w3.org/2000/svg\" width=\"%d\" height=\"%d\" viewBox=\"0 0 %d %d\">\n",
+		width, width, width, width)
+	fmt.Fprintf(buf, "<style>%s</style>\n", svgStyle)
+	for row := 0; row < geometry.Size; row++ {
+		for col := 0; col < geometry.Size; col++ {
+			x, y := col*svgCellSize, row*svgCellSize
+			if row == col {
+				fmt.Fprintf(buf, "<rect class=\"diagonal\" x=\"%d\" y=\"%d\" width=\"%d\" height=\"%d\"/>\n", x, y,
+					svgCellSize, svgCellSize)
+				continue
+			}
+
+			cell := Cell{Row: row, Col: col}
+			fmt.Fprintf(buf, "<rect class=\"%s\" x=\"%d\" y=\"%d\" width=\"%d\" height=\"%d\"/>\n", status[cell], x, y,
+				svgCellSize, svgCellSize)
+			fmt.Fprintf(buf, "<text x=\"%d\" y=\"%d\">%d</text>\n", x+svgCellSize/2, y+svgCellSize/2,
+				blockNums[cell])
+		}
+	}
+	fmt.Fprintf(buf, "</svg>\n")
+
+	_, err = w.Write(buf.Bytes())
+	return err
+}
@@ -0,0 +1,45 @@
+package blockmatrix
+
+import (
+	"bytes"
+	"encoding/xml"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestExportSVG(t *testing.T) {
+	bm, err := NewWithLevelDB(newTestDB(t))
+	require.NoError(t, err)
+	require.NoError(t, createTestBlocks(bm, 9))
+	require.NoError(t, bm.EraseBlock("key2"))
+
+	buf := &bytes.Buffer{}
+	require.NoError(t, bm.ExportSVG(buf))
+
+	var image struct {
+		XMLName xml.Name `xml:"svg"`
+		Rects   []struct {
+			Class string `xml:"class,attr"`
+			X     int    `xml:"x,attr"`
+			Y     int    `xml:"y,attr"`
+		} `xml:"rect"`
+		Labels []string `xml:"text"`
+	}
+	require.NoError(t, xml.Unmarshal(buf.Bytes(), &image))
+
+	// a 4x4 matrix holding 9 blocks, the second of which is erased, with 3 empty cells
+	require.Len(t, image.Rects, 16)
+	classes := make(map[string]int)
+	for _, rect := range image.Rects {
+		classes[rect.Class]++
+		if rect.X == rect.Y {
+			require.Equal(t, "diagonal", rect.Class)
+		}
+	}
+	require.Equal(t, map[string]int{"live": 8, "erased": 1, "empty": 3, "diagonal": 4}, classes)
+
+	// block 2 is at row 1 and column 0
+	require.Equal(t, "erased", image.Rects[4].Class)
+	require.Len(t, image.Labels, 12)
+	require.Equal(t, "2", image.Labels[3])
+}
@@ -0,0 +1,368 @@
+package blockmatrix
+
+import (
+	"archive/tar"
+	"encoding/json"
+	"fmt"
+	"github.com/syndtr/goleveldb/leveldb"
+	"io"
+	"io/ioutil"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	tarInfoEntry   = "info.json"
+	tarKeysEntry   = "keys.json"
+	tarBlockPrefix = "blocks/"
+	tarBlockSuffix = ".json"
+)
+
+// matrixArchive is the content of a block matrix archive, read from a tar archive or a JSON export.
+type matrixArchive struct {
+	info   *BlockMatrixInfo
+	keys   map[string]int
+	blocks map[int]*Block
+}
+
+// ExportTar writes the block matrix to w as a tar archive that can be inspected with standard tools.  The archive holds
+// the block matrix info in info.json, the key to block number index in keys.json, and every block up to the capacity of
+// the block matrix as JSON in blocks/<block number>.json.  Use ImportTar to rebuild a block matrix from the archive.
+func (b *BlockMatrix) ExportTar(w io.Writer) error {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	archive, err := b.readArchive()
+	if err != nil {
+		return err
+	}
+
+	tw := tar.NewWriter(w)
+
+	if err = b.writeTarEntry(tw, tarInfoEntry, archive.info); err != nil {
+		return err
+	}
+
+	if err = b.writeTarEntry(tw, tarKeysEntry, archive.keys); err != nil {
+		return err
+	}
+
+	for blockNum := 1; blockNum <= capacity(archive.info.Size); blockNum++ {
+		if err = b.writeTarEntry(tw, tarBlockEntry(blockNum), archive.blocks[blockNum]); err != nil {
+			return err
+		}
+	}
+
+	return tw.Close()
+}
+
+// readArchive returns the info, the key to block number index, and every block up to the capacity of the block matrix
+// for an export.  The caller holds the read lock so the archive is a consistent snapshot.
+func (b *BlockMatrix) readArchive() (*matrixArchive, error) {
+	info, err := b.GetBlockMatrixInfo()
+	if err != nil {
+		return nil, err
+	}
+
+	blockKeys, err := b.blockKeys()
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]int, len(blockKeys))
+	for blockNum, key := range blockKeys {
+		keys[key] = blockNum
+	}
+
+	blocks, err := b.getBlocksByNumbers(blockRange(capacity(info.Size)))
+	if err != nil {
+		return nil, err
+	}
+
+	// the MAC is specific to the key of this block matrix, the importing block matrix computes its own
+	for _, block := range blocks {
+		block.MAC = nil
+	}
+
+	return &matrixArchive{info: info, keys: keys, blocks: blocks}, nil
+}
+
+// writeTarEntry writes v encoded as JSON to the archive under the given name.
+func (b *BlockMatrix) writeTarEntry(tw *tar.Writer, name string, v interface{}) error {
+	bytes, err := b.marshalJSON(v)
+	if err != nil {
+		return err
+	}
+
+	if err = tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(bytes))}); err != nil {
+		return fmt.Errorf("error writing tar header for %s: %w", name, err)
+	}
+
+	if _, err = tw.Write(bytes); err != nil {
+		return fmt.Errorf("error writing tar entry %s: %w", name, err)
+	}
+
+	return nil
+}
+
+func tarBlockEntry(blockNum int) string {
+	return tarBlockPrefix + strconv.Itoa(blockNum) + tarBlockSuffix
+}
+
+// ImportTar creates a block matrix in the given database from an archive written by ExportTar.  The archive must hold
+// the info, the keys, and every block up to the capacity of the block matrix, otherwise ErrMalformedArchive is returned.
+// Like NewWithData, the block matrix is built in memory and written to the database in a single batch, and an error is
+// returned if the database already has a block matrix.  The hashes in the archive are trusted unless the
+// WithValidateOnImport option is given.
+func ImportTar(db *leveldb.DB, r io.Reader, opts ...Option) (*BlockMatrix, error) {
+	if err := checkNoMatrix(db); err != nil {
+		return nil, err
+	}
+
+	archive, err := readTar(r)
+	if err != nil {
+		return nil, err
+	}
+
+	staging, err := newStaging()
+	if err != nil {
+		return nil, err
+	}
+	defer staging.Close()
+
+	bm, err := NewWithLevelDB(staging, opts...)
+	if err != nil {
+		return nil, err
+	}
+	defer bm.Close()
+
+	if err = bm.importArchive(archive); err != nil {
+		return nil, err
+	}
+
+	if err = copyStaged(staging, db); err != nil {
+		return nil, err
+	}
+
+	return NewWithLevelDB(db, opts...)
+}
+
+// readTar reads and checks the entries of a block matrix tar archive.
+func readTar(r io.Reader) (*matrixArchive, error) {
+	archive := &matrixArchive{blocks: make(map[int]*Block)}
+
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("error reading tar archive: %w", err)
+		}
+
+		bytes, err := ioutil.ReadAll(tr)
+		if err != nil {
+			return nil, fmt.Errorf("error reading tar entry %s: %w", header.Name, err)
+		}
+
+		var v interface{}
+		switch {
+		case header.Name == tarInfoEntry:
+			v = &archive.info
+		case header.Name == tarKeysEntry:
+			v = &archive.keys
+		case strings.HasPrefix(header.Name, tarBlockPrefix) && strings.HasSuffix(header.Name, tarBlockSuffix):
+			blockNum, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(header.Name, tarBlockPrefix), tarBlockSuffix))
+			if err != nil {
+				return nil, fmt.Errorf("%w: invalid block entry %s", ErrMalformedArchive, header.Name)
+			}
+
+			block := &Block{}
+			archive.blocks[blockNum] = block
+			v = block
+		default:
+			return nil, fmt.Errorf("%w: unexpected entry %s", ErrMalformedArchive, header.Name)
+		}
+
+		if err = json.Unmarshal(bytes, v); err != nil {
+			return nil, fmt.Errorf("%w: error decoding %s: %v", ErrMalformedArchive, header.Name, err)
+		}
+	}
+
+	if archive.info == nil {
+		return nil, fmt.Errorf("%w: missing %s", ErrMalformedArchive, tarInfoEntry)
+	}
+
+	if err := archive.checkBlocks(); err != nil {
+		return nil, err
+	}
+
+	return archive, nil
+}
+
+// checkBlocks returns an error wrapping ErrMalformedArchive unless the archive has exactly the blocks up to the
+// capacity of its block matrix.
+func (archive *matrixArchive) checkBlocks() error {
+	if len(archive.blocks) != capacity(archive.info.Size) {
+		return fmt.Errorf("%w: has %d blocks, a block matrix of size %d has %d",
+			ErrMalformedArchive, len(archive.blocks), archive.info.Size, capacity(archive.info.Size))
+	}
+
+	for blockNum := 1; blockNum <= capacity(archive.info.Size); blockNum++ {
+		if _, ok := archive.blocks[blockNum]; !ok {
+			return fmt.Errorf("%w: missing block %d", ErrMalformedArchive, blockNum)
+		}
+	}
+
+	return nil
+}
+
+// freeSlots returns the slots to import as freed by DeleteBlock.  A freed slot is archived as an empty block without a
+// key, like an erased block, so the block count is all that tells them apart: the empty blocks without a key before the
+// last block with a key or data are freed, highest first, until the block count plus the free slots reaches it.
+func (archive *matrixArchive) freeSlots() (map[int]bool, error) {
+	keyed := make(map[int]bool, len(archive.keys))
+	for _, blockNum := range archive.keys {
+		keyed[blockNum] = true
+	}
+
+	last := 0
+	for blockNum, block := range archive.blocks {
+		if (keyed[blockNum] || !block.IsEmpty()) && blockNum > last {
+			last = blockNum
+		}
+	}
+
+	free := make(map[int]bool)
+	for blockNum := last - 1; blockNum > 0 && archive.info.BlockCount+len(free) < last; blockNum-- {
+		if !keyed[blockNum] && archive.blocks[blockNum].IsEmpty() {
+			free[blockNum] = true
+		}
+	}
+
+	if archive.info.BlockCount+len(free) < last {
+		return nil, fmt.Errorf("%w: block count %d does not include block %d", ErrMalformedArchive,
+			archive.info.BlockCount, last)
+	}
+
+	return free, nil
+}
+
+// importArchive writes the blocks, keys, and info of the archive to the block matrix, checking their hashes first if the
+// block matrix was created WithValidateOnImport.
+func (b *BlockMatrix) importArchive(archive *matrixArchive) error {
+	if err := b.checkHasher(archive.info); err != nil {
+		return err
+	}
+
+	if b.validateOnImport {
+		if err := ValidateInfo(archive.info); err != nil {
+			return fmt.Errorf("%w: %v", ErrMalformedArchive, err)
+		}
+	}
+
+	free, err := archive.freeSlots()
+	if err != nil {
+		return err
+	}
+
+	for blockNum := 1; blockNum <= capacity(archive.info.Size); blockNum++ {
+		block := archive.blocks[blockNum]
+		if b.validateOnImport && !reflect.DeepEqual(block.Hash, b.blockHash(block)) {
+			return &ImportError{BlockNumber: blockNum, Row: -1, Column: -1}
+		}
+
+		if free[blockNum] {
+			if err = b.put(freeEntry(blockNum), nil); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		bytes, err := b.encodeBlock(block)
+		if err != nil {
+			return err
+		}
+
+		if err = b.put([]byte(strconv.Itoa(blockNum)), bytes); err != nil {
+			return err
+		}
+	}
+
+	// keys are indexed in block number order, the closest to the order they were added in
+	keys := make([]string, 0, len(archive.keys))
+	for key := range archive.keys {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if archive.keys[keys[i]] != archive.keys[keys[j]] {
+			return archive.keys[keys[i]] < archive.keys[keys[j]]
+		}
+
+		return keys[i] < keys[j]
+	})
+
+	for _, key := range keys {
+		blockNum := archive.keys[key]
+		key = b.normalizeKey(key)
+		dbKey := b.dbKey(key)
+		if err := b.checkKeyCollision(key, dbKey); err != nil {
+			return err
+		}
+
+		if err := b.put(dbKey, []byte(strconv.Itoa(blockNum))); err != nil {
+			return err
+		}
+
+		if b.keyTransform != nil && !isLargeChunkEntry(dbKey) {
+			if err := b.put(originalKeyEntry(dbKey), []byte(key)); err != nil {
+				return err
+			}
+		}
+
+		if err := b.indexKey(key, dbKey); err != nil {
+			return err
+		}
+	}
+
+	if b.validateOnImport {
+		if err := b.checkImportedHashes(archive.info); err != nil {
+			return err
+		}
+	}
+
+	// the blocks were written with this block matrix's encoding, whichever the archived block matrix used
+	archive.info.BlockEncoding = blockEncodingID(b.blockEncoding)
+	return b.putBlockMatrixInfo(archive.info)
+}
+
+// checkImportedHashes returns an ImportError for the first row or column hash of info that does not match the imported
+// blocks.
+func (b *BlockMatrix) checkImportedHashes(info *BlockMatrixInfo) error {
+	for i := 0; i < info.Size; i++ {
+		hash, err := b.calculateRowHash(i, info.Size)
+		if err != nil {
+			return err
+		}
+
+		if !reflect.DeepEqual(hash, info.Rows[i]) {
+			return &ImportError{Row: i, Column: -1}
+		}
+	}
+
+	for i := 0; i < info.Size; i++ {
+		hash, err := b.calculateColumnHash(i, info.Size)
+		if err != nil {
+			return err
+		}
+
+		if !reflect.DeepEqual(hash, info.Cols[i]) {
+			return &ImportError{Row: -1, Column: i}
+		}
+	}
+
+	return nil
+}
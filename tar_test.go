@@ -0,0 +1,161 @@
+package blockmatrix
+
+import (
+	"archive/tar"
+	"bytes"
+	"encoding/json"
+	"github.com/stretchr/testify/require"
+	"io"
+	"io/ioutil"
+	"testing"
+)
+
+func TestExportImportTar(t *testing.T) {
+	bm, err := NewWithLevelDB(newTestDB(t))
+	require.NoError(t, err)
+	require.NoError(t, createTestBlocks(bm, 9))
+	require.NoError(t, bm.EraseBlock("key4"))
+
+	buf := &bytes.Buffer{}
+	require.NoError(t, bm.ExportTar(buf))
+
+	db := newTestDB(t)
+	imported, err := ImportTar(db, bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+
+	info, err := bm.GetBlockMatrixInfo()
+	require.NoError(t, err)
+	importedInfo, err := imported.GetBlockMatrixInfo()
+	require.NoError(t, err)
+	require.Equal(t, info, importedInfo)
+
+	matrix, err := bm.Matrix()
+	require.NoError(t, err)
+	importedMatrix, err := imported.Matrix()
+	require.NoError(t, err)
+	require.Equal(t, matrix, importedMatrix)
+
+	block, err := imported.GetBlock("key7")
+	require.NoError(t, err)
+	require.Equal(t, []byte{7}, block.Data)
+	_, err = imported.GetBlock("key4")
+	require.Error(t, err)
+
+	result, err := imported.Validate()
+	require.NoError(t, err)
+	require.True(t, result.OK)
+
+	_, err = ImportTar(db, bytes.NewReader(buf.Bytes()))
+	require.ErrorIs(t, err, ErrMatrixExists)
+}
+
+func TestImportTarMissingBlock(t *testing.T) {
+	bm, err := NewWithLevelDB(newTestDB(t))
+	require.NoError(t, err)
+	require.NoError(t, createTestBlocks(bm, 4))
+
+	buf := &bytes.Buffer{}
+	require.NoError(t, bm.ExportTar(buf))
+
+	// drop the last block
+	truncated := rewriteTar(t, buf, func(name string, data []byte) ([]byte, bool) {
+		return data, name != tarBlockEntry(6)
+	})
+
+	db := newTestDB(t)
+	_, err = ImportTar(db, truncated)
+	require.ErrorIs(t, err, ErrMalformedArchive)
+
+	ok, err := db.Has(InfoKey, nil)
+	require.NoError(t, err)
+	require.False(t, ok)
+}
+
+func TestImportTarValidate(t *testing.T) {
+	bm, err := NewWithLevelDB(newTestDB(t))
+	require.NoError(t, err)
+	require.NoError(t, createTestBlocks(bm, 4))
+
+	buf := &bytes.Buffer{}
+	require.NoError(t, bm.ExportTar(buf))
+
+	// change the data of block 3 without updating its hash
+	corrupted := rewriteTar(t, bytes.NewBuffer(buf.Bytes()), func(name string, data []byte) ([]byte, bool) {
+		if name != tarBlockEntry(3) {
+			return data, true
+		}
+
+		block := &Block{}
+		require.NoError(t, json.Unmarshal(data, block))
+		block.Data = []byte("tampered")
+		data, err := json.Marshal(block)
+		require.NoError(t, err)
+		return data, true
+	})
+
+	// the corrupted dump is trusted by default
+	_, err = ImportTar(newTestDB(t), bytes.NewReader(corrupted.Bytes()))
+	require.NoError(t, err)
+
+	db := newTestDB(t)
+	_, err = ImportTar(db, bytes.NewReader(corrupted.Bytes()), WithValidateOnImport())
+	require.ErrorIs(t, err, ErrHashMismatch)
+	importErr := &ImportError{}
+	require.ErrorAs(t, err, &importErr)
+	require.Equal(t, 3, importErr.BlockNumber)
+
+	ok, err := db.Has(InfoKey, nil)
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	// a consistent block with a stale row hash is caught after the blocks are imported
+	stale := rewriteTar(t, bytes.NewBuffer(buf.Bytes()), func(name string, data []byte) ([]byte, bool) {
+		if name != tarBlockEntry(3) {
+			return data, true
+		}
+
+		data, err := json.Marshal(NewBlock([]byte("tampered")))
+		require.NoError(t, err)
+		return data, true
+	})
+
+	row, col := locateBlock(3)
+	_, err = ImportTar(newTestDB(t), stale, WithValidateOnImport())
+	require.ErrorAs(t, err, &importErr)
+	require.Equal(t, &ImportError{Row: row, Column: -1}, importErr)
+	require.NotEqual(t, row, col)
+
+	_, err = ImportTar(newTestDB(t), bytes.NewReader(buf.Bytes()), WithValidateOnImport())
+	require.NoError(t, err)
+}
+
+// rewriteTar copies the entries of the archive through fn, which returns the new content of an entry and whether to keep
+// it.
+func rewriteTar(t *testing.T, r io.Reader, fn func(name string, data []byte) ([]byte, bool)) *bytes.Buffer {
+	out := &bytes.Buffer{}
+	tr := tar.NewReader(r)
+	tw := tar.NewWriter(out)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+
+		data, err := ioutil.ReadAll(tr)
+		require.NoError(t, err)
+
+		data, keep := fn(header.Name, data)
+		if !keep {
+			continue
+		}
+
+		header.Size = int64(len(data))
+		require.NoError(t, tw.WriteHeader(header))
+		_, err = tw.Write(data)
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+
+	return out
+}
@@ -0,0 +1,42 @@
+package blockmatrix
+
+import "context"
+
+// Tracer starts spans around block matrix operations so they can be recorded by a distributed tracing system without
+// this package depending on one.  StartSpan returns the context carrying the new span and a function that ends it.
+//
+// An OpenTelemetry adapter only needs to wrap a trace.Tracer:
+//
+//	type otelTracer struct {
+//		tracer trace.Tracer
+//	}
+//
+//	func (t otelTracer) StartSpan(ctx context.Context, name string) (context.Context, func()) {
+//		ctx, span := t.tracer.Start(ctx, name)
+//		return ctx, func() { span.End() }
+//	}
+type Tracer interface {
+	StartSpan(ctx context.Context, name string) (context.Context, func())
+}
+
+// noopTracer is the default Tracer, it does not record anything.
+type noopTracer struct{}
+
+func (noopTracer) StartSpan(ctx context.Context, _ string) (context.Context, func()) {
+	return ctx, func() {}
+}
+
+// startSpan starts a span for the named operation and returns the function that ends it, intended to be deferred at the
+// top of public operations:
+//
+//	defer b.startSpan("AddBlock")()
+func (b *BlockMatrix) startSpan(operation string) func() {
+	_, end := b.startSpanContext(context.Background(), operation)
+	return end
+}
+
+// startSpanContext starts a span for the named operation as a child of the span carried by ctx, if any, and returns the
+// context carrying the new span along with the function that ends it.
+func (b *BlockMatrix) startSpanContext(ctx context.Context, operation string) (context.Context, func()) {
+	return b.tracer.StartSpan(ctx, "blockmatrix."+operation)
+}
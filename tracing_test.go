@@ -0,0 +1,39 @@
+package blockmatrix
+
+import (
+	"context"
+	"github.com/stretchr/testify/require"
+	"sync"
+	"testing"
+)
+
+// recordingTracer records the name of every span that was started and ended.
+type recordingTracer struct {
+	mu    sync.Mutex
+	ended []string
+}
+
+func (r *recordingTracer) StartSpan(ctx context.Context, name string) (context.Context, func()) {
+	return ctx, func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.ended = append(r.ended, name)
+	}
+}
+
+func TestWithTracer(t *testing.T) {
+	tracer := &recordingTracer{}
+	bm, err := NewWithLevelDB(newTestDB(t), WithTracer(tracer))
+	require.NoError(t, err)
+
+	require.NoError(t, bm.AddBlock("key1", []byte{1}))
+	_, err = bm.GetBlock("key1")
+	require.NoError(t, err)
+	_, err = bm.ValidateRow(0)
+	require.NoError(t, err)
+	_, err = bm.ValidateColumn(1)
+	require.NoError(t, err)
+
+	require.Equal(t, []string{"blockmatrix.AddBlock", "blockmatrix.GetBlock", "blockmatrix.ValidateRow",
+		"blockmatrix.ValidateColumn"}, tracer.ended)
+}
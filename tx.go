@@ -0,0 +1,548 @@
+package blockmatrix
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// ErrTxDone is returned when a transaction is used after it was committed or rolled back.
+var ErrTxDone = errors.New("transaction has already been committed or rolled back")
+
+type (
+	// Tx is an all or nothing set of adds, updates, and erases obtained with Begin.  Operations are only queued until
+	// Commit, which computes their combined effect on the block matrix and writes it in a single leveldb batch, or
+	// Rollback, which discards them.
+	Tx struct {
+		bm   *BlockMatrix
+		ops  []txOp
+		done bool
+	}
+
+	txOp struct {
+		kind txOpKind
+		key  string
+		data []byte
+	}
+
+	txOpKind int
+
+	// txState holds the writes of a transaction being committed on top of the database.  A nil value is a delete.
+	txState struct {
+		bm     *BlockMatrix
+		writes map[string][]byte
+	}
+)
+
+const (
+	txAdd txOpKind = iota
+	txUpdate
+	txErase
+	txAddChunk
+)
+
+// Begin starts a transaction on the block matrix.
+func (b *BlockMatrix) Begin() *Tx {
+	return &Tx{bm: b}
+}
+
+// AddBlock queues adding a block with the given key and data.
+func (tx *Tx) AddBlock(key string, data []byte) error {
+	return tx.queue(txOp{kind: txAdd, key: key, data: data})
+}
+
+// UpdateBlock queues replacing the data of the block associated with the given key, the block keeps its metadata.
+func (tx *Tx) UpdateBlock(key string, data []byte) error {
+	return tx.queue(txOp{kind: txUpdate, key: key, data: data})
+}
+
+// EraseBlock queues erasing the block associated with the given key.
+func (tx *Tx) EraseBlock(key string) error {
+	return tx.queue(txOp{kind: txErase, key: key})
+}
+
+// addChunk queues adding a block with the given data mapped from the chunk entry of a large value, see AddLargeBlock.
+func (tx *Tx) addChunk(entry string, data []byte) error {
+	return tx.queue(txOp{kind: txAddChunk, key: entry, data: data})
+}
+
+func (tx *Tx) queue(op txOp) error {
+	if tx.done {
+		return ErrTxDone
+	}
+
+	tx.ops = append(tx.ops, op)
+	return nil
+}
+
+// Rollback discards the queued operations.
+func (tx *Tx) Rollback() error {
+	if tx.done {
+		return ErrTxDone
+	}
+
+	tx.done = true
+	tx.ops = nil
+	return nil
+}
+
+// Commit applies the queued operations as one leveldb batch.  The operations are applied in order to a view of the block
+// matrix, the affected row and column hashes are recomputed once, and the erases of blocks that existed before the
+// transaction are checked together by the validity policy.  If any operation fails or the policy rejects the erases
+// nothing is written.
+func (tx *Tx) Commit() error {
+	b := tx.bm
+	defer b.startSpan("Tx.Commit")()
+
+	if tx.done {
+		return ErrTxDone
+	}
+	tx.done = true
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	info, err := b.GetBlockMatrixInfo()
+	if err != nil {
+		return err
+	}
+	before := copyInfo(info)
+
+	state := &txState{bm: b, writes: make(map[string][]byte)}
+	dirtyRows := make(map[int]bool)
+	dirtyCols := make(map[int]bool)
+	erased := make(map[int]bool)
+	added := make(map[int]bool)
+	grew := false
+
+	for _, op := range tx.ops {
+		var blockNum int
+		switch op.kind {
+		case txAdd:
+			var opGrew bool
+			if blockNum, opGrew, err = state.add(info, op.key, b.newBlock(op.data)); err != nil {
+				return err
+			}
+			grew = grew || opGrew
+			added[blockNum] = true
+		case txAddChunk:
+			var opGrew bool
+			if blockNum, opGrew, err = state.addChunk(info, op.key, b.newBlock(op.data)); err != nil {
+				return err
+			}
+			grew = grew || opGrew
+			added[blockNum] = true
+		case txUpdate:
+			var key string
+			if key, err = b.resolveAlias(op.key); err != nil {
+				return err
+			}
+
+			if blockNum, err = state.replace(key, op.data); err != nil {
+				return err
+			}
+		case txErase:
+			var key string
+			if key, err = b.resolveAlias(op.key); err != nil {
+				return err
+			}
+
+			var aliasEntries [][]byte
+			if aliasEntries, err = b.erasedAliasEntries(key); err != nil {
+				return err
+			}
+
+			if blockNum, err = state.update(key, b.emptyBlock()); err != nil {
+				return err
+			}
+
+			state.deleteKey(key)
+			for _, entry := range aliasEntries {
+				state.delete(entry)
+			}
+			if !added[blockNum] {
+				erased[blockNum] = true
+			}
+		}
+
+		row, col := locateBlock(blockNum)
+		dirtyRows[row] = true
+		dirtyCols[col] = true
+	}
+
+	// only the erases of blocks that existed before the transaction are subject to the validity policy, the other
+	// operations are applied on top of them
+	if len(erased) > 0 {
+		if err = tx.checkErases(state, before, erased); err != nil {
+			return err
+		}
+	}
+
+	if err = state.updateHashes(info, dirtyRows, dirtyCols, grew); err != nil {
+		return err
+	}
+
+	if err = state.write(info); err != nil {
+		return err
+	}
+
+	for blockNum := range added {
+		b.observer.BlockAdded(blockNum)
+	}
+	for blockNum := range erased {
+		b.observer.BlockErased(blockNum)
+	}
+
+	return nil
+}
+
+// checkErases returns an error if the validity policy rejects erasing the given blocks from the block matrix described
+// by before, otherwise the erase is recorded in the erase journal with the transaction's writes.
+func (tx *Tx) checkErases(state *txState, before *BlockMatrixInfo, erased map[int]bool) error {
+	after := copyInfo(before)
+	getBlock := func(blockNum int) (*Block, error) {
+		if erased[blockNum] {
+			return tx.bm.emptyBlock(), nil
+		}
+
+		return tx.bm.GetBlockByNumber(blockNum)
+	}
+
+	for blockNum := range erased {
+		row, col := locateBlock(blockNum)
+
+		rowBlocks, err := rowBlockNumbers(row, after.Size)
+		if err != nil {
+			return err
+		}
+
+		if after.Rows[row], err = hashBlocks(tx.bm.hasher, rowBlocks, getBlock); err != nil {
+			return err
+		}
+
+		colBlocks, err := columnBlockNumbers(col, after.Size)
+		if err != nil {
+			return err
+		}
+
+		if after.Cols[col], err = hashBlocks(tx.bm.hasher, colBlocks, getBlock); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.bm.checkErase(before, after); err != nil {
+		return err
+	}
+
+	key, value, err := tx.bm.eraseJournalWrite(before, after, sortedIndices(erased))
+	if err != nil {
+		return err
+	}
+
+	state.put(key, value)
+	return nil
+}
+
+func (s *txState) get(key []byte) ([]byte, error) {
+	if value, ok := s.writes[string(key)]; ok {
+		if value == nil {
+			return nil, ErrNotFound
+		}
+
+		return value, nil
+	}
+
+	return s.bm.get(key)
+}
+
+func (s *txState) has(key []byte) (bool, error) {
+	if value, ok := s.writes[string(key)]; ok {
+		return value != nil, nil
+	}
+
+	return s.bm.has(key)
+}
+
+func (s *txState) put(key []byte, value []byte) {
+	s.writes[string(key)] = value
+}
+
+func (s *txState) delete(key []byte) {
+	s.writes[string(key)] = nil
+}
+
+func (s *txState) putBlock(blockNum int, block *Block) error {
+	b := s.bm
+	if err := b.checkHashCollision(block, s.get, s.getBlock); err != nil {
+		return err
+	}
+
+	bytes, err := b.encodeBlock(block)
+	if err != nil {
+		return err
+	}
+
+	s.put([]byte(strconv.Itoa(blockNum)), bytes)
+	if b.hashCollisionCheck && !block.IsEmpty() {
+		s.put(hashIndexEntry(block.Hash), []byte(strconv.Itoa(blockNum)))
+	}
+
+	return nil
+}
+
+func (s *txState) getBlock(blockNum int) (*Block, error) {
+	bytes, err := s.get([]byte(strconv.Itoa(blockNum)))
+	if errors.Is(err, ErrNotFound) && (s.bm.lazyEmptyBlocks || isFreeSlot(s.get, blockNum)) {
+		// the transaction only reads slots within the capacity of the block matrix it is growing
+		return s.bm.emptyBlock(), nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	return s.bm.decodeBlock(bytes)
+}
+
+func (s *txState) blockNumber(key string) (int, error) {
+	bytes, err := s.get(s.bm.dbKey(key))
+	if err != nil {
+		return 0, fmt.Errorf("error getting block number of key %q: %w", key, err)
+	}
+
+	return strconv.Atoi(string(bytes))
+}
+
+// add stores the block in the lowest free slot or under the next block number like AddBlock, growing the block matrix
+// described by info if needed.
+func (s *txState) add(info *BlockMatrixInfo, key string, block *Block) (int, bool, error) {
+	b := s.bm
+	key = b.normalizeKey(key)
+	dbKey := b.dbKey(key)
+	if err := b.checkKeyLength(key, dbKey); err != nil {
+		return 0, false, err
+	}
+
+	if err := checkReservedKey(key, dbKey); err != nil {
+		return 0, false, err
+	}
+
+	if b.keyTransform != nil {
+		if original, err := s.get(originalKeyEntry(dbKey)); err == nil && string(original) != key {
+			return 0, false, fmt.Errorf("%w: %q and %q both map to %q", ErrKeyCollision, original, key, dbKey)
+		} else if err != nil && !errors.Is(err, ErrNotFound) {
+			return 0, false, err
+		}
+	}
+
+	blockNum, grow, err := s.allocate(info)
+	if err != nil {
+		return 0, false, err
+	}
+
+	s.put(dbKey, []byte(strconv.Itoa(blockNum)))
+	if b.keyTransform != nil {
+		s.put(originalKeyEntry(dbKey), []byte(key))
+	}
+
+	if value, err := b.keyIndexWrite(s.get, key, dbKey); err != nil {
+		return 0, false, err
+	} else if value != nil {
+		s.put(keyIndexEntry(dbKey), value)
+	}
+
+	return blockNum, grow, s.putBlock(blockNum, block)
+}
+
+// addChunk stores the block like add and maps the chunk entry of a large value to it.
+func (s *txState) addChunk(info *BlockMatrixInfo, entry string, block *Block) (int, bool, error) {
+	blockNum, grow, err := s.allocate(info)
+	if err != nil {
+		return 0, false, err
+	}
+
+	s.put([]byte(entry), []byte(strconv.Itoa(blockNum)))
+	return blockNum, grow, s.putBlock(blockNum, block)
+}
+
+// allocate returns the lowest free slot, or the next block number if no slot is free, growing the block matrix described
+// by info if needed.
+func (s *txState) allocate(info *BlockMatrixInfo) (int, bool, error) {
+	b := s.bm
+
+	// free slots reused earlier in the transaction have their entries deleted in the writes
+	blockNum, err := b.lowestFreeSlot(func(blockNum int) bool {
+		value, ok := s.writes[string(freeEntry(blockNum))]
+		return ok && value == nil
+	})
+	if err != nil {
+		return 0, false, err
+	}
+
+	grow := false
+	if blockNum > 0 {
+		s.delete(freeEntry(blockNum))
+		info.BlockCount++
+	} else {
+		newSize := b.Size(info.BlockCount + 1)
+		grow = newSize > info.Size
+		if grow {
+			if b.maxSize > 0 && newSize > b.maxSize {
+				return 0, false, fmt.Errorf("%w: adding block %d requires size %d, the maximum is %d",
+					ErrMaxSizeExceeded, info.BlockCount+1, newSize, b.maxSize)
+			}
+
+			for blockNum := capacity(info.Size) + 1; blockNum <= capacity(newSize) && !b.lazyEmptyBlocks; blockNum++ {
+				if err := s.putBlock(blockNum, b.emptyBlock()); err != nil {
+					return 0, false, err
+				}
+			}
+
+			for i := info.Size; i < newSize; i++ {
+				info.Rows = append(info.Rows, make([]byte, 0))
+				info.Cols = append(info.Cols, make([]byte, 0))
+			}
+			b.logGrowth(info.Size, newSize)
+			info.Size = newSize
+		}
+
+		info.BlockCount++
+		blockNum = info.BlockCount
+	}
+
+	return blockNum, grow, nil
+}
+
+// updateHashes recomputes the hashes of the dirty rows and columns of info from the blocks as of the writes, or of every
+// row and column if the block matrix grew.
+func (s *txState) updateHashes(info *BlockMatrixInfo, dirtyRows, dirtyCols map[int]bool, grew bool) error {
+	if grew {
+		for i := 0; i < info.Size; i++ {
+			dirtyRows[i] = true
+			dirtyCols[i] = true
+		}
+	}
+
+	for _, row := range sortedIndices(dirtyRows) {
+		blockNums, err := rowBlockNumbers(row, info.Size)
+		if err != nil {
+			return err
+		}
+
+		if info.Rows[row], err = hashBlocks(s.bm.hasher, blockNums, s.getBlock); err != nil {
+			return err
+		}
+	}
+
+	for _, col := range sortedIndices(dirtyCols) {
+		blockNums, err := columnBlockNumbers(col, info.Size)
+		if err != nil {
+			return err
+		}
+
+		if info.Cols[col], err = hashBlocks(s.bm.hasher, blockNums, s.getBlock); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// update replaces the block associated with key, which is no longer reserved afterwards.
+func (s *txState) update(key string, block *Block) (int, error) {
+	blockNum, err := s.blockNumber(key)
+	if err != nil {
+		return 0, err
+	}
+
+	if err = s.putBlock(blockNum, block); err != nil {
+		return 0, err
+	}
+
+	if ok, err := s.has(reservedEntry(blockNum)); err != nil {
+		return 0, err
+	} else if ok {
+		s.delete(reservedEntry(blockNum))
+	}
+
+	return blockNum, nil
+}
+
+// replace replaces the data of the block associated with key like update, keeping the metadata of the block.
+func (s *txState) replace(key string, data []byte) (int, error) {
+	blockNum, err := s.blockNumber(key)
+	if err != nil {
+		return 0, err
+	}
+
+	block, err := s.getBlock(blockNum)
+	if err != nil {
+		return 0, err
+	}
+
+	return s.update(key, s.bm.newBlockWithMetadata(data, block.Metadata))
+}
+
+// deleteKey removes the entries of key.
+func (s *txState) deleteKey(key string) {
+	dbKey := s.bm.dbKey(key)
+	s.delete(dbKey)
+	if s.bm.keyTransform != nil {
+		s.delete(originalKeyEntry(dbKey))
+	}
+	s.delete(keyIndexEntry(dbKey))
+}
+
+// write stores the transaction's writes, the info, and a change log entry in a single leveldb batch, journaling each
+// write first.
+func (s *txState) write(info *BlockMatrixInfo) error {
+	b := s.bm
+
+	infoBytes, err := encodeInfo(info)
+	if err != nil {
+		return err
+	}
+	s.put(InfoKey, infoBytes)
+
+	changed := make(map[int]bool)
+	for key := range s.writes {
+		if blockNum, err := strconv.Atoi(key); err == nil {
+			changed[blockNum] = true
+		}
+	}
+
+	entry, err := json.Marshal(changeLogEntry{
+		Root:   s.bm.rootHash(info),
+		Blocks: sortedIndices(changed),
+		Time:   time.Now().UnixNano(),
+	})
+	if err != nil {
+		return err
+	}
+
+	seq, err := b.nextChangeLogSeq()
+	if err != nil {
+		return err
+	}
+	s.put(changeLogKey(seq), entry)
+
+	keys := make([]string, 0, len(s.writes))
+	for key := range s.writes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	writes := make([]StoreWrite, 0, len(keys))
+	for _, key := range keys {
+		value := s.writes[key]
+		writes = append(writes, StoreWrite{Key: []byte(key), Value: value, Delete: value == nil})
+	}
+
+	if err = b.write(writes); err != nil {
+		return err
+	}
+
+	b.cacheInfo(info)
+	return nil
+}
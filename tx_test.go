@@ -0,0 +1,97 @@
+package blockmatrix
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestTx(t *testing.T) {
+	expected, err := NewWithLevelDB(newTestDB(t), WithClock(testClock))
+	require.NoError(t, err)
+	require.NoError(t, createTestBlocks(expected, 5))
+	require.NoError(t, expected.AddBlock("key6", []byte{6}))
+	require.NoError(t, expected.AddBlock("key7", []byte{7}))
+	require.NoError(t, expected.EraseBlock("key3"))
+
+	bm, err := NewWithLevelDB(newTestDB(t), WithClock(testClock))
+	require.NoError(t, err)
+	require.NoError(t, createTestBlocks(bm, 5))
+
+	tx := bm.Begin()
+	require.NoError(t, tx.AddBlock("key6", []byte{6}))
+	require.NoError(t, tx.UpdateBlock("key2", []byte{20}))
+	require.NoError(t, tx.AddBlock("key7", []byte{7}))
+	require.NoError(t, tx.EraseBlock("key3"))
+	require.NoError(t, tx.Commit())
+	require.ErrorIs(t, tx.Commit(), ErrTxDone)
+
+	block, err := bm.GetBlock("key2")
+	require.NoError(t, err)
+	require.Equal(t, []byte{20}, block.Data)
+	_, err = bm.GetBlock("key3")
+	require.Error(t, err)
+
+	// apart from the update the result is the same as applying the operations one at a time
+	require.NoError(t, expected.EraseBlock("key2"))
+	require.NoError(t, bm.EraseBlock("key2"))
+
+	expectedInfo, err := expected.GetBlockMatrixInfo()
+	require.NoError(t, err)
+	info, err := bm.GetBlockMatrixInfo()
+	require.NoError(t, err)
+	require.Equal(t, expectedInfo.Size, info.Size)
+	require.Equal(t, expectedInfo.BlockCount, info.BlockCount)
+	require.Equal(t, expectedInfo.Rows, info.Rows)
+	require.Equal(t, expectedInfo.Cols, info.Cols)
+
+	result, err := bm.Validate()
+	require.NoError(t, err)
+	require.True(t, result.OK)
+}
+
+func TestTxRollback(t *testing.T) {
+	db := newTestDB(t)
+	bm, err := NewWithLevelDB(db)
+	require.NoError(t, err)
+	require.NoError(t, createTestBlocks(bm, 5))
+
+	root, err := bm.RootHash()
+	require.NoError(t, err)
+
+	// erasing blocks 1 and 5 changes two rows and two columns which the default validity policy rejects
+	tx := bm.Begin()
+	require.NoError(t, tx.AddBlock("key6", []byte{6}))
+	require.NoError(t, tx.EraseBlock("key1"))
+	require.NoError(t, tx.EraseBlock("key5"))
+	require.ErrorIs(t, tx.Commit(), ErrInvalidErase)
+
+	// a failing operation also leaves the block matrix untouched
+	tx = bm.Begin()
+	require.NoError(t, tx.AddBlock("key6", []byte{6}))
+	require.NoError(t, tx.EraseBlock("missing"))
+	require.Error(t, tx.Commit())
+
+	tx = bm.Begin()
+	require.NoError(t, tx.AddBlock("key6", []byte{6}))
+	require.NoError(t, tx.Rollback())
+	require.ErrorIs(t, tx.AddBlock("key7", []byte{7}), ErrTxDone)
+
+	after, err := bm.RootHash()
+	require.NoError(t, err)
+	require.Equal(t, root, after)
+
+	info, err := bm.GetBlockMatrixInfo()
+	require.NoError(t, err)
+	require.Equal(t, 5, info.BlockCount)
+
+	for _, key := range []string{"key1", "key5"} {
+		_, err = bm.GetBlock(key)
+		require.NoError(t, err)
+	}
+	_, err = bm.GetBlock("key6")
+	require.Error(t, err)
+
+	changes, err := bm.ChangesSince(root)
+	require.NoError(t, err)
+	require.Empty(t, changes)
+}
@@ -0,0 +1,380 @@
+package blockmatrix
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"runtime"
+	"sort"
+	"sync"
+)
+
+// ValidationResult describes which parts of a block matrix failed validation.
+type ValidationResult struct {
+	// OK is true if no block, row, or column errors were found
+	OK bool `json:"ok"`
+	// BlockErrors stores the numbers of blocks whose stored hash does not match their data
+	BlockErrors []int `json:"block_errors"`
+	// RowErrors stores the indices of rows whose stored hash does not match their blocks
+	RowErrors []int `json:"row_errors"`
+	// ColumnErrors stores the indices of columns whose stored hash does not match their blocks
+	ColumnErrors []int `json:"column_errors"`
+}
+
+// Validate recomputes every block, row, and column hash in the block matrix and compares them to the stored hashes.
+// Inconsistencies are reported in the returned ValidationResult, an error is only returned if the block matrix could not
+// be read.
+func (b *BlockMatrix) Validate() (*ValidationResult, error) {
+	return b.ValidateContext(context.Background())
+}
+
+// ValidateContext validates the block matrix like Validate, checking ctx before each block is read and returning the
+// context's error as soon as it is done.
+func (b *BlockMatrix) ValidateContext(ctx context.Context) (*ValidationResult, error) {
+	ctx, end := b.startSpanContext(ctx, "Validate")
+	defer end()
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	return b.validate(ctx)
+}
+
+func (b *BlockMatrix) validate(ctx context.Context) (*ValidationResult, error) {
+	info, err := b.GetBlockMatrixInfo()
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ValidationResult{
+		RowErrors:    make([]int, 0),
+		ColumnErrors: make([]int, 0),
+	}
+
+	// check block hashes
+	if result.BlockErrors, err = b.corruptBlocks(ctx); err != nil {
+		return nil, err
+	}
+	for _, blockNum := range result.BlockErrors {
+		row, col := locateBlock(blockNum)
+		b.logger.Warn("block hash mismatch", "block", blockNum, "row", row, "col", col)
+	}
+
+	getBlock := b.getSlotContext(ctx)
+
+	// check row hashes
+	for i := 0; i < info.Size; i++ {
+		var blocks []int
+		if blocks, err = rowBlockNumbers(i, info.Size); err != nil {
+			return nil, err
+		}
+
+		var hash []byte
+		if hash, err = hashBlocks(b.hasher, blocks, getBlock); err != nil {
+			return nil, err
+		}
+
+		// a partially written info may be missing hashes
+		if i >= len(info.Rows) || !reflect.DeepEqual(info.Rows[i], hash) {
+			result.RowErrors = append(result.RowErrors, i)
+			b.logger.Warn("row hash mismatch", "row", i)
+		}
+	}
+
+	// check col hashes
+	for i := 0; i < info.Size; i++ {
+		var blocks []int
+		if blocks, err = columnBlockNumbers(i, info.Size); err != nil {
+			return nil, err
+		}
+
+		var hash []byte
+		if hash, err = hashBlocks(b.hasher, blocks, getBlock); err != nil {
+			return nil, err
+		}
+
+		if i >= len(info.Cols) || !reflect.DeepEqual(info.Cols[i], hash) {
+			result.ColumnErrors = append(result.ColumnErrors, i)
+			b.logger.Warn("column hash mismatch", "col", i)
+		}
+	}
+
+	result.OK = len(result.BlockErrors) == 0 && len(result.RowErrors) == 0 && len(result.ColumnErrors) == 0
+	if !result.OK {
+		b.observer.ValidationFailed(result.failureReason())
+	}
+
+	return result, nil
+}
+
+// ValidateRow recomputes the hash of the row at the given index from its blocks and compares it to the stored hash,
+// without reading the rest of the block matrix.  It returns false if the hashes do not match, the blocks' own hashes are
+// not checked.  An error is returned if the row is out of range.
+func (b *BlockMatrix) ValidateRow(row int) (bool, error) {
+	defer b.startSpan("ValidateRow")()
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	info, err := b.GetBlockMatrixInfo()
+	if err != nil {
+		return false, err
+	}
+
+	if err = checkIndex("row", row, info.Size); err != nil {
+		return false, err
+	}
+
+	hash, err := b.calculateRowHash(row, info.Size)
+	if err != nil {
+		return false, err
+	}
+
+	// a partially written info may be missing hashes
+	if row >= len(info.Rows) || !reflect.DeepEqual(info.Rows[row], hash) {
+		b.logger.Warn("row hash mismatch", "row", row)
+		b.observer.ValidationFailed((&ValidationResult{RowErrors: []int{row}}).failureReason())
+		return false, nil
+	}
+
+	return true, nil
+}
+
+// ValidateColumn recomputes the hash of the column at the given index from its blocks and compares it to the stored
+// hash, like ValidateRow.
+func (b *BlockMatrix) ValidateColumn(col int) (bool, error) {
+	defer b.startSpan("ValidateColumn")()
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	info, err := b.GetBlockMatrixInfo()
+	if err != nil {
+		return false, err
+	}
+
+	if err = checkIndex("column", col, info.Size); err != nil {
+		return false, err
+	}
+
+	hash, err := b.calculateColumnHash(col, info.Size)
+	if err != nil {
+		return false, err
+	}
+
+	if col >= len(info.Cols) || !reflect.DeepEqual(info.Cols[col], hash) {
+		b.logger.Warn("column hash mismatch", "col", col)
+		b.observer.ValidationFailed((&ValidationResult{ColumnErrors: []int{col}}).failureReason())
+		return false, nil
+	}
+
+	return true, nil
+}
+
+// VerifyContents checks that the block matrix contains exactly the given key to data pairs.  It returns true if every
+// expected key is present with matching data and no other live blocks exist, along with a description of each
+// discrepancy found: missing keys, keys with mismatched data, and extra blocks not claimed by any expected key.
+func (b *BlockMatrix) VerifyContents(expected map[string][]byte) (bool, []string, error) {
+	info, err := b.GetBlockMatrixInfo()
+	if err != nil {
+		return false, nil, err
+	}
+
+	discrepancies := make([]string, 0)
+	claimed := make(map[int]bool)
+	for _, key := range sortedKeys(expected) {
+		blockNum, err := b.BlockNumber(key)
+		if errors.Is(err, ErrNotFound) {
+			discrepancies = append(discrepancies, fmt.Sprintf("missing key %q", key))
+			continue
+		} else if err != nil {
+			return false, nil, err
+		}
+
+		claimed[blockNum] = true
+
+		block, err := b.GetBlockByNumber(blockNum)
+		if err != nil {
+			return false, nil, err
+		}
+
+		if !bytes.Equal(block.Data, expected[key]) {
+			discrepancies = append(discrepancies, fmt.Sprintf("mismatched data for key %q", key))
+		}
+	}
+
+	last, err := b.lastBlock(info)
+	if err != nil {
+		return false, nil, err
+	}
+
+	for i := 1; i <= last; i++ {
+		if claimed[i] {
+			continue
+		}
+
+		block, err := b.GetBlockByNumber(i)
+		if err != nil {
+			return false, nil, err
+		}
+
+		if !block.IsEmpty() {
+			discrepancies = append(discrepancies, fmt.Sprintf("extra block %d", i))
+		}
+	}
+
+	return len(discrepancies) == 0, discrepancies, nil
+}
+
+// CorruptBlocks returns the numbers of every block whose stored hash does not match the hash of its data, in ascending
+// order.  The blocks are checked concurrently, by as many goroutines as WithValidationConcurrency allows, while holding
+// the block matrix's read lock.
+func (b *BlockMatrix) CorruptBlocks() ([]int, error) {
+	return b.CorruptBlocksContext(context.Background())
+}
+
+// CorruptBlocksContext finds the corrupt blocks like CorruptBlocks, checking ctx before each block is read and
+// returning the context's error as soon as it is done.
+func (b *BlockMatrix) CorruptBlocksContext(ctx context.Context) ([]int, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	return b.corruptBlocks(ctx)
+}
+
+func (b *BlockMatrix) corruptBlocks(ctx context.Context) ([]int, error) {
+	info, err := b.GetBlockMatrixInfo()
+	if err != nil {
+		return nil, err
+	}
+
+	last, err := b.lastBlock(info)
+	if err != nil {
+		return nil, err
+	}
+
+	nums := make(chan int)
+	go func() {
+		defer close(nums)
+		for i := 1; i <= last; i++ {
+			select {
+			case nums <- i:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	var (
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+		corrupt = make([]int, 0)
+		errs    = make([]error, 0)
+	)
+
+	for w := 0; w < b.validationWorkers(); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for num := range nums {
+				if ctx.Err() != nil {
+					continue
+				}
+
+				ok, err := b.checkBlockHash(num)
+
+				mu.Lock()
+				if err != nil {
+					errs = append(errs, err)
+				} else if !ok {
+					corrupt = append(corrupt, num)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if err = ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(errs) > 0 {
+		return nil, errs[0]
+	}
+
+	sort.Ints(corrupt)
+
+	return corrupt, nil
+}
+
+// validationWorkers returns the number of goroutines blocks are checked with during validation.
+func (b *BlockMatrix) validationWorkers() int {
+	if b.validationConcurrency > 0 {
+		return b.validationConcurrency
+	}
+
+	return runtime.GOMAXPROCS(0)
+}
+
+// getSlotContext returns a function that reads blocks like getSlot but returns the context's error once ctx is done.
+func (b *BlockMatrix) getSlotContext(ctx context.Context) func(blockNum int) (*Block, error) {
+	return func(blockNum int) (*Block, error) {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		return b.getSlot(blockNum)
+	}
+}
+
+// checkBlockHash returns true if the stored hash of the block with the given number matches the hash of its data and,
+// if an HMAC key is configured, its MAC is authentic.
+func (b *BlockMatrix) checkBlockHash(blockNum int) (bool, error) {
+	block, err := b.getSlot(blockNum)
+	if errors.Is(err, ErrAuthenticationFailed) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	return reflect.DeepEqual(block.Hash, b.blockHash(block)), nil
+}
+
+// ValidateInfo checks the structure of a block matrix info without a database, for example one received in a dump: the
+// size must be positive, there must be one row and one column hash per row and column, the block count must fit in the
+// capacity of the block matrix, and every hash must have the length of the block matrix's hash function.  The hashes
+// themselves are not verified.
+func ValidateInfo(info *BlockMatrixInfo) error {
+	if info == nil {
+		return fmt.Errorf("block matrix info is nil")
+	}
+
+	if info.Size < 1 {
+		return fmt.Errorf("size %d is not positive", info.Size)
+	}
+
+	if len(info.Rows) != info.Size || len(info.Cols) != info.Size {
+		return fmt.Errorf("%d rows and %d columns for size %d", len(info.Rows), len(info.Cols), info.Size)
+	}
+
+	if info.BlockCount < 0 || info.BlockCount > capacity(info.Size) {
+		return fmt.Errorf("block count %d is outside the capacity %d of size %d", info.BlockCount, capacity(info.Size),
+			info.Size)
+	}
+
+	hashLength := hasherIDSize(info.Hasher)
+	for i := 0; i < info.Size; i++ {
+		if len(info.Rows[i]) != hashLength {
+			return fmt.Errorf("row %d hash has length %d, expected %d", i, len(info.Rows[i]), hashLength)
+		}
+
+		if len(info.Cols[i]) != hashLength {
+			return fmt.Errorf("column %d hash has length %d, expected %d", i, len(info.Cols[i]), hashLength)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,297 @@
+package blockmatrix
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/require"
+	"github.com/syndtr/goleveldb/leveldb"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// corruptBlockData overwrites the data of the stored block without updating its hash.
+func corruptBlockData(t *testing.T, db *leveldb.DB, blockNum int, data []byte) {
+	bytes, err := db.Get([]byte(fmt.Sprint(blockNum)), nil)
+	require.NoError(t, err)
+
+	block, err := BinaryBlockEncoding().Unmarshal(bytes)
+	require.NoError(t, err)
+	block.Data = data
+
+	bytes, err = BinaryBlockEncoding().Marshal(block)
+	require.NoError(t, err)
+	require.NoError(t, db.Put([]byte(fmt.Sprint(blockNum)), bytes, nil))
+}
+
+// corruptInfo applies fn to the stored block matrix info and writes it back.
+func corruptInfo(t *testing.T, db *leveldb.DB, fn func(info *BlockMatrixInfo)) {
+	bytes, err := db.Get(InfoKey, nil)
+	require.NoError(t, err)
+
+	info, err := decodeInfo(bytes)
+	require.NoError(t, err)
+	fn(info)
+
+	bytes, err = encodeInfo(info)
+	require.NoError(t, err)
+	require.NoError(t, db.Put(InfoKey, bytes, nil))
+}
+
+func TestValidate(t *testing.T) {
+	db := newTestDB(t)
+	bm, err := NewWithLevelDB(db)
+	require.NoError(t, err)
+	require.NoError(t, createTestBlocks(bm, 8))
+
+	result, err := bm.Validate()
+	require.NoError(t, err)
+	require.True(t, result.OK)
+	require.Empty(t, result.BlockErrors)
+	require.Empty(t, result.RowErrors)
+	require.Empty(t, result.ColumnErrors)
+
+	corruptBlockData(t, db, 3, []byte("tampered"))
+	corruptInfo(t, db, func(info *BlockMatrixInfo) {
+		info.Rows[2] = []byte("tampered")
+	})
+
+	result, err = bm.Validate()
+	require.NoError(t, err)
+	require.False(t, result.OK)
+	require.Equal(t, []int{3}, result.BlockErrors)
+	require.Equal(t, []int{2}, result.RowErrors)
+	require.Empty(t, result.ColumnErrors)
+
+	ok, err := bm.IsValid()
+	require.NoError(t, err)
+	require.False(t, ok)
+}
+
+func TestValidateRowAndColumn(t *testing.T) {
+	db := newTestDB(t)
+	bm, err := NewWithLevelDB(db)
+	require.NoError(t, err)
+	require.NoError(t, createTestBlocks(bm, 8))
+
+	corruptInfo(t, db, func(info *BlockMatrixInfo) {
+		info.Rows[2] = []byte("tampered")
+	})
+
+	for i := 0; i < 4; i++ {
+		ok, err := bm.ValidateRow(i)
+		require.NoError(t, err)
+		require.Equal(t, i != 2, ok, "row %d", i)
+
+		ok, err = bm.ValidateColumn(i)
+		require.NoError(t, err)
+		require.True(t, ok, "column %d", i)
+	}
+
+	_, err = bm.ValidateRow(4)
+	require.ErrorIs(t, err, ErrOutOfRange)
+	_, err = bm.ValidateColumn(-1)
+	require.ErrorIs(t, err, ErrOutOfRange)
+}
+
+func TestIsValid(t *testing.T) {
+	db := newTestDB(t)
+	bm, err := NewWithLevelDB(db)
+	require.NoError(t, err)
+	require.NoError(t, createTestBlocks(bm, 8))
+	require.NoError(t, bm.EraseBlock("key5"))
+
+	ok, err := bm.IsValid()
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	corruptBlockData(t, db, 6, []byte("tampered"))
+
+	ok, err = bm.IsValid()
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	corruptBlockData(t, db, 6, []byte{6})
+
+	ok, err = bm.IsValid()
+	require.NoError(t, err)
+	require.True(t, ok)
+}
+
+func TestVerifyContents(t *testing.T) {
+	bm, err := NewWithLevelDB(newTestDB(t))
+	require.NoError(t, err)
+	require.NoError(t, createTestBlocks(bm, 5))
+	require.NoError(t, bm.EraseBlock("key5"))
+
+	ok, discrepancies, err := bm.VerifyContents(map[string][]byte{
+		"key1": {1},
+		"key2": {2},
+		"key3": {3},
+		"key4": {4},
+	})
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Empty(t, discrepancies)
+
+	ok, discrepancies, err = bm.VerifyContents(map[string][]byte{
+		"key1": {1},
+		"key2": {20},
+		"key3": {3},
+		"key6": {6},
+	})
+	require.NoError(t, err)
+	require.False(t, ok)
+	require.Equal(t, []string{
+		`mismatched data for key "key2"`,
+		`missing key "key6"`,
+		"extra block 4",
+	}, discrepancies)
+}
+
+func TestCorruptBlocks(t *testing.T) {
+	db := newTestDB(t)
+	bm, err := NewWithLevelDB(db)
+	require.NoError(t, err)
+	require.NoError(t, createTestBlocks(bm, 20))
+
+	corrupt, err := bm.CorruptBlocks()
+	require.NoError(t, err)
+	require.Empty(t, corrupt)
+
+	for _, blockNum := range []int{2, 11, 17} {
+		corruptBlockData(t, db, blockNum, []byte("tampered"))
+	}
+
+	corrupt, err = bm.CorruptBlocks()
+	require.NoError(t, err)
+	require.Equal(t, []int{2, 11, 17}, corrupt)
+}
+
+func TestValidateInfo(t *testing.T) {
+	bm, err := NewWithLevelDB(newTestDB(t))
+	require.NoError(t, err)
+	require.NoError(t, createTestBlocks(bm, 5))
+
+	info, err := bm.GetBlockMatrixInfo()
+	require.NoError(t, err)
+	require.NoError(t, ValidateInfo(info))
+
+	tests := []struct {
+		name   string
+		modify func(info *BlockMatrixInfo)
+	}{
+		{"zero size", func(info *BlockMatrixInfo) { info.Size = 0 }},
+		{"missing row", func(info *BlockMatrixInfo) { info.Rows = info.Rows[1:] }},
+		{"extra column", func(info *BlockMatrixInfo) { info.Cols = append(info.Cols, info.Cols[0]) }},
+		{"block count over capacity", func(info *BlockMatrixInfo) { info.BlockCount = 7 }},
+		{"negative block count", func(info *BlockMatrixInfo) { info.BlockCount = -1 }},
+		{"short row hash", func(info *BlockMatrixInfo) { info.Rows[1] = info.Rows[1][:16] }},
+		{"empty column hash", func(info *BlockMatrixInfo) { info.Cols[2] = nil }},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			malformed := copyInfo(info)
+			test.modify(malformed)
+			require.Error(t, ValidateInfo(malformed))
+		})
+	}
+
+	require.Error(t, ValidateInfo(nil))
+}
+
+func TestVerifyReconstructable(t *testing.T) {
+	db := newTestDB(t)
+	bm, err := NewWithLevelDB(db)
+	require.NoError(t, err)
+
+	ok, err := bm.VerifyReconstructable()
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	require.NoError(t, createTestBlocks(bm, 8))
+	require.NoError(t, bm.EraseBlock("key3"))
+
+	ok, err = bm.VerifyReconstructable()
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	corruptInfo(t, db, func(info *BlockMatrixInfo) {
+		info.Rows[1] = append([]byte{}, info.Rows[0]...)
+	})
+
+	ok, err = bm.VerifyReconstructable()
+	require.NoError(t, err)
+	require.False(t, ok)
+}
+
+func TestVerifyReconstructableBlockCount(t *testing.T) {
+	db := newTestDB(t)
+	bm, err := NewWithLevelDB(db)
+	require.NoError(t, err)
+	require.NoError(t, createTestBlocks(bm, 8))
+
+	corruptInfo(t, db, func(info *BlockMatrixInfo) {
+		info.BlockCount = 5
+	})
+
+	ok, err := bm.VerifyReconstructable()
+	require.NoError(t, err)
+	require.False(t, ok)
+}
+
+// concurrencyStore records the largest number of block reads in progress at once.
+type concurrencyStore struct {
+	Store
+	mu      sync.Mutex
+	current int
+	max     int
+}
+
+func (s *concurrencyStore) Get(key []byte) ([]byte, error) {
+	if _, err := strconv.Atoi(string(key)); err != nil {
+		return s.Store.Get(key)
+	}
+
+	s.mu.Lock()
+	s.current++
+	if s.current > s.max {
+		s.max = s.current
+	}
+	s.mu.Unlock()
+
+	// give other readers a chance to overlap
+	time.Sleep(time.Millisecond)
+
+	s.mu.Lock()
+	s.current--
+	s.mu.Unlock()
+
+	return s.Store.Get(key)
+}
+
+func TestWithValidationConcurrency(t *testing.T) {
+	db := newTestDB(t)
+	bm, err := NewWithLevelDB(db)
+	require.NoError(t, err)
+	require.NoError(t, createTestBlocks(bm, 20))
+	for _, blockNum := range []int{2, 11, 17} {
+		corruptBlockData(t, db, blockNum, []byte("tampered"))
+	}
+
+	results := make([]*ValidationResult, 0)
+	for _, n := range []int{1, 8} {
+		store := &concurrencyStore{Store: &leveldbStore{db: db}}
+		bm, err = New(store, WithValidationConcurrency(n))
+		require.NoError(t, err)
+
+		result, err := bm.Validate()
+		require.NoError(t, err)
+		require.LessOrEqual(t, store.max, n)
+		results = append(results, result)
+	}
+
+	require.Equal(t, []int{2, 11, 17}, results[0].BlockErrors)
+	require.Equal(t, results[0], results[1])
+}
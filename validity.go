@@ -0,0 +1,27 @@
+package blockmatrix
+
+// ValidityPolicy decides whether a change to the block matrix is valid.  CheckErase is given the block matrix info
+// before and after an erase and returns false if the erase should be rejected.  A policy is installed with
+// WithValidityPolicy, by default an erase must change exactly one row hash and one column hash.
+type ValidityPolicy interface {
+	CheckErase(before, after *BlockMatrixInfo) (bool, error)
+}
+
+// singleEraseValidityPolicy accepts erases that change exactly one row hash and one column hash, i.e. erases of a single
+// block.
+type singleEraseValidityPolicy struct{}
+
+func (singleEraseValidityPolicy) CheckErase(before, after *BlockMatrixInfo) (bool, error) {
+	return checkValidErase(after, before.Rows, before.Cols)
+}
+
+// copyInfo returns a copy of info that is unaffected by updates to the row and column hashes of info.
+func copyInfo(info *BlockMatrixInfo) *BlockMatrixInfo {
+	infoCopy := *info
+	infoCopy.Rows = make([][]byte, len(info.Rows))
+	infoCopy.Cols = make([][]byte, len(info.Cols))
+	copy(infoCopy.Rows, info.Rows)
+	copy(infoCopy.Cols, info.Cols)
+
+	return &infoCopy
+}
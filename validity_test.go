@@ -0,0 +1,96 @@
+package blockmatrix
+
+import (
+	"github.com/stretchr/testify/require"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+// maxCellsPolicy accepts erases that change at most n row hashes and n column hashes.
+type maxCellsPolicy struct {
+	n int
+}
+
+func (p maxCellsPolicy) CheckErase(before, after *BlockMatrixInfo) (bool, error) {
+	var rows, cols int
+	for i := 0; i < after.Size; i++ {
+		if !reflect.DeepEqual(before.Rows[i], after.Rows[i]) {
+			rows++
+		}
+		if !reflect.DeepEqual(before.Cols[i], after.Cols[i]) {
+			cols++
+		}
+	}
+
+	return rows > 0 && rows <= p.n && cols > 0 && cols <= p.n, nil
+}
+
+func TestWithValidityPolicy(t *testing.T) {
+	// blocks 1 and 5 are in different rows and columns
+	bm, err := NewWithLevelDB(newTestDB(t))
+	require.NoError(t, err)
+	require.NoError(t, createTestBlocks(bm, 9))
+	require.ErrorIs(t, bm.EraseBlocks("key1", "key5"), ErrInvalidErase)
+	require.NoError(t, bm.EraseBlock("key2"))
+
+	bm, err = NewWithLevelDB(newTestDB(t), WithValidityPolicy(maxCellsPolicy{n: 2}))
+	require.NoError(t, err)
+	require.NoError(t, createTestBlocks(bm, 9))
+	require.NoError(t, bm.EraseBlocks("key1", "key5"))
+	require.NoError(t, bm.EraseBlock("key2"))
+	require.ErrorIs(t, bm.EraseBlocks("key3", "key4", "key6"), ErrInvalidErase)
+
+	for _, key := range []string{"key1", "key5"} {
+		_, err = bm.GetBlock(key)
+		require.Error(t, err)
+	}
+
+	result, err := bm.Validate()
+	require.NoError(t, err)
+	require.True(t, result.OK)
+}
+
+func TestCheckValidErase(t *testing.T) {
+	bm, err := NewWithLevelDB(newTestDB(t))
+	require.NoError(t, err)
+	require.NoError(t, createTestBlocks(bm, 9))
+	before, err := bm.GetBlockMatrixInfo()
+	require.NoError(t, err)
+
+	// blocks 1 and 4 are in different rows and columns, changing both changes two rows and two columns
+	for _, blockNum := range []int{1, 4} {
+		bytes, err := bm.encodeBlock(NewBlock([]byte("mutated")))
+		require.NoError(t, err)
+		require.NoError(t, bm.put([]byte(strconv.Itoa(blockNum)), bytes))
+	}
+	after := copyInfo(before)
+	for i := 0; i < after.Size; i++ {
+		after.Rows[i], err = bm.calculateRowHash(i, after.Size)
+		require.NoError(t, err)
+		after.Cols[i], err = bm.calculateColumnHash(i, after.Size)
+		require.NoError(t, err)
+	}
+
+	ok, err := checkValidErase(after, before.Rows, before.Cols)
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	// a row and a column changing at the same index are both counted
+	after = copyInfo(before)
+	after.Rows[0] = []byte("changed")
+	after.Cols[0] = []byte("changed")
+	after.Cols[1] = []byte("changed")
+	ok, err = checkValidErase(after, before.Rows, before.Cols)
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	// a single erase changes exactly one row and one column
+	row, col := locateBlock(5)
+	after = copyInfo(before)
+	after.Rows[row] = []byte("changed")
+	after.Cols[col] = []byte("changed")
+	ok, err = checkValidErase(after, before.Rows, before.Cols)
+	require.NoError(t, err)
+	require.True(t, ok)
+}